@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
+
+	"collector/internal/app"
+	"collector/internal/config"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+// commands are the subcommands; without one the collector runs the
+// pipeline as configured by the top-level flags.
+var commands = []command{
+	{"validate", "check a config and print its components and env expansions", runValidate},
+	{"schema", "print the JSON Schema of the config file", runSchema},
+	{"parse-test", "show how lines from stdin are detected and normalized", runParseTest},
+	{"resolve", "look hosts up through the configured resolvers", runResolve},
+	{"graph", "build the call graph from a finite log and print it", runGraph},
+	{"replay", "replay a captured log in event time and print its anomalies", runReplay},
+	{"tui", "attach the terminal UI to a collector running with -metrics", runTUIClient},
+	{"version", "print build information", runVersion},
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: %s -c config.yml [-tui | -metrics]\n", os.Args[0])
+	fmt.Fprintf(out, "       %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-11s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(out, "\nflags:")
+	flag.PrintDefaults()
+}
+
+// loadConfig loads and validates the config at path. An empty path is
+// allowed when required is false and yields an empty config: the
+// debugging commands then use the built-in parsers and settings.
+func loadConfig(cmd, path string, required bool) *config.Config {
+	if path == "" {
+		if required {
+			log.Fatalf("%s: config file is required (-c)", cmd)
+		}
+		return &config.Config{}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
+
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// runValidate handles "collector validate -c config.yml".
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configPath := fs.String("c", "", "path to config file")
+	fs.Parse(args)
+
+	cfg := loadConfig("validate", *configPath, true)
+	if err := app.New(cfg).CheckConfig(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\n%s: ok\n", *configPath)
+}
+
+// runSchema handles "collector schema", whose output is checked in as
+// config.schema.json.
+func runSchema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	fs.Parse(args)
+
+	schema, err := config.Schema()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(schema))
+}
+
+// runParseTest handles "collector parse-test [-c config.yml] [-source name]".
+func runParseTest(args []string) {
+	fs := flag.NewFlagSet("parse-test", flag.ExitOnError)
+	configPath := fs.String("c", "", "config providing source parsers and aliases (optional)")
+	source := fs.String("source", "", "source whose parsers apply to the lines")
+	fs.Parse(args)
+
+	cfg := loadConfig("parse-test", *configPath, false)
+	if err := app.New(cfg).ParseTest(os.Stdin, os.Stdout, *source); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runResolve handles "collector resolve -c config.yml host...".
+func runResolve(args []string) {
+	fs := flag.NewFlagSet("resolve", flag.ExitOnError)
+	configPath := fs.String("c", "", "path to config file")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		log.Fatal("resolve: at least one host is required")
+	}
+	cfg := loadConfig("resolve", *configPath, true)
+
+	ctx, stop := signalContext()
+	defer stop()
+	if err := app.New(cfg).ResolveHosts(ctx, fs.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runGraph handles "collector graph [-c config.yml] [-source name] file".
+func runGraph(args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	configPath := fs.String("c", "", "config providing parsers and resolvers (optional)")
+	source := fs.String("source", "", "source whose parsers and service apply to the log")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		log.Fatal("graph: exactly one log file is required (- for stdin)")
+	}
+	cfg := loadConfig("graph", *configPath, false)
+
+	ctx, stop := signalContext()
+	defer stop()
+	if err := app.New(cfg).RunGraph(ctx, fs.Arg(0), *source, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runReplay handles "collector replay [-c config] [-speed N] file": the
+// graph and anomaly detector driven by the timestamps of a captured log.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("c", "", "config providing parsers, resolver and anomaly settings (optional)")
+	source := fs.String("source", "", "source whose parsers and service apply to the log")
+	speed := fs.Float64("speed", 0, "replay at N times the captured pace; 0 is as fast as possible")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		log.Fatal("replay: exactly one log file is required (- for stdin)")
+	}
+	if *speed < 0 {
+		log.Fatal("replay: -speed must not be negative")
+	}
+	cfg := loadConfig("replay", *configPath, false)
+
+	ctx, stop := signalContext()
+	defer stop()
+	if err := app.New(cfg).RunReplay(ctx, fs.Arg(0), *source, *speed, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runTUIClient handles "collector tui --connect host:port": the TUI of a
+// collector started elsewhere with -metrics.
+func runTUIClient(args []string) {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	addr := fs.String("connect", "", "address of a collector running with -metrics (host:port)")
+	fs.Parse(args)
+
+	if *addr == "" {
+		log.Fatal("tui: --connect host:port is required")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	if err := app.RunRemoteTUI(ctx, *addr); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runVersion handles "collector version".
+func runVersion(args []string) {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	fs.Parse(args)
+
+	fmt.Printf("collector %s\n", version)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	fmt.Printf("  go        %s\n", info.GoVersion)
+	settings := map[string]string{}
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	if rev := settings["vcs.revision"]; rev != "" {
+		if settings["vcs.modified"] == "true" {
+			rev += " (modified)"
+		}
+		fmt.Printf("  revision  %s\n", rev)
+	}
+	if t := settings["vcs.time"]; t != "" {
+		fmt.Printf("  committed %s\n", t)
+	}
+	fmt.Printf("  platform  %s/%s\n", settings["GOOS"], settings["GOARCH"])
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the collector itself when the tests re-execute their own
+// binary, so that the subcommands can exit and write to stdout as usual.
+func TestMain(m *testing.M) {
+	if os.Getenv("COLLECTOR_TEST_MAIN") == "1" {
+		os.Args = append([]string{"collector"}, strings.Fields(os.Getenv("COLLECTOR_TEST_ARGS"))...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestFindCommand(t *testing.T) {
+	for _, c := range commands {
+		if got := findCommand(c.name); got == nil || got.name != c.name {
+			t.Errorf("findCommand(%q) = %v", c.name, got)
+		}
+	}
+	for _, name := range []string{"", "-c", "help", "Validate"} {
+		if got := findCommand(name); got != nil {
+			t.Errorf("findCommand(%q) = %q, want the top-level flags", name, got.name)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yml")
+	os.WriteFile(config, []byte(`
+sources:
+  app:
+    type: file
+    service: api
+    path: `+filepath.Join(dir, "app.log")+`
+sinks:
+  out:
+    type: stdout
+    inputs: [app]
+`), 0o644)
+	log := filepath.Join(dir, "calls.log")
+	os.WriteFile(log, []byte(`{"timestamp":"2024-05-01T10:00:00Z","service":"api","dst_service":"db","operation":"query","latency_ms":12}`+"\n"), 0o644)
+
+	cases := []struct {
+		name  string
+		args  string
+		stdin string
+		code  int
+		want  string
+	}{
+		{"validate", "validate -c " + config, "", 0, config + ": ok"},
+		{"validate without config", "validate", "", 1, "validate: config file is required (-c)"},
+		{"validate missing config", "validate -c " + filepath.Join(dir, "none.yml"), "", 1, "none.yml"},
+		{"validate unknown flag", "validate -x", "", 2, "flag provided but not defined: -x"},
+		{"schema", "schema", "", 0, `"$schema"`},
+		{"schema unknown flag", "schema -c " + config, "", 2, "flag provided but not defined: -c"},
+		{"parse-test", "parse-test", `{"level":"warn","msg":"slow"}`, 0, "line 1: json"},
+		{"parse-test unknown source", "parse-test -c " + config + " -source nope", "x", 1, "nope"},
+		{"resolve without hosts", "resolve -c " + config, "", 1, "resolve: at least one host is required"},
+		{"graph", "graph " + log, "", 0, "1 lines, 1 calls: 2 services, 1 edges"},
+		{"graph without file", "graph", "", 1, "graph: exactly one log file is required"},
+		{"replay without file", "replay", "", 1, "replay: exactly one log file is required"},
+		{"replay negative speed", "replay -speed -1 " + log, "", 1, "replay: -speed must not be negative"},
+		{"tui without address", "tui", "", 1, "tui: --connect host:port is required"},
+		{"version", "version", "", 0, "collector dev"},
+		{"version with arguments", "version -v", "", 2, "flag provided but not defined: -v"},
+		{"no command", "", "", 1, "config file is required (-c)"},
+		{"unknown command", "frobnicate", "", 1, "config file is required (-c)"},
+		{"help", "help", "", 0, "commands:\n  validate"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^$")
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "COLLECTOR_TEST_MAIN=1", "COLLECTOR_TEST_ARGS="+tc.args)
+			cmd.Stdin = strings.NewReader(tc.stdin)
+			out, err := cmd.CombinedOutput()
+
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tc.code {
+				t.Errorf("exit code %d, want %d\n%s", code, tc.code, out)
+			}
+			if !strings.Contains(string(out), tc.want) {
+				t.Errorf("output lacks %q:\n%s", tc.want, out)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
 	"os/signal"
 	"syscall"
 
@@ -18,10 +19,22 @@ func main() {
 		log.Println("Note: .env file not found, using system environment variables")
 	}
 
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			cmd.run(os.Args[2:])
+			return
+		}
+	}
+	flag.Usage = usage
+
 	configPath := flag.String("c", "", "path to config file")
 	useTUI := flag.Bool("tui", false, "run with terminal UI")
 	useMetrics := flag.Bool("metrics", false, "run headless with Prometheus metrics endpoint")
 	metricsAddr := flag.String("metrics-addr", ":2112", "metrics server listen address")
+	if len(os.Args) > 1 && os.Args[1] == "help" {
+		usage()
+		return
+	}
 	flag.Parse()
 
 	if *configPath == "" {
@@ -0,0 +1,132 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Ack silences alerts for one edge until a deadline.
+type Ack struct {
+	EdgeKey string    `json:"edge_key"`
+	Until   time.Time `json:"until"`
+	AckedAt time.Time `json:"acked_at"`
+}
+
+// AckStore keeps acknowledged edges in a JSON file so mutes survive a
+// restart. An empty path keeps them in memory only.
+type AckStore struct {
+	path string
+
+	mu   sync.Mutex
+	acks map[string]Ack
+}
+
+// DefaultAckFile is where acks are kept when the config names no file.
+func DefaultAckFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "collector-acks.json"
+	}
+	return filepath.Join(dir, "collector", "acks.json")
+}
+
+// LoadAcks reads the store at path. A missing file yields an empty store.
+func LoadAcks(path string) (*AckStore, error) {
+	s := &AckStore{path: path, acks: make(map[string]Ack)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("acks: %w", err)
+	}
+	var list []Ack
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("acks: %s: %w", path, err)
+	}
+	for _, a := range list {
+		s.acks[a.EdgeKey] = a
+	}
+	return s, nil
+}
+
+// Ack mutes edgeKey until the given time and saves the store.
+func (s *AckStore) Ack(edgeKey string, until, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acks[edgeKey] = Ack{EdgeKey: edgeKey, Until: until, AckedAt: now}
+	return s.save(now)
+}
+
+// Unack lifts the mute on edgeKey and saves the store.
+func (s *AckStore) Unack(edgeKey string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.acks, edgeKey)
+	return s.save(now)
+}
+
+// Until reports when the mute on edgeKey ends, if it is still in force.
+func (s *AckStore) Until(edgeKey string, now time.Time) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.acks[edgeKey]
+	if !ok || !now.Before(a.Until) {
+		return time.Time{}, false
+	}
+	return a.Until, true
+}
+
+// Active returns the acks still in force, ordered by edge.
+func (s *AckStore) Active(now time.Time) []Ack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Ack, 0, len(s.acks))
+	for _, a := range s.acks {
+		if now.Before(a.Until) {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].EdgeKey < out[j].EdgeKey })
+	return out
+}
+
+// save drops expired acks and atomically rewrites the file.
+func (s *AckStore) save(now time.Time) error {
+	list := make([]Ack, 0, len(s.acks))
+	for key, a := range s.acks {
+		if !now.Before(a.Until) {
+			delete(s.acks, key)
+			continue
+		}
+		list = append(list, a)
+	}
+	if s.path == "" {
+		return nil
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].EdgeKey < list[j].EdgeKey })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("acks: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("acks: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("acks: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("acks: %w", err)
+	}
+	return nil
+}
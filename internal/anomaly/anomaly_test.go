@@ -2,8 +2,12 @@ package anomaly
 
 import (
 	"math"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"collector/internal/clock"
 )
 
 func TestRollingStats_Empty(t *testing.T) {
@@ -209,6 +213,50 @@ func TestDetector_Cooldown(t *testing.T) {
 	}
 }
 
+func TestDetector_CooldownExpires(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	d := NewZScoreDetector(50, 3.0, 64).WithMinSamples(10).WithCooldown(time.Hour).WithClock(clk)
+
+	spike := func() {
+		for i := 0; i < 5; i++ {
+			d.Feed("A|B|op", "latency", 10.0)
+		}
+		d.Feed("A|B|op", "latency", 10000.0)
+	}
+	for i := 0; i < 50; i++ {
+		d.Feed("A|B|op", "latency", 10.0)
+	}
+
+	spike()
+	clk.Advance(59 * time.Minute)
+	spike()
+	clk.Advance(2 * time.Minute)
+	spike()
+
+	evs := drainAnomalyEvents(d.Events(), 20*time.Millisecond)
+	if len(evs) != 2 {
+		t.Fatalf("expected 2 events an hour apart, got %d", len(evs))
+	}
+	if got := evs[1].Timestamp.Sub(evs[0].Timestamp); got != 61*time.Minute {
+		t.Errorf("events %s apart, want 1h1m", got)
+	}
+}
+
+func TestDetector_MuteExpires(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	d := NewZScoreDetector(50, 3.0, 64).WithMinSamples(10).WithCooldown(0).WithClock(clk)
+
+	for i := 0; i < 50; i++ {
+		d.Feed("A|B|op", "latency", 10.0)
+	}
+	d.Mute("A|B|op", clk.Now().Add(4*time.Hour))
+	clk.Advance(4*time.Hour + time.Second)
+	d.Feed("A|B|op", "latency", 10000.0)
+	if evs := drainAnomalyEvents(d.Events(), 20*time.Millisecond); len(evs) != 1 {
+		t.Errorf("expired mute: expected 1 event, got %d", len(evs))
+	}
+}
+
 func TestDetector_MultipleEdges(t *testing.T) {
 	d := NewZScoreDetector(50, 3.0, 64)
 	d.WithMinSamples(10)
@@ -248,6 +296,70 @@ func TestDetector_Stats_UnknownKey(t *testing.T) {
 	}
 }
 
+func TestDetector_Mute(t *testing.T) {
+	d := NewZScoreDetector(50, 3.0, 64)
+	d.WithMinSamples(10).WithCooldown(0)
+
+	for i := 0; i < 50; i++ {
+		d.Feed("A|B|op", "latency", 10.0)
+	}
+	d.Mute("A|B|op", time.Now().Add(time.Hour))
+	d.Feed("A|B|op", "latency", 10000.0)
+	if evs := drainAnomalyEvents(d.Events(), 50*time.Millisecond); len(evs) != 0 {
+		t.Fatalf("muted edge: expected no events, got %d", len(evs))
+	}
+
+	d.Mute("A|B|op", time.Time{})
+	d.Feed("A|B|op", "latency", 10000.0)
+	if evs := drainAnomalyEvents(d.Events(), 50*time.Millisecond); len(evs) != 1 {
+		t.Errorf("unmuted edge: expected 1 event, got %d", len(evs))
+	}
+}
+
+func TestAckStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "acks.json")
+	now := time.Now()
+
+	s, err := LoadAcks(path)
+	if err != nil {
+		t.Fatalf("LoadAcks on a missing file: %v", err)
+	}
+	if err := s.Ack("A|B|op", now.Add(time.Hour), now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ack("C|D|op", now.Add(-time.Minute), now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := s.Active(now)
+	if len(active) != 1 || active[0].EdgeKey != "A|B|op" {
+		t.Fatalf("want only the live ack after reload, got %+v", active)
+	}
+	if _, ok := s.Until("A|B|op", now.Add(2*time.Hour)); ok {
+		t.Error("ack should have expired")
+	}
+
+	if err := s.Unack("A|B|op", now); err != nil {
+		t.Fatal(err)
+	}
+	s, _ = LoadAcks(path)
+	if len(s.Active(now)) != 0 {
+		t.Error("unacked edge should not come back")
+	}
+}
+
+func TestAckStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acks.json")
+	os.WriteFile(path, []byte("{not json"), 0o644)
+	if _, err := LoadAcks(path); err == nil {
+		t.Error("want an error for a corrupt file")
+	}
+}
+
 func drainAnomalyEvents(ch <-chan AnomalyEvent, timeout time.Duration) []AnomalyEvent {
 	var out []AnomalyEvent
 	deadline := time.After(timeout)
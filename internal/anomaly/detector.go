@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"collector/internal/clock"
 	"collector/internal/metrics"
 )
 
@@ -24,11 +25,13 @@ type ZScoreDetector struct {
 	threshold  float64
 	minSamples int
 	cooldown   time.Duration
+	clock      clock.Clock
 
 	mu          sync.Mutex
 	stats       map[string]*RollingStats
 	inAnomaly   map[string]bool
 	lastAlerted map[string]time.Time
+	muted       map[string]time.Time // edge key -> end of an acknowledged mute
 
 	out chan AnomalyEvent
 }
@@ -39,9 +42,11 @@ func NewZScoreDetector(windowSize int, threshold float64, bufSize int) *ZScoreDe
 		threshold:   threshold,
 		minSamples:  windowSize / 2,
 		cooldown:    30 * time.Second,
+		clock:       clock.Real(),
 		stats:       make(map[string]*RollingStats),
 		inAnomaly:   make(map[string]bool),
 		lastAlerted: make(map[string]time.Time),
+		muted:       make(map[string]time.Time),
 		out:         make(chan AnomalyEvent, bufSize),
 	}
 }
@@ -56,6 +61,13 @@ func (d *ZScoreDetector) WithCooldown(cd time.Duration) *ZScoreDetector {
 	return d
 }
 
+// WithClock makes cooldowns, mutes and event timestamps follow c instead
+// of the wall clock.
+func (d *ZScoreDetector) WithClock(c clock.Clock) *ZScoreDetector {
+	d.clock = c
+	return d
+}
+
 func (d *ZScoreDetector) Feed(edgeKey, metric string, value float64) {
 	key := edgeKey + ":" + metric
 
@@ -86,12 +98,20 @@ func (d *ZScoreDetector) Feed(edgeKey, metric string, value float64) {
 		return
 	}
 
-	if last, ok := d.lastAlerted[key]; ok && time.Since(last) < d.cooldown {
+	now := d.clock.Now()
+	if last, ok := d.lastAlerted[key]; ok && now.Sub(last) < d.cooldown {
 		return
 	}
 
+	if until, ok := d.muted[edgeKey]; ok {
+		if now.Before(until) {
+			return
+		}
+		delete(d.muted, edgeKey)
+	}
+
 	d.inAnomaly[key] = true
-	d.lastAlerted[key] = time.Now()
+	d.lastAlerted[key] = now
 	metrics.AnomaliesTotal.WithLabelValues(metric).Inc()
 
 	ev := AnomalyEvent{
@@ -102,13 +122,26 @@ func (d *ZScoreDetector) Feed(edgeKey, metric string, value float64) {
 		Mean:      s.Mean(),
 		StdDev:    s.StdDev(),
 		Threshold: d.threshold,
-		Timestamp: time.Now(),
+		Timestamp: now,
 	}
 
 	select {
 	case d.out <- ev:
 	default:
+		metrics.AnomalyEventsDropped.Inc()
+	}
+}
+
+// Mute extends the cooldown of every metric on edgeKey until the given
+// time, so an acknowledged edge stays quiet. A zero time lifts the mute.
+func (d *ZScoreDetector) Mute(edgeKey string, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if until.IsZero() {
+		delete(d.muted, edgeKey)
+		return
 	}
+	d.muted[edgeKey] = until
 }
 
 func (d *ZScoreDetector) Events() <-chan AnomalyEvent {
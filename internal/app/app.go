@@ -2,9 +2,13 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,7 +18,10 @@ import (
 	"collector/internal/event"
 	"collector/internal/graph"
 	"collector/internal/metrics"
+	"collector/internal/parse"
 	"collector/internal/pipeline"
+	"collector/internal/remote"
+	"collector/internal/replay"
 	"collector/internal/resolve"
 	"collector/internal/sinks"
 	"collector/internal/sources"
@@ -38,6 +45,12 @@ func (a *App) Run(ctx context.Context) error {
 		return err
 	}
 
+	for _, sCfg := range a.cfg.Sinks {
+		if sCfg.Type == "prometheus_exporter" {
+			serveMetrics(ctx, sCfg.Address, nil)
+		}
+	}
+
 	go func() {
 		<-ctx.Done()
 		log.Println("shutdown signal received")
@@ -51,18 +64,18 @@ func (a *App) Run(ctx context.Context) error {
 func (a *App) RunWithTUI(ctx context.Context) error {
 	g := graph.New(256)
 
-	det := anomaly.NewZScoreDetector(100, 3.0, 256).
-		WithMinSamples(20).
-		WithCooldown(30 * time.Second)
+	det := a.newDetector(256)
 	g.WithAnomalyDetector(det)
+	acks := a.loadAcks(det)
 
 	ctx, cancel := context.WithCancel(ctx)
 	g.Start(ctx)
 
-	m := tui.New(g, det, cancel)
+	feed := tui.NewEventFeed(256)
+	m := tui.New(tui.Local(g, det), cancel).WithEventFeed(feed).WithAcks(acks)
 	prog := tea.NewProgram(m, tea.WithAltScreen())
 
-	sink := &graphSink{graph: g, processed: func() { metrics.PipelineProcessed.Inc() }}
+	sink := &graphSink{graph: g, feed: feed, processed: func() { metrics.PipelineProcessed.Inc() }}
 
 	p, err := a.buildPipelineWithSink(sink)
 	if err != nil {
@@ -84,18 +97,171 @@ func (a *App) RunWithTUI(ctx context.Context) error {
 	return <-pipelineErr
 }
 
+// RunRemoteTUI attaches the TUI to a collector running with -metrics at
+// addr. The event log stays empty: only the graph and anomalies are
+// streamed.
+func RunRemoteTUI(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := remote.Dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	_, err = tea.NewProgram(tui.New(client, cancel), tea.WithAltScreen()).Run()
+	return err
+}
+
 func (a *App) RunMetrics(ctx context.Context, addr string) error {
 	g := graph.New(1024)
-	det := anomaly.NewZScoreDetector(100, 3.0, 1024).
-		WithMinSamples(20).
-		WithCooldown(30 * time.Second)
+	det := a.newDetector(1024)
 	g.WithAnomalyDetector(det)
+	a.loadAcks(det)
 
 	ctx, cancel := context.WithCancel(ctx)
 	g.Start(ctx)
 
+	// Remote TUIs attach to the read-only stream served next to /metrics.
+	srv := remote.NewServer(g, det)
+	go srv.Run(ctx)
+	serveMetrics(ctx, addr, srv.Handler())
+
+	sink := &graphSink{graph: g, processed: func() { metrics.PipelineProcessed.Inc() }}
+	p, err := a.buildPipelineWithSink(sink)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	err = p.Run(ctx)
+	cancel()
+	return err
+}
+
+// RunReplay replays the log at path (or stdin for "-") in event time and
+// writes every anomaly to out. The parse chain and service of the source
+// named source apply to the lines; with no name, a config with a single
+// source uses that one.
+func (a *App) RunReplay(ctx context.Context, path, source string, speed float64, out io.Writer) error {
+	in, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	rp, err := a.newReplayer(source)
+	if err != nil {
+		return err
+	}
+	rp.Speed = speed
+
+	st, err := rp.Run(ctx, in, func(ev anomaly.AnomalyEvent) {
+		fmt.Fprintf(out, "%s  %-10s %-40s value=%.2f z=%.2f mean=%.2f stddev=%.2f\n",
+			ev.Timestamp.UTC().Format(time.RFC3339Nano), ev.Metric, ev.EdgeKey,
+			ev.Value, ev.ZScore, ev.Mean, ev.StdDev)
+	})
+	log.Printf("replay: %d lines, %d calls, %d anomalies, event time %s to %s",
+		st.Lines, st.Calls, st.Anomalies,
+		st.First.UTC().Format(time.RFC3339), st.Last.UTC().Format(time.RFC3339))
+	return err
+}
+
+// newReplayer builds a replay over a fresh graph and detector, reading
+// lines as the source named source would.
+func (a *App) newReplayer(source string) (*replay.Replayer, error) {
+	if err := event.SetAliases(a.cfg.Aliases); err != nil {
+		return nil, err
+	}
+	resolver, err := resolve.FromConfig(a.cfg.Resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	// The detector buffer only has to hold what one event can fire.
+	rp := replay.New(graph.New(256), a.newDetector(16))
+	rp.Resolver = resolver
+	if rp.Parser, rp.Service, err = a.lineParser(source); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// lineParser returns the parse chain and service of the named source, or
+// of the only source when name is empty.
+func (a *App) lineParser(name string) (pipeline.LineParser, string, error) {
+	if name == "" && len(a.cfg.Sources) == 1 {
+		for n := range a.cfg.Sources {
+			name = n
+		}
+	}
+	if name == "" {
+		return nil, "", nil
+	}
+	sCfg, ok := a.cfg.Sources[name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown source '%s'", name)
+	}
+	p, err := sourceParser(name, sCfg)
+	return p, sCfg.Service, err
+}
+
+// openInput opens path for reading, or stdin for "-".
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// newDetector builds the z-score detector from the anomaly config, using
+// the built-in settings for anything left unset.
+func (a *App) newDetector(bufSize int) *anomaly.ZScoreDetector {
+	c := a.cfg.Anomaly
+	window, threshold, minSamples, cooldown := 100, 3.0, 20, 30*time.Second
+	if c.WindowSize > 0 {
+		window = c.WindowSize
+	}
+	if c.Threshold > 0 {
+		threshold = c.Threshold
+	}
+	if c.MinSamples > 0 {
+		minSamples = c.MinSamples
+	}
+	if c.CooldownSeconds > 0 {
+		cooldown = time.Duration(c.CooldownSeconds) * time.Second
+	}
+	return anomaly.NewZScoreDetector(window, threshold, bufSize).
+		WithMinSamples(minSamples).
+		WithCooldown(cooldown)
+}
+
+// loadAcks opens the ack store and mutes the edges acknowledged in an
+// earlier session. An unreadable file is reported and left untouched;
+// acks then last for this session only.
+func (a *App) loadAcks(det *anomaly.ZScoreDetector) *anomaly.AckStore {
+	path := a.cfg.Anomaly.AckFile
+	if path == "" {
+		path = anomaly.DefaultAckFile()
+	}
+	acks, err := anomaly.LoadAcks(path)
+	if err != nil {
+		log.Printf("anomaly acks disabled: %v", err)
+		acks, _ = anomaly.LoadAcks("")
+	}
+	for _, ack := range acks.Active(time.Now()) {
+		det.Mute(ack.EdgeKey, ack.Until)
+	}
+	return acks
+}
+
+// serveMetrics exposes the default Prometheus registry on addr until ctx is
+// done, and the remote TUI stream when tuiFeed is non-nil.
+func serveMetrics(ctx context.Context, addr string, tuiFeed http.Handler) {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", metrics.Handler())
+	if tuiFeed != nil {
+		mux.Handle("/tui/", tuiFeed)
+	}
 	srv := &http.Server{Addr: addr, Handler: mux}
 	go func() {
 		<-ctx.Done()
@@ -107,21 +273,11 @@ func (a *App) RunMetrics(ctx context.Context, addr string) error {
 			log.Printf("metrics server error: %v", err)
 		}
 	}()
-
-	sink := &graphSink{graph: g, processed: func() { metrics.PipelineProcessed.Inc() }}
-	p, err := a.buildPipelineWithSink(sink)
-	if err != nil {
-		cancel()
-		return err
-	}
-
-	err = p.Run(ctx)
-	cancel()
-	return err
 }
 
 type graphSink struct {
 	graph     *graph.CallGraph
+	feed      *tui.EventFeed // optional live tail for the TUI event log
 	processed func()
 }
 
@@ -137,16 +293,11 @@ func (s *graphSink) Run(ctx context.Context, in <-chan *event.NormalizedEvent) e
 			if s.processed != nil {
 				s.processed()
 			}
-			if ev.SrcService != "" && ev.DstService != "" {
-				s.graph.Feed(&graph.NormalizedEvent{
-					SrcService: ev.SrcService,
-					DstService: ev.DstService,
-					Operation:  ev.Operation,
-					IsError:    ev.StatusCode >= 500,
-					Latency:    ev.Latency,
-					OccurredAt: ev.Timestamp,
-				})
+			if s.feed != nil {
+				s.feed.Publish(ev)
 			}
+			s.graph.Feed(graph.FromEvent(ev))
+			ev.Done()
 		}
 	}
 }
@@ -161,8 +312,9 @@ func (a *App) buildPipeline() (*pipeline.Pipeline, error) {
 	if len(a.cfg.Transforms) > 1 {
 		return nil, fmt.Errorf("only 1 transform is supported right now, got: %d", len(a.cfg.Transforms))
 	}
-	if len(a.cfg.Sinks) > 1 {
-		return nil, fmt.Errorf("only 1 sink is supported right now, got: %d", len(a.cfg.Sinks))
+	routeTargets := a.routeTargets()
+	if len(a.cfg.Sinks)-len(routeTargets) != 1 {
+		return nil, fmt.Errorf("only 1 sink is supported right now, got: %d", len(a.cfg.Sinks)-len(routeTargets))
 	}
 
 	selectedSources, trans, transformName, hasTransform, err := a.buildSourcesAndTransform()
@@ -173,6 +325,9 @@ func (a *App) buildPipeline() (*pipeline.Pipeline, error) {
 	var sinkName string
 	var sinkCfg config.SinkConfig
 	for name, sCfg := range a.cfg.Sinks {
+		if routeTargets[name] {
+			continue
+		}
 		sinkName = name
 		sinkCfg = sCfg
 		break
@@ -184,12 +339,9 @@ func (a *App) buildPipeline() (*pipeline.Pipeline, error) {
 		return nil, err
 	}
 
-	var sink pipeline.Sink
-	switch sinkCfg.Type {
-	case "stdout":
-		sink = &sinks.StdoutSink{Pretty: sinkCfg.Pretty}
-	default:
-		return nil, fmt.Errorf("unknown sink type: %s", sinkCfg.Type)
+	sink, err := buildSink(sinkCfg)
+	if err != nil {
+		return nil, err
 	}
 
 	resolver, err := resolve.FromConfig(a.cfg.Resolve)
@@ -197,14 +349,106 @@ func (a *App) buildPipeline() (*pipeline.Pipeline, error) {
 		return nil, err
 	}
 
+	routes, err := a.buildRoutes(trans, transformName)
+	if err != nil {
+		return nil, err
+	}
+
 	return &pipeline.Pipeline{
-		Sources:   selectedSources,
-		Transform: trans,
-		Sink:      sink,
-		Resolver:  resolver,
+		Sources:       selectedSources,
+		Transform:     trans,
+		Sink:          sink,
+		Resolver:      resolver,
+		Routes:        routes,
+		TransformName: transformName,
+		SinkName:      sinkName,
 	}, nil
 }
 
+// routeTargets returns the sinks that only receive routed transform output.
+func (a *App) routeTargets() map[string]bool {
+	targets := make(map[string]bool)
+	for _, t := range a.cfg.Transforms {
+		if t.RouteTo != "" {
+			targets[t.RouteTo] = true
+		}
+	}
+	return targets
+}
+
+// buildRoutes wires a throttle transform's overflow output to its route_to sink.
+func (a *App) buildRoutes(trans pipeline.Transformer, transformName string) ([]pipeline.Route, error) {
+	th, ok := trans.(*transform.ThrottleTransform)
+	if !ok || th.Overflow != transform.OverflowRoute {
+		return nil, nil
+	}
+	target := a.cfg.Transforms[transformName].RouteTo
+	if target == "" {
+		return nil, fmt.Errorf("transform [%s]: overflow 'route' requires route_to", transformName)
+	}
+	sinkCfg, ok := a.cfg.Sinks[target]
+	if !ok {
+		return nil, fmt.Errorf("transform [%s]: route_to refers to unknown sink '%s'", transformName, target)
+	}
+	log.Printf("initializing route sink: %s (type: %s)", target, sinkCfg.Type)
+	sink, err := buildSink(sinkCfg)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan event.Event, 100)
+	th.Route = ch
+	return []pipeline.Route{{Name: target, In: ch, Sink: sink}}, nil
+}
+
+func buildSink(sinkCfg config.SinkConfig) (pipeline.Sink, error) {
+	switch sinkCfg.Type {
+	case "stdout":
+		return &sinks.StdoutSink{Pretty: sinkCfg.Pretty}, nil
+	case "prometheus_remote_write":
+		if sinkCfg.Endpoint == "" {
+			return nil, fmt.Errorf("prometheus_remote_write sink requires an endpoint")
+		}
+		return &sinks.RemoteWriteSink{
+			Endpoint:      sinkCfg.Endpoint,
+			Headers:       sinkCfg.Headers,
+			Labels:        sinkCfg.Labels,
+			BatchSize:     sinkCfg.BatchSize,
+			FlushInterval: sinkCfg.FlushInterval,
+			MaxRetries:    sinkCfg.MaxRetries,
+		}, nil
+	case "prometheus_exporter":
+		if sinkCfg.Address == "" {
+			return nil, fmt.Errorf("prometheus_exporter sink requires an address")
+		}
+		return &sinks.PromExporterSink{Labels: sinkCfg.Labels}, nil
+	case "file":
+		return &sinks.FileSink{
+			Path:           sinkCfg.Path,
+			Encoding:       sinkCfg.Encoding,
+			MaxSize:        sinkCfg.MaxSize,
+			RotateInterval: sinkCfg.RotateInterval,
+			Compression:    sinkCfg.Compression,
+			MaxFiles:       sinkCfg.MaxFiles,
+			MaxAge:         sinkCfg.MaxAge,
+			Fsync:          sinkCfg.Fsync,
+			FlushInterval:  sinkCfg.FlushInterval,
+		}, nil
+	case "kafka":
+		return &sinks.KafkaSink{
+			Brokers:       sinkCfg.Brokers,
+			Topic:         sinkCfg.Topic,
+			Key:           sinkCfg.Key,
+			Encoding:      sinkCfg.Encoding,
+			BatchBytes:    sinkCfg.BatchBytes,
+			FlushInterval: sinkCfg.FlushInterval,
+			Compression:   sinkCfg.Compression,
+			Acks:          sinkCfg.Acks,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type: %s", sinkCfg.Type)
+	}
+}
+
 func (a *App) buildPipelineWithSink(normSink pipeline.NormalizedSink) (*pipeline.Pipeline, error) {
 	if len(a.cfg.Sources) == 0 {
 		return nil, fmt.Errorf("no sources defined in config")
@@ -213,7 +457,7 @@ func (a *App) buildPipelineWithSink(normSink pipeline.NormalizedSink) (*pipeline
 		return nil, fmt.Errorf("only 1 transform is supported right now, got: %d", len(a.cfg.Transforms))
 	}
 
-	selectedSources, trans, _, _, err := a.buildSourcesAndTransform()
+	selectedSources, trans, transformName, _, err := a.buildSourcesAndTransform()
 	if err != nil {
 		return nil, err
 	}
@@ -223,11 +467,19 @@ func (a *App) buildPipelineWithSink(normSink pipeline.NormalizedSink) (*pipeline
 		return nil, err
 	}
 
+	routes, err := a.buildRoutes(trans, transformName)
+	if err != nil {
+		return nil, err
+	}
+
 	return &pipeline.Pipeline{
 		Sources:        selectedSources,
 		Transform:      trans,
 		NormalizedSink: normSink,
 		Resolver:       resolver,
+		Routes:         routes,
+		TransformName:  transformName,
+		SinkName:       "graph",
 	}, nil
 }
 
@@ -238,19 +490,20 @@ func (a *App) buildSourcesAndTransform() (
 	hasTransform bool,
 	err error,
 ) {
+	if err = event.SetAliases(a.cfg.Aliases); err != nil {
+		return
+	}
+
 	sourcesByName := make(map[string]pipeline.Source, len(a.cfg.Sources))
 	for name, sCfg := range a.cfg.Sources {
 		log.Printf("initializing source: %s (type: %s)", name, sCfg.Type)
 		var src pipeline.Source
-		switch sCfg.Type {
-		case "stdin":
-			src = &sources.StdinSource{Service: sCfg.Service}
-		case "file":
-			src = &sources.FileSource{Service: sCfg.Service, Path: sCfg.Path}
-		case "docker":
-			src = &sources.DockerSource{Service: sCfg.Service, ContainerID: sCfg.ContainerID}
-		default:
-			err = fmt.Errorf("unknown source type: %s", sCfg.Type)
+		if src, err = buildSource(sCfg); err != nil {
+			err = fmt.Errorf("source [%s]: %w", name, err)
+			return
+		}
+		src = pipeline.Observed(name, src)
+		if src, err = withSourceParser(name, sCfg, src); err != nil {
 			return
 		}
 		sourcesByName[name] = src
@@ -272,6 +525,54 @@ func (a *App) buildSourcesAndTransform() (
 				AddFields: transformCfg.AddFields,
 				Case:      transformCfg.Case,
 			}
+		case "log_to_metric":
+			trans, err = buildLogToMetric(transformCfg)
+			if err != nil {
+				return
+			}
+		case "aggregate":
+			trans, err = transform.NewAggregateTransform(
+				transformCfg.GroupBy,
+				transformCfg.Window,
+				transformCfg.Slide,
+				transformCfg.AllowedLateness,
+			)
+			if err != nil {
+				return
+			}
+		case "dedupe":
+			trans = &transform.DedupeTransform{Window: transformCfg.Window}
+		case "throttle":
+			trans, err = transform.NewThrottleTransform(
+				transformCfg.KeyFields,
+				transformCfg.Rate,
+				transformCfg.Burst,
+				transformCfg.Overflow,
+				transformCfg.SampleRate,
+			)
+			if err != nil {
+				return
+			}
+		case "sample":
+			var st *transform.SampleTransform
+			st, err = transform.NewSampleTransform(
+				transformCfg.Mode,
+				transformCfg.Field,
+				transformCfg.Rate,
+				transformCfg.LevelRates,
+			)
+			if err != nil {
+				return
+			}
+			st.Window = transformCfg.Window
+			st.LatencyThreshold = transformCfg.LatencyThreshold
+			st.MaxTraces = transformCfg.MaxTraces
+			trans = st
+		case "redact":
+			trans, err = buildRedact(transformCfg)
+			if err != nil {
+				return
+			}
 		default:
 			err = fmt.Errorf("unknown transform type: %s", transformCfg.Type)
 			return
@@ -296,6 +597,190 @@ func (a *App) buildSourcesAndTransform() (
 	return
 }
 
+func buildSource(sCfg config.SourceConfig) (pipeline.Source, error) {
+	switch sCfg.Type {
+	case "stdin":
+		return &sources.StdinSource{Service: sCfg.Service}, nil
+	case "file":
+		return &sources.FileSource{Service: sCfg.Service, Path: sCfg.Path}, nil
+	case "docker":
+		return &sources.DockerSource{Service: sCfg.Service, ContainerID: sCfg.ContainerID}, nil
+	case "journald":
+		return &sources.JournaldSource{
+			Service:    sCfg.Service,
+			Units:      sCfg.Units,
+			Path:       sCfg.Path,
+			CursorFile: sCfg.CursorFile,
+			Journalctl: sCfg.Journalctl,
+		}, nil
+	case "exec":
+		env := make([]string, 0, len(sCfg.Env))
+		for _, k := range sortedKeys(sCfg.Env) {
+			env = append(env, k+"="+sCfg.Env[k])
+		}
+		return &sources.ExecSource{
+			Service:     sCfg.Service,
+			Command:     sCfg.Command,
+			Env:         env,
+			Dir:         sCfg.WorkingDir,
+			Interval:    sCfg.Interval,
+			StderrLevel: sCfg.StderrLevel,
+		}, nil
+	case "kafka":
+		return &sources.KafkaSource{
+			Service:     sCfg.Service,
+			Brokers:     sCfg.Brokers,
+			Topics:      sCfg.Topics,
+			Group:       sCfg.Group,
+			Decoding:    sCfg.Decoding,
+			StartOffset: sCfg.StartOffset,
+		}, nil
+	case "tcp":
+		tlsCfg, err := serverTLS(sCfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		return &sources.TCPSource{
+			Service:        sCfg.Service,
+			Address:        sCfg.Address,
+			Framing:        sCfg.Framing,
+			MaxLength:      sCfg.MaxLength,
+			MaxConnections: sCfg.MaxConnections,
+			TLS:            tlsCfg,
+		}, nil
+	case "udp":
+		return &sources.UDPSource{Service: sCfg.Service, Address: sCfg.Address, MaxLength: sCfg.MaxLength}, nil
+	case "http":
+		tlsCfg, err := serverTLS(sCfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		return &sources.HTTPSource{
+			Service:        sCfg.Service,
+			Address:        sCfg.Address,
+			AuthToken:      sCfg.AuthToken,
+			MaxLength:      sCfg.MaxLength,
+			MaxConnections: sCfg.MaxConnections,
+			Timeout:        sCfg.Timeout,
+			TLS:            tlsCfg,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown source type: %s", sCfg.Type)
+	}
+}
+
+// serverTLS loads the certificates of a listening source, or returns nil
+// when TLS is not configured.
+func serverTLS(c *config.TLSConfig) (*tls.Config, error) {
+	if c == nil {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates in %s", c.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+func buildRedact(cfg config.TransformConfig) (pipeline.Transformer, error) {
+	rules := make([]*transform.RedactRule, 0, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		rules = append(rules, &transform.RedactRule{
+			Name:     r.Name,
+			Detector: r.Detector,
+			Pattern:  r.Pattern,
+			Action:   r.Action,
+		})
+	}
+	return transform.NewRedactTransform(rules, cfg.AllowFields, cfg.DenyFields, cfg.Action, cfg.HashKey)
+}
+
+// withSourceParser wraps src with the parse chain configured for it, if
+// any.
+func withSourceParser(name string, sCfg config.SourceConfig, src pipeline.Source) (pipeline.Source, error) {
+	p, err := sourceParser(name, sCfg)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return src, nil
+	}
+	return pipeline.WithParser(src, p), nil
+}
+
+// sourceParser builds the parse chain configured for a source, or nil if
+// it has none. A codec is shorthand for a single-entry chain.
+func sourceParser(name string, sCfg config.SourceConfig) (pipeline.LineParser, error) {
+	stages := sCfg.Parsers
+	if sCfg.Codec != "" {
+		if len(stages) > 0 {
+			return nil, fmt.Errorf("source [%s]: codec and parsers are mutually exclusive", name)
+		}
+		stages = []config.ParserConfig{{
+			Type:               sCfg.Codec,
+			Template:           sCfg.Template,
+			Patterns:           sCfg.Patterns,
+			PatternDefinitions: sCfg.PatternDefinitions,
+		}}
+	}
+	if len(stages) == 0 {
+		if sCfg.Flatten == nil {
+			return nil, nil
+		}
+		stages = []config.ParserConfig{{Type: parse.ParserAuto}}
+	}
+
+	chain := &parse.Chain{KeepOriginal: sCfg.KeepOriginal}
+	if f := sCfg.Flatten; f != nil {
+		chain.Flatten = &parse.Flattener{Separator: f.Separator, MaxDepth: f.MaxDepth}
+	}
+	for i, st := range stages {
+		p, err := parse.NewParser(st.Type, parse.ParserOptions{
+			Template:           st.Template,
+			Patterns:           st.Patterns,
+			PatternDefinitions: st.PatternDefinitions,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("source [%s]: parsers[%d]: %w", name, i, err)
+		}
+		chain.Parsers = append(chain.Parsers, p)
+	}
+	return chain, nil
+}
+
+func buildLogToMetric(cfg config.TransformConfig) (pipeline.Transformer, error) {
+	rules := make([]*transform.MetricRule, 0, len(cfg.Metrics))
+	for _, m := range cfg.Metrics {
+		filter, err := transform.CompileFilter(m.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("log_to_metric %q: %w", m.Name, err)
+		}
+		rules = append(rules, &transform.MetricRule{
+			Type:           m.Type,
+			Name:           m.Name,
+			Filter:         filter,
+			Field:          m.Field,
+			Labels:         m.Labels,
+			Buckets:        m.Buckets,
+			MaxCardinality: m.MaxCardinality,
+		})
+	}
+	return transform.NewLogToMetricTransform(cfg.Mode, rules)
+}
+
 func (a *App) validateSinkInputs(sinkName string, sinkCfg config.SinkConfig, transformName string, hasTransform bool) error {
 	if hasTransform {
 		for _, in := range sinkCfg.Inputs {
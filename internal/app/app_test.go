@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"collector/internal/config"
+)
+
+func TestRun_ServesPrometheusExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+	os.WriteFile(path, []byte(`{"metric":"queue_depth","value":7,"service":"api"}`+"\n"), 0o644)
+	addr := freeAddr(t)
+
+	a := New(&config.Config{
+		Sources: map[string]config.SourceConfig{"in": {Type: "file", Path: path}},
+		Sinks: map[string]config.SinkConfig{"scrape": {
+			Type:    "prometheus_exporter",
+			Inputs:  []string{"in"},
+			Address: addr,
+		}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		body := scrape(addr)
+		if strings.Contains(body, "queue_depth{") && strings.Contains(body, "logshipper_") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue_depth not served on %s:\n%s", addr, body)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestBuildSink_ExporterRequiresAddress(t *testing.T) {
+	if _, err := buildSink(config.SinkConfig{Type: "prometheus_exporter"}); err == nil {
+		t.Error("want an error: the series would never be served")
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func scrape(addr string) string {
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
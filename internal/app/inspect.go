@@ -0,0 +1,339 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"collector/internal/config"
+	"collector/internal/event"
+	"collector/internal/graph"
+	"collector/internal/parse"
+	"collector/internal/pipeline"
+	"collector/internal/resolve"
+)
+
+// The commands below back the debugging subcommands of cmd/collector.
+// None of them start sources or sinks.
+
+// CheckConfig builds every source parse chain, which Validate cannot do
+// on its own, and writes the files the config was read from, the
+// component DAG and the environment variables and secrets it expanded.
+func (a *App) CheckConfig(w io.Writer) error {
+	for _, name := range sortedKeys(a.cfg.Sources) {
+		if _, err := sourceParser(name, a.cfg.Sources[name]); err != nil {
+			return err
+		}
+	}
+	if err := event.SetAliases(a.cfg.Aliases); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(a.cfg.Files) > 1 {
+		fmt.Fprintln(tw, "files")
+		for _, f := range a.cfg.Files {
+			fmt.Fprintf(tw, "  %s\n", f)
+		}
+	}
+	fmt.Fprintln(tw, "sources")
+	for _, name := range sortedKeys(a.cfg.Sources) {
+		s := a.cfg.Sources[name]
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", name, s.Type, describeSource(s))
+	}
+	if len(a.cfg.Transforms) > 0 {
+		fmt.Fprintln(tw, "transforms")
+		for _, name := range sortedKeys(a.cfg.Transforms) {
+			t := a.cfg.Transforms[name]
+			fmt.Fprintf(tw, "  %s\t%s\t<- %s\n", name, t.Type, strings.Join(t.Inputs, ", "))
+		}
+	}
+	fmt.Fprintln(tw, "sinks")
+	routed := a.routeTargets()
+	for _, name := range sortedKeys(a.cfg.Sinks) {
+		s := a.cfg.Sinks[name]
+		from := strings.Join(s.Inputs, ", ")
+		if routed[name] {
+			for _, tn := range sortedKeys(a.cfg.Transforms) {
+				if a.cfg.Transforms[tn].RouteTo == name {
+					from = tn + " (route_to)"
+				}
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t<- %s\n", name, s.Type, from)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(a.cfg.Expansions) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "environment")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, e := range a.cfg.Expansions {
+		switch {
+		case !e.Set && !e.Default:
+			fmt.Fprintf(tw, "  %s\t(unset, expanded to \"\")\n", e.Name)
+		case (e.File || looksSecret(e.Name)) && e.Default:
+			fmt.Fprintf(tw, "  %s\t%s (default)\n", e.Name, strings.Repeat("*", 8))
+		case e.File || looksSecret(e.Name):
+			fmt.Fprintf(tw, "  %s\t%s\n", e.Name, strings.Repeat("*", 8))
+		case e.Default:
+			fmt.Fprintf(tw, "  %s\t%q (default)\n", e.Name, e.Value)
+		default:
+			fmt.Fprintf(tw, "  %s\t%q\n", e.Name, e.Value)
+		}
+	}
+	return tw.Flush()
+}
+
+func describeSource(s config.SourceConfig) string {
+	var parts []string
+	if s.Service != "" {
+		parts = append(parts, "service="+s.Service)
+	}
+	switch {
+	case s.Path != "":
+		parts = append(parts, "path="+s.Path)
+	case s.ContainerID != "":
+		parts = append(parts, "container="+s.ContainerID)
+	case s.Address != "":
+		parts = append(parts, "address="+s.Address)
+	case len(s.Units) > 0:
+		parts = append(parts, "units="+strings.Join(s.Units, ","))
+	case len(s.Command) > 0:
+		parts = append(parts, "command="+strings.Join(s.Command, " "))
+	case len(s.Topics) > 0:
+		parts = append(parts, "topics="+strings.Join(s.Topics, ","))
+		if s.Group != "" {
+			parts = append(parts, "group="+s.Group)
+		}
+	}
+	var parsers []string
+	if s.Codec != "" {
+		parsers = append(parsers, s.Codec)
+	}
+	for _, p := range s.Parsers {
+		parsers = append(parsers, p.Type)
+	}
+	if len(parsers) > 0 {
+		parts = append(parts, "parsers="+strings.Join(parsers, ","))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// looksSecret reports whether an environment variable's value, or the
+// default standing in for it, should be kept out of the output.
+func looksSecret(name string) bool {
+	name = strings.ToUpper(name)
+	for _, s := range []string{"SECRET", "TOKEN", "PASSWORD", "PASSWD", "KEY", "CREDENTIAL"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTest reads lines from r and writes how each was detected and
+// normalized, with the raw key every canonical field came from. Lines go
+// through the parse chain of the named source, as in the pipeline.
+func (a *App) ParseTest(r io.Reader, w io.Writer, source string) error {
+	if err := event.SetAliases(a.cfg.Aliases); err != nil {
+		return err
+	}
+	parser, service, err := a.lineParser(source)
+	if err != nil {
+		return err
+	}
+	consumer, _ := parser.(pipeline.LineConsumer)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		ingest := time.Now().UTC()
+		evt := event.Event{
+			Timestamp: ingest,
+			Source:    "parse-test",
+			Service:   service,
+			Type:      event.TypeLog,
+			Message:   sc.Text(),
+			Level:     "info",
+		}
+		if consumer != nil && consumer.Consume(&evt) {
+			fmt.Fprintf(w, "line %d: consumed by the parser (directive)\n\n", line)
+			continue
+		}
+		if parser != nil {
+			parser.ParseEvent(&evt)
+		}
+		parse.ParseEvent(&evt)
+		n := event.Normalize(&evt)
+
+		raw, detected := parse.Fields(sc.Text())
+		fmt.Fprintf(w, "line %d: %s", line, n.Format)
+		if detected != n.Format {
+			fmt.Fprintf(w, " (auto-detection alone: %s)", detected)
+		}
+		fmt.Fprintln(w)
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		used := make(map[string]bool)
+		field := func(name, value, fallback string) {
+			if value == "" {
+				return
+			}
+			from := event.ResolveKey(raw, name)
+			switch {
+			case from != "":
+				for _, k := range strings.Split(from, "+") {
+					used[k] = true
+				}
+				from = "<- " + from
+			case fallback != "":
+				from = "(" + fallback + ")"
+			default:
+				from = "(" + n.Format + " parser)"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", name, value, from)
+		}
+		tsFallback := ""
+		if n.Timestamp.Equal(ingest) {
+			tsFallback = "ingest time"
+		}
+		svcFallback := ""
+		if n.SrcService == service {
+			svcFallback = "source service"
+		}
+		field(event.FieldTimestamp, n.Timestamp.Format(time.RFC3339Nano), tsFallback)
+		field(event.FieldService, n.SrcService, svcFallback)
+		field(event.FieldDstService, n.DstService, "")
+		field(event.FieldOperation, n.Operation, "")
+		if n.StatusCode != 0 {
+			field(event.FieldStatusCode, fmt.Sprint(n.StatusCode), "")
+		}
+		if n.Latency != 0 {
+			field(event.FieldLatency, n.Latency.String(), "")
+		}
+		field(event.FieldLevel, n.Level, "default")
+		field(event.FieldTraceID, n.TraceID, "")
+		field(event.FieldSpanID, n.SpanID, "")
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if extra := extraKeys(n.Raw, used); len(extra) > 0 {
+			fmt.Fprintf(w, "  other fields: %s\n", strings.Join(extra, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+	return sc.Err()
+}
+
+// extraKeys lists the attributes that did not become a canonical field.
+func extraKeys(raw map[string]any, used map[string]bool) []string {
+	canonical := map[string]bool{
+		"format": true, event.FieldTimestamp: true, event.FieldService: true,
+		event.FieldDstService: true, event.FieldOperation: true, event.FieldStatusCode: true,
+		"latency_ms": true, event.FieldLevel: true, event.FieldTraceID: true,
+		event.FieldSpanID: true, event.FieldMessage: true,
+	}
+	var keys []string
+	for k := range raw {
+		if !canonical[k] && !used[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ResolveHosts looks each host up through the configured resolver chain.
+func (a *App) ResolveHosts(ctx context.Context, hosts []string, w io.Writer) error {
+	r, err := resolve.FromConfig(a.cfg.Resolve)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return fmt.Errorf("no resolvers configured")
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, h := range hosts {
+		if svc, ok := r.Resolve(ctx, h); ok {
+			fmt.Fprintf(tw, "%s\t%s\n", h, svc)
+		} else {
+			fmt.Fprintf(tw, "%s\t(unresolved)\n", h)
+		}
+	}
+	return tw.Flush()
+}
+
+// RunGraph feeds the log at path (or stdin for "-") through the source's
+// parsers and the resolver, in event time, and writes the resulting call
+// graph.
+func (a *App) RunGraph(ctx context.Context, path, source string, w io.Writer) error {
+	in, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	rp, err := a.newReplayer(source)
+	if err != nil {
+		return err
+	}
+	var cycles [][]string
+	rp.OnGraphEvent = func(ev graph.GraphEvent) {
+		if ev.Type == graph.GraphEventNewCycle {
+			cycles = append(cycles, ev.Cycle)
+		}
+	}
+	st, err := rp.Run(ctx, in, nil)
+	if err != nil {
+		return err
+	}
+
+	snap := rp.Snapshot()
+	edges := snap.Edges
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Src != edges[j].Src {
+			return edges[i].Src < edges[j].Src
+		}
+		if edges[i].Dst != edges[j].Dst {
+			return edges[i].Dst < edges[j].Dst
+		}
+		return edges[i].Operation < edges[j].Operation
+	})
+
+	fmt.Fprintf(w, "%d lines, %d calls: %d services, %d edges\n\n", st.Lines, st.Calls, len(snap.Nodes), len(edges))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SRC\tDST\tOPERATION\tCALLS\tERR%\tAVG\tP99")
+	for _, e := range edges {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.1f%%\t%s\t%s\n",
+			e.Src, e.Dst, e.Operation, e.CallCount, e.ErrorRate()*100,
+			e.AvgLatency().Round(time.Microsecond), e.LatencyP99.Round(time.Microsecond))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, c := range cycles {
+		fmt.Fprintf(w, "\ncycle: %s → %s", strings.Join(c, " → "), c[0])
+	}
+	if len(cycles) > 0 {
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"collector/internal/config"
+)
+
+func TestCheckConfig_MasksSecrets(t *testing.T) {
+	a := New(&config.Config{
+		Sources: map[string]config.SourceConfig{"in": {Type: "file", Path: "/var/log/app.log"}},
+		Sinks:   map[string]config.SinkConfig{"out": {Type: "stdout", Inputs: []string{"in"}}},
+		Expansions: []config.Expansion{
+			{Name: "API_TOKEN", Value: "fallback-token", Default: true},
+			{Name: "DB_PASSWORD", Value: "hunter2", Set: true},
+			{Name: "file:///run/secrets/key", Value: "from-file", Set: true, File: true},
+			{Name: "LOG_DIR", Value: "/var/log", Default: true},
+			{Name: "REGION", Value: "eu-west-1", Set: true},
+		},
+	})
+	var out strings.Builder
+	if err := a.CheckConfig(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"fallback-token", "hunter2", "from-file"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("%q printed:\n%s", secret, out.String())
+		}
+	}
+	for _, want := range []string{"******** (default)", `"/var/log" (default)`, `"eu-west-1"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package clock lets time-driven components run on wall time, on the
+// timestamps of the events being replayed, or on a fake clock that tests
+// move by hand.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real returns the wall clock.
+func Real() Clock { return realClock{} }
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// Or returns c, or the wall clock when c is nil, for components whose
+// clock is an optional field.
+func Or(c Clock) Clock {
+	if c == nil {
+		return realClock{}
+	}
+	return c
+}
+
+// Fake is a clock that only moves when told to, so hours of TTLs and
+// cooldowns can be tested without sleeping.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a fake clock reading t.
+func NewFake(t time.Time) *Fake { return &Fake{now: t} }
+
+func (c *Fake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Fake) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t, backwards if need be.
+func (c *Fake) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// Replay is a clock moved forward by the events being replayed. It starts
+// at the zero time and never runs backwards, so out-of-order events do not
+// rewind TTLs or cooldowns.
+type Replay struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewReplay() *Replay { return &Replay{} }
+
+func (c *Replay) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t if t is later than the current time, and
+// reports whether it moved.
+func (c *Replay) Set(t time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !t.After(c.now) {
+		return false
+	}
+	c.now = t
+	return true
+}
@@ -0,0 +1,43 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	c.Advance(90 * time.Minute)
+	if got := c.Now(); !got.Equal(start.Add(90 * time.Minute)) {
+		t.Errorf("after Advance: %s", got)
+	}
+	c.Set(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Set should move the clock back: %s", got)
+	}
+}
+
+func TestReplay_NeverRunsBackwards(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c := NewReplay()
+	if !c.Set(start) || !c.Now().Equal(start) {
+		t.Fatal("first event should set the clock")
+	}
+	if c.Set(start.Add(-time.Second)) || !c.Now().Equal(start) {
+		t.Error("an out-of-order event moved the clock back")
+	}
+	if !c.Set(start.Add(time.Second)) {
+		t.Error("a later event should move the clock")
+	}
+}
+
+func TestOr(t *testing.T) {
+	f := NewFake(time.Time{})
+	if Or(f) != Clock(f) {
+		t.Error("Or should keep a non-nil clock")
+	}
+	if d := time.Since(Or(nil).Now()); d < 0 || d > time.Minute {
+		t.Error("Or(nil) should be the wall clock")
+	}
+}
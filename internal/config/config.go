@@ -1,6 +1,10 @@
 package config
 
-import "time"
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 type ResolveConfig struct {
 	Static map[string]string `yaml:"static"`
@@ -24,6 +28,10 @@ type AnomalyConfig struct {
 	Threshold       float64 `yaml:"threshold"`
 	CooldownSeconds int     `yaml:"cooldown_seconds"`
 	MinSamples      int     `yaml:"min_samples"`
+
+	// AckFile keeps edges muted from the TUI across restarts; empty uses
+	// the user config directory.
+	AckFile string `yaml:"ack_file,omitempty"`
 }
 
 type Config struct {
@@ -33,6 +41,26 @@ type Config struct {
 	Resolve    ResolveConfig              `yaml:"resolve"`
 	Graph      GraphConfig                `yaml:"graph"`
 	Anomaly    AnomalyConfig              `yaml:"anomaly"`
+
+	// Aliases adds raw keys (dotted paths allowed) for canonical fields
+	// such as service or latency, tried before the built-in ones.
+	Aliases map[string][]string `yaml:"aliases,omitempty"`
+
+	// Include lists files, directories (their *.yml and *.yaml files) or
+	// globs, relative to this file, merged into the config by Load. The
+	// same setting in two files is an error rather than an override.
+	Include []string `yaml:"include,omitempty"`
+
+	// Files lists the files Load read, the including file first.
+	Files []string `yaml:"-"`
+
+	// Expansions lists the environment variables and secret files Load
+	// substituted.
+	Expansions []Expansion `yaml:"-"`
+
+	file  string                // path the config was loaded from, for error positions
+	root  *yaml.Node            // merged top mapping, for error positions
+	files map[*yaml.Node]string // file of every node, when includes were merged
 }
 
 type SourceConfig struct {
@@ -40,6 +68,76 @@ type SourceConfig struct {
 	Service     string `yaml:"service"`
 	Path        string `yaml:"path,omitempty"`
 	ContainerID string `yaml:"container_id,omitempty"`
+
+	// Network sources (tcp, udp, http) listen on Address. MaxLength bounds
+	// a line, frame, datagram or request body in bytes; MaxConnections the
+	// tcp connections or http requests handled at once. Timeout is how long
+	// an http request waits for a full pipeline before a 503.
+	Address        string        `yaml:"address,omitempty"`
+	Framing        string        `yaml:"framing,omitempty"` // tcp: newline (default) or length_prefixed
+	MaxLength      int           `yaml:"max_length,omitempty"`
+	MaxConnections int           `yaml:"max_connections,omitempty"`
+	Timeout        time.Duration `yaml:"timeout,omitempty"`
+	AuthToken      string        `yaml:"auth_token,omitempty"` // http: required bearer token
+	TLS            *TLSConfig    `yaml:"tls,omitempty"`        // tcp, http
+
+	// journald reads "journalctl -o export" (or Path, in that format) for
+	// Units, resuming after the cursor kept in CursorFile.
+	Units      []string `yaml:"units,omitempty"`
+	CursorFile string   `yaml:"cursor_file,omitempty"`
+	Journalctl string   `yaml:"journalctl,omitempty"` // default "journalctl"
+
+	// exec runs Command with Env added to the environment, restarting it
+	// when it exits or, with Interval, running it on that schedule.
+	Command     []string          `yaml:"command,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"`
+	WorkingDir  string            `yaml:"working_dir,omitempty"`
+	Interval    time.Duration     `yaml:"interval,omitempty"`
+	StderrLevel string            `yaml:"stderr_level,omitempty"` // default "error"
+
+	// kafka consumes Topics as a member of Group, committing offsets once
+	// the sinks are done with the events; without a group nothing is
+	// committed.
+	Brokers     []string `yaml:"brokers,omitempty"`
+	Topics      []string `yaml:"topics,omitempty"`
+	Group       string   `yaml:"group,omitempty"`
+	Decoding    string   `yaml:"decoding,omitempty"`     // raw (default) or json
+	StartOffset string   `yaml:"start_offset,omitempty"` // earliest or latest (default)
+
+	// Codec fixes the format of a source and is shorthand for a one-entry
+	// Parsers chain using Template/Patterns/PatternDefinitions below.
+	// Parsers are tried in order; lines none of them recognise are kept as
+	// plain text. Sources with neither use the global auto-detection.
+	Codec              string            `yaml:"codec,omitempty"`
+	Template           string            `yaml:"template,omitempty"`
+	Patterns           []string          `yaml:"patterns,omitempty"`
+	PatternDefinitions map[string]string `yaml:"pattern_definitions,omitempty"`
+	Parsers            []ParserConfig    `yaml:"parsers,omitempty"`
+	KeepOriginal       bool              `yaml:"keep_original,omitempty"`
+	Flatten            *FlattenConfig    `yaml:"flatten,omitempty"`
+}
+
+// TLSConfig serves a listening source over TLS. With ClientCAFile, clients
+// must present a certificate signed by that CA.
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+}
+
+// FlattenConfig rewrites nested attributes as joined keys after parsing.
+type FlattenConfig struct {
+	Separator string `yaml:"separator,omitempty"` // default "."
+	MaxDepth  int    `yaml:"max_depth,omitempty"` // default 5
+}
+
+// ParserConfig is one stage of a source's parse chain: auto, json, ecs,
+// logfmt, template, regex (grok), cef, leef, elb, w3c or none.
+type ParserConfig struct {
+	Type               string            `yaml:"type"`
+	Template           string            `yaml:"template,omitempty"`
+	Patterns           []string          `yaml:"patterns,omitempty"`
+	PatternDefinitions map[string]string `yaml:"pattern_definitions,omitempty"`
 }
 
 type TransformConfig struct {
@@ -47,10 +145,88 @@ type TransformConfig struct {
 	Inputs    []string          `yaml:"inputs"`
 	AddFields map[string]string `yaml:"add_fields"`
 	Case      string            `yaml:"case,omitempty"`
+
+	Mode    string            `yaml:"mode,omitempty"`
+	Metrics []LogMetricConfig `yaml:"metrics,omitempty"`
+
+	GroupBy         []string      `yaml:"group_by,omitempty"`
+	Window          time.Duration `yaml:"window,omitempty"`
+	Slide           time.Duration `yaml:"slide,omitempty"`
+	AllowedLateness time.Duration `yaml:"allowed_lateness,omitempty"`
+
+	KeyFields  []string `yaml:"key_fields,omitempty"`
+	Rate       float64  `yaml:"rate,omitempty"`
+	Burst      int      `yaml:"burst,omitempty"`
+	Overflow   string   `yaml:"overflow,omitempty"`
+	SampleRate int      `yaml:"sample_rate,omitempty"`
+	RouteTo    string   `yaml:"route_to,omitempty"`
+
+	Field            string             `yaml:"field,omitempty"`
+	LevelRates       map[string]float64 `yaml:"level_rates,omitempty"`
+	LatencyThreshold time.Duration      `yaml:"latency_threshold,omitempty"`
+	MaxTraces        int                `yaml:"max_traces,omitempty"`
+
+	Rules       []RedactRuleConfig `yaml:"rules,omitempty"`
+	AllowFields []string           `yaml:"allow_fields,omitempty"`
+	DenyFields  []string           `yaml:"deny_fields,omitempty"`
+	Action      string             `yaml:"action,omitempty"`
+	HashKey     string             `yaml:"hash_key,omitempty"`
+}
+
+// RedactRuleConfig describes one rule of the redact transform: a built-in
+// detector or a custom pattern, and what to do with its matches.
+type RedactRuleConfig struct {
+	Name     string `yaml:"name,omitempty"`
+	Detector string `yaml:"detector,omitempty"`
+	Pattern  string `yaml:"pattern,omitempty"`
+	Action   string `yaml:"action,omitempty"`
+}
+
+// LogMetricConfig describes one metric derived by the log_to_metric transform.
+type LogMetricConfig struct {
+	Type           string            `yaml:"type"`
+	Name           string            `yaml:"name"`
+	Filter         string            `yaml:"filter,omitempty"`
+	Field          string            `yaml:"field,omitempty"`
+	Labels         map[string]string `yaml:"labels,omitempty"`
+	Buckets        []float64         `yaml:"buckets,omitempty"`
+	MaxCardinality int               `yaml:"max_cardinality,omitempty"`
 }
 
 type SinkConfig struct {
 	Type   string   `yaml:"type"`
 	Inputs []string `yaml:"inputs"`
 	Pretty bool     `yaml:"pretty"`
+
+	Endpoint      string            `yaml:"endpoint,omitempty"`
+	Headers       map[string]string `yaml:"headers,omitempty"`
+	BatchSize     int               `yaml:"batch_size,omitempty"`
+	FlushInterval time.Duration     `yaml:"flush_interval,omitempty"`
+	MaxRetries    int               `yaml:"max_retries,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"` // attribute -> label name; default service and le only
+
+	// prometheus_exporter serves its series, with the internal metrics, on
+	// /metrics at Address. -metrics mode replaces the configured sinks, so
+	// the exporter needs a listener of its own.
+	Address string `yaml:"address,omitempty"`
+
+	// kafka produces to Topic, keyed by the Key template; FlushInterval is
+	// how long a batch lingers.
+	Brokers     []string `yaml:"brokers,omitempty"`
+	Topic       string   `yaml:"topic,omitempty"`
+	Key         string   `yaml:"key,omitempty"`
+	Encoding    string   `yaml:"encoding,omitempty"`    // json (default), logfmt or raw; also for file
+	BatchBytes  int      `yaml:"batch_bytes,omitempty"` // default 1 MiB
+	Compression string   `yaml:"compression,omitempty"` // none, gzip, snappy, lz4 or zstd (default); file: none (default), gzip or zstd
+	Acks        string   `yaml:"acks,omitempty"`        // all (default), leader or none
+
+	// file writes to the Path template, rotating by MaxSize and
+	// RotateInterval and keeping MaxFiles or MaxAge of closed segments;
+	// FlushInterval is how often buffered writes are flushed.
+	Path           string        `yaml:"path,omitempty"`
+	MaxSize        int64         `yaml:"max_size,omitempty"` // bytes
+	RotateInterval time.Duration `yaml:"rotate_interval,omitempty"`
+	MaxFiles       int           `yaml:"max_files,omitempty"`
+	MaxAge         time.Duration `yaml:"max_age,omitempty"`
+	Fsync          string        `yaml:"fsync,omitempty"` // never, rotate (default), interval or always
 }
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const typoConfig = `sources:
+  app:
+    type: file
+    pth: /var/log/app.log
+transforms:
+  meta:
+    type: remap-lite
+    inputs: [app]
+    add_feilds:
+      env: dev
+sinks:
+  out:
+    type: stdout
+    inputs: [meta]
+`
+
+func TestParse_UnknownKeys(t *testing.T) {
+	_, err := parse("app.yml", []byte(typoConfig))
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("want 2 errors, got %v", err)
+	}
+	want := []string{
+		`app.yml:4:5: sources.app: unknown field "pth" (did you mean "path"?)`,
+		`app.yml:9:5: transforms.meta: unknown field "add_feilds" (did you mean "add_fields"?)`,
+	}
+	for i, w := range want {
+		if errs[i].Error() != w {
+			t.Errorf("error %d:\n got %s\nwant %s", i, errs[i], w)
+		}
+	}
+}
+
+func TestParse_TypeErrorHasLine(t *testing.T) {
+	_, err := parse("app.yml", []byte("anomaly:\n  window_size: lots\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "app.yml:2: ") {
+		t.Errorf("want an error on line 2, got %v", err)
+	}
+}
+
+func TestValidate_TypeRequirements(t *testing.T) {
+	cfg, err := parse("app.yml", []byte(`sources:
+  app:
+    type: file
+  web:
+    type: websocket
+sinks:
+  bus:
+    type: kafka
+    inputs: [app]
+    brokers: [localhost:9092]
+    topic: logs
+    compression: brotli
+  out:
+    type: prometheus_remote_write
+    inputs: [app, nope]
+  scrape:
+    type: prometheus_exporter
+    inputs: [app]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.Validate()
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("want Errors, got %v", err)
+	}
+	want := []string{
+		"app.yml:2:3: source [app]: file source requires path",
+		"app.yml:5:11: source [web]: unknown type 'websocket' (one of docker, exec, file, http, journald, kafka, stdin, tcp, udp)",
+		"app.yml:12:18: sink [bus]: unknown compression 'brotli' (one of none, gzip, snappy, lz4, zstd)",
+		"app.yml:13:3: sink [out]: prometheus_remote_write sink requires endpoint",
+		"app.yml:15:19: sink [out]: refers to unknown input 'nope'",
+		"app.yml:16:3: sink [scrape]: prometheus_exporter sink requires address",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors:\n%v", len(errs), err)
+	}
+	for i, w := range want {
+		if errs[i].Error() != w {
+			t.Errorf("error %d:\n got %s\nwant %s", i, errs[i], w)
+		}
+	}
+}
+
+func TestValidate_WithoutFile(t *testing.T) {
+	cfg := &Config{
+		Sources: map[string]SourceConfig{"in": {Type: "docker"}},
+		Sinks:   map[string]SinkConfig{"out": {Type: "stdout", Inputs: []string{"in"}}},
+	}
+	err := cfg.Validate()
+	if err == nil || err.Error() != "source [in]: docker source requires container_id" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestLoad_SampleConfigs(t *testing.T) {
+	for _, name := range []string{"config.yml", "config-tui.yml", "config-metrics.yml"} {
+		cfg, err := Load(filepath.Join("..", "..", name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestSchema_UpToDate(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked, err := os.ReadFile(filepath.Join("..", "..", SchemaFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(checked), schema) {
+		t.Errorf("%s is stale; regenerate it with: collector schema > %s", SchemaFile, SchemaFile)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"HOST": "db", "EMPTY": ""}
+	tests := []struct {
+		in, want, err string
+	}{
+		{in: "host: $HOST", want: "host: db"},
+		{in: "host: ${HOST}:5432", want: "host: db:5432"},
+		{in: "host: ${MISSING}", want: "host: "},
+		{in: "host: ${MISSING:-localhost}", want: "host: localhost"},
+		{in: "host: ${EMPTY:-localhost}", want: "host: localhost"},
+		{in: "host: ${HOST:-localhost}", want: "host: db"},
+		{in: "host: ${MISSING:-${HOST}}", want: "host: db"},
+		{in: "host: $${HOST} $$HOST", want: "host: ${HOST} $HOST"},
+		{in: "pattern: ^a$|b$", want: "pattern: ^a$|b$"},
+		{in: "a: 1\nhost: ${MISSING:?set it}", err: "app.yml:2:7: MISSING: set it"},
+		{in: "host: ${EMPTY:?}", err: "app.yml:1:7: EMPTY: required variable is not set"},
+		{in: "host: ${HOST", err: "app.yml:1:7: unterminated ${"},
+		{in: "host: ${HOST-x}", err: "app.yml:1:7: bad substitution ${HOST-x}"},
+	}
+	for _, tt := range tests {
+		x := newExpander()
+		x.lookup = func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		}
+		got, err := x.expandFile("app.yml", tt.in)
+		switch {
+		case tt.err != "":
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: got error %v, want %s", tt.in, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%q: %v", tt.in, err)
+		case got != tt.want:
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad_Secret(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"token": "s3cret\n",
+		"app.yml": `sources:
+  in:
+    type: stdin
+sinks:
+  out:
+    type: prometheus_remote_write
+    inputs: [in]
+    endpoint: http://prom:9090/api/v1/write
+    headers:
+      Authorization: file://token
+`,
+	})
+	cfg, err := Load(filepath.Join(dir, "app.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Sinks["out"].Headers["Authorization"]; got != "s3cret" {
+		t.Errorf("got %q", got)
+	}
+	if len(cfg.Expansions) != 1 || !cfg.Expansions[0].File || cfg.Expansions[0].Name != "file://token" {
+		t.Errorf("expansions: %+v", cfg.Expansions)
+	}
+
+	_, err = parse(filepath.Join(dir, "other.yml"), []byte("sinks:\n  out:\n    endpoint: file://missing\n"))
+	if err == nil || !strings.Contains(err.Error(), "other.yml:3:15: read secret:") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestLoad_Include(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yml": `include: [conf.d, sinks.yml]
+sources:
+  app:
+    type: file
+graph:
+  edge_ttl: 5m
+`,
+		"conf.d/10-app.yml": `sources:
+  app:
+    path: /var/log/app.log
+`,
+		"conf.d/20-web.yaml": `sources:
+  web:
+    type: stdin
+`,
+		"conf.d/notes.txt": "ignored",
+		"sinks.yml": `sinks:
+  out:
+    type: stdout
+    inputs: [app, web]
+`,
+	})
+	cfg, err := Load(filepath.Join(dir, "base.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Sources["app"].Path != "/var/log/app.log" || cfg.Sources["web"].Type != "stdin" || cfg.Sinks["out"].Type != "stdout" {
+		t.Errorf("merged config: %+v", cfg)
+	}
+	if len(cfg.Files) != 4 {
+		t.Errorf("files: %v", cfg.Files)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"conflict.yml": "include: [other.yml]\ngraph:\n  edge_ttl: 5m\n",
+		"other.yml":    "graph:\n  edge_ttl: 1m\n",
+		"a.yml":        "include: [b.yml]\n",
+		"b.yml":        "include: [a.yml]\n",
+		"typo.yml":     "include: [typo-inc.yml]\n",
+		"typo-inc.yml": "sources:\n  app:\n    type: file\n    pth: x\n",
+		"missing.yml":  "include: [nope.yml, 'conf.d/*.yml']\n",
+	})
+	tests := map[string]string{
+		"conflict.yml": "other.yml:2:3: graph.edge_ttl is also set at " + filepath.Join(dir, "conflict.yml") + ":3:3",
+		"a.yml":        "b.yml:1:11: include cycle through " + filepath.Join(dir, "a.yml"),
+		"typo.yml":     `typo-inc.yml:4:5: sources.app: unknown field "pth" (did you mean "path"?)`,
+		"missing.yml":  "missing.yml:1:11: include: stat ",
+	}
+	for name, want := range tests {
+		_, err := Load(filepath.Join(dir, name))
+		if err == nil || !strings.HasPrefix(err.Error(), filepath.Join(dir, want)) {
+			t.Errorf("%s: got %v\nwant %s", name, err, filepath.Join(dir, want))
+		}
+	}
+}
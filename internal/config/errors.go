@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a problem with the config, located in the file when it was
+// loaded from one.
+type Error struct {
+	File   string
+	Line   int // 0 when unknown
+	Column int // 0 when unknown
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File + ":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, "%d:", e.Column)
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	return b.String() + e.Msg
+}
+
+// Errors is every problem found in one pass, in file order.
+type Errors []*Error
+
+func (es Errors) Error() string {
+	lines := make([]string, len(es))
+	for i, e := range es {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// err returns nil for an empty list, so callers can return it directly.
+func (es Errors) err() error {
+	if len(es) == 0 {
+		return nil
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		if es[i].File != es[j].File {
+			return es[i].File < es[j].File
+		}
+		if es[i].Line != es[j].Line {
+			return es[i].Line < es[j].Line
+		}
+		return es[i].Column < es[j].Column
+	})
+	return es
+}
+
+// errorAt reports msg at the node reached by following path from the
+// document root. Path elements are mapping keys, or the value of a list
+// item. A scalar value is pointed at directly, anything else at its key;
+// when the path does not exist, e.g. for a missing required field, the
+// deepest node on the way is used.
+func (c *Config) errorAt(msg string, path ...string) *Error {
+	e := &Error{File: c.file, Msg: msg}
+	if c.root == nil {
+		return e
+	}
+	at := c.root
+	n := c.root
+	for _, key := range path {
+		k, v := child(resolveAlias(n), key)
+		if k == nil {
+			break
+		}
+		at, n = k, v
+	}
+	if v := resolveAlias(n); v.Kind == yaml.ScalarNode {
+		at = v
+	}
+	e.File, e.Line, e.Column = c.fileOf(at), at.Line, at.Column
+	return e
+}
+
+// child returns the key and value nodes of key in mapping n, or the item
+// equal to key in sequence n (as both key and value).
+func child(n *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				return n.Content[i], n.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			if item.Kind == yaml.ScalarNode && item.Value == key {
+				return item, item
+			}
+		}
+	}
+	return nil, nil
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Expansion is one environment variable or secret file substituted into
+// the config.
+type Expansion struct {
+	Name    string
+	Value   string
+	Set     bool // false when the variable was unset
+	Default bool // Value came from a ${VAR:-default}
+	File    bool // Name is a file:// reference; Value is a secret
+}
+
+// expander substitutes environment variables into config text:
+//
+//	$VAR, ${VAR}      the value, "" when unset
+//	${VAR:-default}   default when VAR is unset or empty
+//	${VAR:?message}   an error when VAR is unset or empty
+//	$$                a literal $, so $${VAR} is left as ${VAR}
+//
+// Defaults and messages are expanded in turn. A $ followed by anything
+// else is kept as is. Each variable is recorded once, in order of first
+// use, across every file of the config.
+type expander struct {
+	lookup     func(string) (string, bool)
+	expansions []Expansion
+	seen       map[string]bool
+
+	file string // file being expanded, for errors
+	src  string
+}
+
+func newExpander() *expander {
+	return &expander{lookup: os.LookupEnv, seen: make(map[string]bool)}
+}
+
+func (x *expander) expandFile(file, src string) (string, error) {
+	x.file, x.src = file, src
+	out, errs := x.expand(src, 0)
+	return out, errs.err()
+}
+
+// expand substitutes s, found at offset base of the source.
+func (x *expander) expand(s string, base int) (string, Errors) {
+	var b strings.Builder
+	var errs Errors
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch c := s[i+1]; {
+		case c == '$':
+			b.WriteByte('$')
+			i++
+		case c == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				errs = append(errs, x.errorAt(base+i, "unterminated ${"))
+				b.WriteString(s[i:])
+				return b.String(), errs
+			}
+			v, es := x.braced(s[i+2:end], base+i)
+			b.WriteString(v)
+			errs = append(errs, es...)
+			i = end
+		case isNameStart(c):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			value, set := x.lookup(s[i+1 : j])
+			x.record(Expansion{Name: s[i+1 : j], Value: value, Set: set})
+			b.WriteString(value)
+			i = j - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), errs
+}
+
+// braced expands the expression between "${" and "}", the "$" being at
+// offset at of the source.
+func (x *expander) braced(expr string, at int) (string, Errors) {
+	n := 0
+	for n < len(expr) && isNameChar(expr[n]) {
+		n++
+	}
+	name, op := expr[:n], expr[n:]
+	if name == "" || !isNameStart(name[0]) || (op != "" && !strings.HasPrefix(op, ":-") && !strings.HasPrefix(op, ":?")) {
+		return "", Errors{x.errorAt(at, fmt.Sprintf("bad substitution ${%s}", expr))}
+	}
+	value, set := x.lookup(name)
+	if op == "" || value != "" {
+		x.record(Expansion{Name: name, Value: value, Set: set})
+		return value, nil
+	}
+
+	arg, errs := x.expand(op[2:], at+2+n+2)
+	if op[1] == '?' {
+		if arg == "" {
+			arg = "required variable is not set"
+		}
+		return "", append(errs, x.errorAt(at, name+": "+arg))
+	}
+	x.record(Expansion{Name: name, Value: arg, Set: set, Default: true})
+	return arg, errs
+}
+
+func (x *expander) record(e Expansion) {
+	if !x.seen[e.Name] {
+		x.seen[e.Name] = true
+		x.expansions = append(x.expansions, e)
+	}
+}
+
+func (x *expander) errorAt(offset int, msg string) *Error {
+	line := 1 + strings.Count(x.src[:offset], "\n")
+	col := offset - strings.LastIndexByte(x.src[:offset], '\n')
+	return &Error{File: x.file, Line: line, Column: col, Msg: msg}
+}
+
+// closingBrace returns the index of the "}" closing an expression that
+// starts at start, skipping nested ${...}, or -1.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for j := start; j < len(s); j++ {
+		switch {
+		case s[j] == '$' && j+1 < len(s) && s[j+1] == '$':
+			j++
+		case s[j] == '$' && j+1 < len(s) && s[j+1] == '{':
+			depth++
+			j++
+		case s[j] == '}':
+			if depth == 0 {
+				return j
+			}
+			depth--
+		case s[j] == '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || ('0' <= c && c <= '9')
+}
+
+// secretPrefix marks a value read from a file, e.g. a credential mounted
+// at file:///run/secrets/remote_write_token. Relative paths are relative
+// to the config file.
+const secretPrefix = "file://"
+
+// readSecrets replaces every file:// value under n with the contents of
+// the file, without the trailing newline.
+func (x *expander) readSecrets(n *yaml.Node, file string) Errors {
+	var errs Errors
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, item := range n.Content {
+			errs = append(errs, x.readSecrets(item, file)...)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			errs = append(errs, x.readSecrets(n.Content[i], file)...)
+		}
+	case yaml.ScalarNode:
+		ref, ok := strings.CutPrefix(n.Value, secretPrefix)
+		if !ok {
+			break
+		}
+		path := ref
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, &Error{File: file, Line: n.Line, Column: n.Column, Msg: fmt.Sprintf("read secret: %v", err)})
+			break
+		}
+		secret := strings.TrimRight(string(data), "\r\n")
+		x.record(Expansion{Name: n.Value, Value: secret, Set: true, File: true})
+		n.Value = secret
+		n.Tag = "!!str"
+	}
+	return errs
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loader reads a config file and the files it includes into one node
+// tree, each file expanded, checked and located on its own.
+type loader struct {
+	cfg     *Config
+	env     *expander
+	loading map[string]bool // files on the include stack, for cycles
+	loaded  map[string]bool // files already merged, included once only
+}
+
+// load returns the top mapping of file with its includes merged in, or
+// nil for an empty document.
+func (l *loader) load(file string, data []byte) (*yaml.Node, error) {
+	text, err := l.env.expandFile(file, string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		return nil, decodeErrors(file, err)
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	doc := root.Content[0]
+	l.cfg.track(doc, file)
+
+	var errs Errors
+	l.cfg.checkKeys(doc, reflect.TypeOf(Config{}), "", &errs)
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	// Decoding each file on its own reports type errors with the right
+	// file; the merged tree is decoded again below.
+	if err := doc.Decode(new(Config)); err != nil {
+		return nil, decodeErrors(file, err)
+	}
+	if err := l.env.readSecrets(doc, file).err(); err != nil {
+		return nil, err
+	}
+
+	includes := removeKey(doc, "include")
+	if includes == nil {
+		return doc, nil
+	}
+	for _, item := range includes.Content {
+		files, err := includedFiles(file, item.Value)
+		if err != nil {
+			return nil, &Error{File: file, Line: item.Line, Column: item.Column, Msg: err.Error()}
+		}
+		for _, f := range files {
+			abs, _ := filepath.Abs(f)
+			if l.loading[abs] {
+				return nil, &Error{File: file, Line: item.Line, Column: item.Column, Msg: fmt.Sprintf("include cycle through %s", f)}
+			}
+			if l.loaded[abs] {
+				continue
+			}
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return nil, &Error{File: file, Line: item.Line, Column: item.Column, Msg: fmt.Sprintf("include: %v", err)}
+			}
+			l.cfg.Files = append(l.cfg.Files, f)
+			l.loading[abs] = true
+			sub, err := l.load(f, data)
+			delete(l.loading, abs)
+			l.loaded[abs] = true
+			if err != nil {
+				return nil, err
+			}
+			if sub == nil {
+				continue
+			}
+			l.cfg.merge(doc, sub, "", &errs)
+		}
+	}
+	return doc, errs.err()
+}
+
+// includedFiles resolves one include entry relative to the including
+// file: a file, a directory (its *.yml and *.yaml files) or a glob. A glob
+// may match nothing, so that e.g. an empty conf.d is fine.
+func includedFiles(from, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %v", pattern, err)
+		}
+		return files, nil
+	}
+	fi, err := os.Stat(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("include: %v", err)
+	}
+	if !fi.IsDir() {
+		return []string{pattern}, nil
+	}
+	var files []string
+	for _, ext := range []string{"*.yml", "*.yaml"} {
+		matches, _ := filepath.Glob(filepath.Join(pattern, ext))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// merge adds the keys of mapping src to mapping dst. Mappings present in
+// both, such as sources, are merged key by key; any other key set in both
+// is a conflict, reported at both places.
+func (c *Config) merge(dst, src *yaml.Node, path string, errs *Errors) {
+	dst, src = resolveAlias(dst), resolveAlias(src)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		k, v := src.Content[i], src.Content[i+1]
+		dk, dv := child(dst, k.Value)
+		switch {
+		case dk == nil:
+			dst.Content = append(dst.Content, k, v)
+		case resolveAlias(dv).Kind == yaml.MappingNode && resolveAlias(v).Kind == yaml.MappingNode:
+			c.merge(dv, v, join(path, k.Value), errs)
+		default:
+			*errs = append(*errs, &Error{
+				File: c.fileOf(k), Line: k.Line, Column: k.Column,
+				Msg: fmt.Sprintf("%s is also set at %s", join(path, k.Value), c.position(dk)),
+			})
+		}
+	}
+}
+
+// removeKey deletes key from mapping n and returns its value.
+func removeKey(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			v := n.Content[i+1]
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return v
+		}
+	}
+	return nil
+}
+
+// track records file as the origin of n and everything under it.
+func (c *Config) track(n *yaml.Node, file string) {
+	if c.files == nil {
+		c.files = make(map[*yaml.Node]string)
+	}
+	c.files[n] = file
+	for _, sub := range n.Content {
+		c.track(sub, file)
+	}
+}
+
+func (c *Config) fileOf(n *yaml.Node) string {
+	if f, ok := c.files[n]; ok {
+		return f
+	}
+	return c.file
+}
+
+func (c *Config) position(n *yaml.Node) string {
+	return fmt.Sprintf("%s:%d:%d", c.fileOf(n), n.Line, n.Column)
+}
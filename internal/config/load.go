@@ -3,8 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
-
-	"gopkg.in/yaml.v3"
+	"path/filepath"
 )
 
 func Load(path string) (*Config, error) {
@@ -12,13 +11,35 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read config file: %w", err)
 	}
+	return parse(path, data)
+}
 
-	expandedData := os.ExpandEnv(string(data))
-
-	var cfg Config
-	if err := yaml.Unmarshal([]byte(expandedData), &cfg); err != nil {
-		return nil, fmt.Errorf("parse yaml: %w", err)
+// parse decodes a config strictly: keys the schema does not know are
+// errors, reported together with their line and column. Environment
+// variables are expanded, file:// secrets read and includes merged first,
+// file by file. The node tree is kept so that Validate can locate its
+// errors too.
+func parse(file string, data []byte) (*Config, error) {
+	cfg := &Config{file: file, Files: []string{file}}
+	abs, _ := filepath.Abs(file)
+	l := &loader{
+		cfg:     cfg,
+		env:     newExpander(),
+		loading: map[string]bool{abs: true},
+		loaded:  make(map[string]bool),
+	}
+	doc, err := l.load(file, data)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Expansions = l.env.expansions
+	if doc == nil {
+		return cfg, nil
 	}
+	cfg.root = doc
 
-	return &cfg, nil
-}
\ No newline at end of file
+	if err := doc.Decode(cfg); err != nil {
+		return nil, decodeErrors(file, err)
+	}
+	return cfg, nil
+}
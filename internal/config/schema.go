@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// SchemaFile is where the generated schema is checked in, for editors
+// (e.g. "# yaml-language-server: $schema=config.schema.json").
+const SchemaFile = "config.schema.json"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Schema returns a JSON Schema (draft-07) for the config file, generated
+// from the config types and the component type tables Validate uses.
+func Schema() ([]byte, error) {
+	g := schemaGen{defs: make(map[string]any)}
+	root := g.object(reflect.TypeOf(Config{}))
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "collector config"
+	root["definitions"] = g.defs
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type schemaGen struct {
+	defs map[string]any
+}
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return map[string]any{
+			"type":    []string{"string", "integer"},
+			"pattern": `^(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+$`,
+		}
+	case t.Kind() == reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // break cycles while the definition is built
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/definitions/" + t.Name()}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	}
+	return map[string]any{}
+}
+
+// object describes a struct, closed to unknown keys like Load is, with the
+// type enum and per-type required fields of component structs.
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	for name, f := range yamlFields(t) {
+		props[name] = g.schema(f.Type)
+	}
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+
+	switch t {
+	case reflect.TypeOf(SourceConfig{}):
+		props["codec"] = map[string]any{"type": "string", "enum": parserTypes}
+		enumSchema(props, sourceEnums)
+		componentSchema(s, props, sourceTypes)
+	case reflect.TypeOf(TransformConfig{}):
+		componentSchema(s, props, transformTypes)
+	case reflect.TypeOf(SinkConfig{}):
+		enumSchema(props, sinkEnums)
+		componentSchema(s, props, sinkTypes)
+	case reflect.TypeOf(TLSConfig{}):
+		s["required"] = []string{"cert_file", "key_file"}
+	case reflect.TypeOf(ParserConfig{}):
+		props["type"] = map[string]any{"type": "string", "enum": parserTypes}
+		s["required"] = []string{"type"}
+	}
+	return s
+}
+
+func enumSchema(props map[string]any, enums map[string][]string) {
+	for key, values := range enums {
+		props[key] = map[string]any{"type": "string", "enum": values}
+	}
+}
+
+func componentSchema(s, props map[string]any, types map[string][]string) {
+	props["type"] = map[string]any{"type": "string", "enum": sorted(types)}
+	s["required"] = []string{"type"}
+	var rules []any
+	for _, typ := range sorted(types) {
+		if required := types[typ]; len(required) > 0 {
+			rules = append(rules, map[string]any{
+				"if":   map[string]any{"properties": map[string]any{"type": map[string]any{"const": typ}}},
+				"then": map[string]any{"required": required},
+			})
+		}
+	}
+	if len(rules) > 0 {
+		s["allOf"] = rules
+	}
+}
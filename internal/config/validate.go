@@ -1,43 +1,184 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
 
+// componentTypes lists, per component kind, the known types and the
+// fields each of them requires. The JSON schema is generated from it too.
+var (
+	sourceTypes = map[string][]string{
+		"stdin":    nil,
+		"file":     {"path"},
+		"docker":   {"container_id"},
+		"tcp":      {"address"},
+		"udp":      {"address"},
+		"http":     {"address"},
+		"journald": nil,
+		"exec":     {"command"},
+		"kafka":    {"brokers", "topics"},
+	}
+	transformTypes = map[string][]string{
+		"remap-lite":    nil,
+		"log_to_metric": {"metrics"},
+		"aggregate":     {"window"},
+		"dedupe":        nil,
+		"throttle":      {"rate"},
+		"sample":        nil,
+		"redact":        nil,
+	}
+	sinkTypes = map[string][]string{
+		"stdout":                  nil,
+		"prometheus_remote_write": {"endpoint"},
+		"prometheus_exporter":     {"address"},
+		"kafka":                   {"brokers", "topic"},
+		"file":                    {"path"},
+	}
+)
+
+// parserTypes are the parse chain stages, see ParserConfig.
+var parserTypes = []string{"auto", "json", "ecs", "logfmt", "template", "regex", "grok", "cef", "leef", "elb", "w3c", "none"}
+
+// sourceEnums and sinkEnums list the values allowed for string fields,
+// by yaml key.
+var (
+	sourceEnums = map[string][]string{
+		"framing":      {"newline", "length_prefixed"},
+		"decoding":     {"raw", "json"},
+		"start_offset": {"earliest", "latest"},
+	}
+	sinkEnums = map[string][]string{
+		"encoding":    {"json", "logfmt", "raw"},
+		"compression": {"none", "gzip", "snappy", "lz4", "zstd"},
+		"acks":        {"all", "leader", "none"},
+		"fsync":       {"never", "rotate", "interval", "always"},
+	}
+)
+
+// Validate checks component types, their required fields and the
+// references between components. Every problem is reported, located in
+// the file when the config came from Load.
 func (c *Config) Validate() error {
+	var errs Errors
+
 	if len(c.Sources) == 0 {
-		return fmt.Errorf("at least one source is required")
+		errs = append(errs, c.errorAt("at least one source is required", "sources"))
 	}
-
 	if len(c.Sinks) == 0 {
-		return fmt.Errorf("at least one sink is required")
+		errs = append(errs, c.errorAt("at least one sink is required", "sinks"))
 	}
 
-	for name, t := range c.Transforms {
+	for _, name := range sorted(c.Sources) {
+		s := c.Sources[name]
+		errs = append(errs, c.checkType("source", "sources", name, s.Type, sourceTypes, s)...)
+		if s.Codec != "" && !contains(parserTypes, s.Codec) {
+			errs = append(errs, c.errorAt(fmt.Sprintf("source [%s]: unknown codec '%s'", name, s.Codec), "sources", name, "codec"))
+		}
+		errs = append(errs, c.checkEnums("source", "sources", name, sourceEnums, s)...)
+		if s.TLS != nil && (s.TLS.CertFile == "" || s.TLS.KeyFile == "") {
+			errs = append(errs, c.errorAt(fmt.Sprintf("source [%s]: tls requires cert_file and key_file", name), "sources", name, "tls"))
+		}
+		for i, p := range s.Parsers {
+			if !contains(parserTypes, p.Type) {
+				errs = append(errs, c.errorAt(fmt.Sprintf("source [%s]: parsers[%d]: unknown parser '%s'", name, i, p.Type), "sources", name, "parsers"))
+			}
+		}
+	}
+
+	for _, name := range sorted(c.Transforms) {
+		t := c.Transforms[name]
+		errs = append(errs, c.checkType("transform", "transforms", name, t.Type, transformTypes, t)...)
 		if len(t.Inputs) == 0 {
-			return fmt.Errorf("transform [%s]: inputs list is empty", name)
+			errs = append(errs, c.errorAt(fmt.Sprintf("transform [%s]: inputs list is empty", name), "transforms", name))
 		}
 		for _, inputName := range t.Inputs {
 			if !c.componentExists(inputName) {
-				return fmt.Errorf("transform [%s]: refers to unknown input '%s'", name, inputName)
+				errs = append(errs, c.errorAt(fmt.Sprintf("transform [%s]: refers to unknown input '%s'", name, inputName), "transforms", name, "inputs", inputName))
+			}
+		}
+		if t.RouteTo != "" {
+			if _, ok := c.Sinks[t.RouteTo]; !ok {
+				errs = append(errs, c.errorAt(fmt.Sprintf("transform [%s]: route_to refers to unknown sink '%s'", name, t.RouteTo), "transforms", name, "route_to"))
 			}
 		}
 	}
 
-	for name, s := range c.Sinks {
+	for _, name := range sorted(c.Sinks) {
+		s := c.Sinks[name]
+		errs = append(errs, c.checkType("sink", "sinks", name, s.Type, sinkTypes, s)...)
+		errs = append(errs, c.checkEnums("sink", "sinks", name, sinkEnums, s)...)
 		if len(s.Inputs) == 0 {
-			return fmt.Errorf("sink [%s]: inputs list is empty", name)
+			errs = append(errs, c.errorAt(fmt.Sprintf("sink [%s]: inputs list is empty", name), "sinks", name))
 		}
 		for _, inputName := range s.Inputs {
 			if !c.componentExists(inputName) {
-				return fmt.Errorf("sink [%s]: refers to unknown input '%s'", name, inputName)
+				errs = append(errs, c.errorAt(fmt.Sprintf("sink [%s]: refers to unknown input '%s'", name, inputName), "sinks", name, "inputs", inputName))
 			}
 		}
 	}
 
-	return nil
+	return errs.err()
+}
+
+// checkType reports an unknown component type, or the fields its type
+// requires that v leaves empty.
+func (c *Config) checkType(kind, section, name, typ string, types map[string][]string, v any) Errors {
+	required, ok := types[typ]
+	if !ok {
+		if typ == "" {
+			return Errors{c.errorAt(fmt.Sprintf("%s [%s]: type is required (one of %s)", kind, name, strings.Join(sorted(types), ", ")), section, name)}
+		}
+		return Errors{c.errorAt(fmt.Sprintf("%s [%s]: unknown type '%s' (one of %s)", kind, name, typ, strings.Join(sorted(types), ", ")), section, name, "type")}
+	}
+	var errs Errors
+	fields := yamlFields(reflect.TypeOf(v))
+	rv := reflect.ValueOf(v)
+	for _, key := range required {
+		if rv.FieldByIndex(fields[key].Index).IsZero() {
+			errs = append(errs, c.errorAt(fmt.Sprintf("%s [%s]: %s %s requires %s", kind, name, typ, kind, key), section, name))
+		}
+	}
+	return errs
+}
+
+// checkEnums reports the string fields of v set to a value their enum
+// does not allow.
+func (c *Config) checkEnums(kind, section, name string, enums map[string][]string, v any) Errors {
+	var errs Errors
+	fields := yamlFields(reflect.TypeOf(v))
+	rv := reflect.ValueOf(v)
+	for _, key := range sorted(enums) {
+		value := rv.FieldByIndex(fields[key].Index).String()
+		if value != "" && !contains(enums[key], value) {
+			errs = append(errs, c.errorAt(fmt.Sprintf("%s [%s]: unknown %s '%s' (one of %s)", kind, name, key, value, strings.Join(enums[key], ", ")), section, name, key))
+		}
+	}
+	return errs
 }
 
 func (c *Config) componentExists(name string) bool {
 	_, existsInSources := c.Sources[name]
 	_, existsInTransforms := c.Transforms[name]
 	return existsInSources || existsInTransforms
-}
\ No newline at end of file
+}
+
+func sorted[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package event
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Canonical fields resolved through the alias registry.
+const (
+	FieldTimestamp  = "timestamp"
+	FieldService    = "service"
+	FieldDstService = "dst_service"
+	FieldTraceID    = "trace_id"
+	FieldSpanID     = "span_id"
+	FieldOperation  = "operation"
+	FieldMethod     = "method"
+	FieldURL        = "url"
+	FieldStatusCode = "status_code"
+	FieldLatency    = "latency"
+	FieldLevel      = "level"
+	FieldMessage    = "message"
+)
+
+// defaultAliases lists, per canonical field, the raw keys tried in order.
+// Keys are dotted paths (see Lookup). A latency alias may name the unit of
+// bare numbers after a colon ("event.duration:ns"); otherwise a _s, _us or
+// _ns key suffix decides, and milliseconds are the default.
+var defaultAliases = map[string][]string{
+	FieldTimestamp:  {"ts", "time", "@timestamp", "timestamp", "datetime"},
+	FieldService:    {"src_service", "service", "service.name", "service_name", "app", "application", "component"},
+	FieldDstService: {"dst_service", "upstream", "target", "remote_service", "peer.service", "destination"},
+	FieldTraceID:    {"trace_id", "traceId", "trace.id", "X-Trace-Id", "x-trace-id"},
+	FieldSpanID:     {"span_id", "spanId", "span.id"},
+	FieldOperation:  {"operation", "event", "rpc.method"},
+	FieldMethod:     {"method", "http.method", "http.request.method", "verb"},
+	FieldURL:        {"url", "path", "uri", "http.url", "http.path", "url.path", "url.full", "request"},
+	FieldStatusCode: {"status_code", "status", "http.status", "http.status_code", "http.response.status_code", "code", "http_status"},
+	FieldLatency: {
+		"latency_ms", "latency", "duration", "elapsed", "response_time",
+		"duration_ms", "elapsed_ms", "latency_s", "duration_s",
+		"request_time:s", "event.duration:ns",
+	},
+	FieldLevel:   {"level", "severity", "lvl", "log_level", "log.level"},
+	FieldMessage: {"message", "msg", "log"},
+}
+
+var aliases atomic.Pointer[map[string][]string]
+
+func init() {
+	aliases.Store(&defaultAliases)
+}
+
+// Aliases returns the raw keys tried for a canonical field.
+func Aliases(field string) []string {
+	return (*aliases.Load())[field]
+}
+
+// SetAliases installs extra keys for canonical fields, tried before the
+// built-in ones. A nil map restores the defaults. It is meant to be
+// called once at startup, before any event is parsed.
+func SetAliases(extra map[string][]string) error {
+	merged := make(map[string][]string, len(defaultAliases))
+	for field, keys := range defaultAliases {
+		merged[field] = keys
+	}
+	for field, keys := range extra {
+		defaults, ok := defaultAliases[field]
+		if !ok {
+			return fmt.Errorf("aliases: unknown field %q", field)
+		}
+		merged[field] = append(append([]string(nil), keys...), defaults...)
+	}
+	aliases.Store(&merged)
+	return nil
+}
+
+// ResolveString returns the first non-empty string among the aliases of field.
+func ResolveString(raw map[string]any, field string) string {
+	for _, key := range Aliases(field) {
+		if s, ok := lookupString(raw, key); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// ResolveLevel returns the lower-cased log level.
+func ResolveLevel(raw map[string]any) string {
+	return strings.ToLower(ResolveString(raw, FieldLevel))
+}
+
+// ResolveStatusCode returns the first numeric status code, or 0.
+func ResolveStatusCode(raw map[string]any) int {
+	for _, key := range Aliases(FieldStatusCode) {
+		if code, ok := lookupInt(raw, key); ok && code != 0 {
+			return code
+		}
+	}
+	return 0
+}
+
+// ResolveLatency returns the first parseable latency, or 0.
+func ResolveLatency(raw map[string]any) time.Duration {
+	for _, alias := range Aliases(FieldLatency) {
+		key, unit := latencyUnit(alias)
+		if d, ok := lookupDuration(raw, key, unit); ok && d != 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// ResolveOperation returns the operation alias, or "METHOD url" built
+// from the method and URL aliases.
+func ResolveOperation(raw map[string]any) string {
+	if op := ResolveString(raw, FieldOperation); op != "" {
+		return op
+	}
+	method, url := ResolveString(raw, FieldMethod), ResolveString(raw, FieldURL)
+	switch {
+	case method != "" && url != "":
+		return method + " " + url
+	case method != "":
+		return method
+	}
+	return url
+}
+
+// ResolveKey returns the raw key the Resolve function for field takes its
+// value from, or "" when no alias matches. An operation built from method
+// and URL is reported as "method+url" with the keys found.
+func ResolveKey(raw map[string]any, field string) string {
+	if field == FieldOperation {
+		if key := resolveKey(raw, FieldOperation); key != "" {
+			return key
+		}
+		method, url := resolveKey(raw, FieldMethod), resolveKey(raw, FieldURL)
+		if method != "" && url != "" {
+			return method + "+" + url
+		}
+		return method + url
+	}
+	return resolveKey(raw, field)
+}
+
+// resolveKey applies the test of the matching Resolve function to each
+// alias in turn, so the key reported is the one the resolver settled on.
+func resolveKey(raw map[string]any, field string) string {
+	for _, alias := range Aliases(field) {
+		key, unit := latencyUnit(alias)
+		var found bool
+		switch field {
+		case FieldTimestamp:
+			_, found = timestampAt(raw, alias)
+		case FieldStatusCode:
+			code, ok := lookupInt(raw, alias)
+			found = ok && code != 0
+		case FieldLatency:
+			d, ok := lookupDuration(raw, key, unit)
+			found = ok && d != 0
+		default:
+			_, found = lookupString(raw, alias)
+		}
+		if found {
+			return key
+		}
+	}
+	return ""
+}
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05.999Z",
+	"2006-01-02 15:04:05",
+}
+
+// ResolveTimestamp returns the first parseable timestamp in UTC, or the
+// zero time. Numbers are Unix seconds, or milliseconds above 1e12.
+func ResolveTimestamp(raw map[string]any) time.Time {
+	for _, key := range Aliases(FieldTimestamp) {
+		if t, ok := timestampAt(raw, key); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// timestampAt parses the timestamp at key, if there is one.
+func timestampAt(raw map[string]any, key string) (time.Time, bool) {
+	v, ok := Lookup(raw, key)
+	if !ok {
+		return time.Time{}, false
+	}
+	switch t := v.(type) {
+	case time.Time:
+		return t.UTC(), true
+	case string:
+		for _, layout := range timestampLayouts {
+			if p, err := time.Parse(layout, t); err == nil {
+				return p.UTC(), true
+			}
+		}
+	case float64, int, int64:
+		f, _ := lookupFloat(raw, key)
+		if f > 1e12 {
+			return time.UnixMilli(int64(f)).UTC(), true
+		}
+		return time.Unix(int64(f), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func latencyUnit(alias string) (string, time.Duration) {
+	if key, unit, ok := strings.Cut(alias, ":"); ok {
+		switch unit {
+		case "s":
+			return key, time.Second
+		case "us", "µs":
+			return key, time.Microsecond
+		case "ns":
+			return key, time.Nanosecond
+		}
+		return key, time.Millisecond
+	}
+	switch {
+	case strings.HasSuffix(alias, "_s"):
+		return alias, time.Second
+	case strings.HasSuffix(alias, "_us"):
+		return alias, time.Microsecond
+	case strings.HasSuffix(alias, "_ns"):
+		return alias, time.Nanosecond
+	}
+	return alias, time.Millisecond
+}
@@ -17,6 +17,25 @@ type Event struct {
 	Attrs     map[string]any `json:"attrs,omitempty"`
 	Metric    string         `json:"metric,omitempty"`
 	Value     float64        `json:"value,omitempty"`
+
+	// Ack, set by sources that commit their position (kafka), is called
+	// once the pipeline is done with the event: written by a sink, or
+	// dropped or absorbed by a transform. A sink that fails to deliver an
+	// event leaves it unacknowledged, so that the source reads it again
+	// rather than committing past it; so do the stages that discard events
+	// still in flight at shutdown. See Done.
+	Ack func() `json:"-"`
+
+	// Received is when the collector read the event, for the pipeline's
+	// latency telemetry. Events a transform makes up have none.
+	Received time.Time `json:"-"`
+}
+
+// Done acknowledges the event to its source, if the source asked for it.
+func (e *Event) Done() {
+	if e.Ack != nil {
+		e.Ack()
+	}
 }
 
 // NormalizedEvent is the unified type consumed by all downstream modules.
@@ -35,4 +54,14 @@ type NormalizedEvent struct {
 	Format     string         `json:"format,omitempty"`
 	SourceName string         `json:"source_name,omitempty"`
 	Raw        map[string]any `json:"raw,omitempty"`
+
+	Ack      func()    `json:"-"` // see Event.Ack
+	Received time.Time `json:"-"` // see Event.Received
+}
+
+// Done acknowledges the event to its source, if the source asked for it.
+func (e *NormalizedEvent) Done() {
+	if e.Ack != nil {
+		e.Ack()
+	}
 }
\ No newline at end of file
@@ -315,4 +315,174 @@ func TestNormalize_MetricEvent(t *testing.T) {
 	if n.Raw["metric_value"] != 42.5 {
 		t.Errorf("metric_value not preserved in Raw")
 	}
-}
\ No newline at end of file
+}
+
+// ----- field paths and aliases -----
+
+func TestLookup_DottedPaths(t *testing.T) {
+	m := map[string]any{
+		"http":          map[string]any{"response": map[string]any{"status_code": float64(500)}},
+		"url.path":      "/flat",
+		"kubernetes.io": map[string]any{"pod": "api-0"},
+		"a":             map[string]any{"b.c": "mixed"},
+	}
+	cases := []struct {
+		path string
+		want any
+	}{
+		{"http.response.status_code", float64(500)},
+		{"url.path", "/flat"},
+		{"kubernetes.io.pod", "api-0"},
+		{"a.b.c", "mixed"},
+		{"http.request.method", nil},
+	}
+	for _, tc := range cases {
+		got, ok := event.Lookup(m, tc.path)
+		if ok != (tc.want != nil) || got != tc.want {
+			t.Errorf("Lookup(%q) = %v, %v; want %v", tc.path, got, ok, tc.want)
+		}
+	}
+}
+
+func TestNewFromRaw_NestedAliases(t *testing.T) {
+	raw := map[string]any{
+		"service":  map[string]any{"name": "checkout"},
+		"http":     map[string]any{"method": "POST", "status_code": "502"},
+		"path":     "/pay",
+		"trace":    map[string]any{"id": "t-9"},
+		"duration": "1.5s",
+		"level":    "ERROR",
+	}
+	e, err := event.NewFromRaw(raw, "file")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.SrcService != "checkout" || e.TraceID != "t-9" || e.Operation != "POST /pay" {
+		t.Errorf("unexpected fields: %+v", e)
+	}
+	if e.StatusCode != 502 || e.Latency != 1500*time.Millisecond || e.Level != "error" {
+		t.Errorf("StatusCode/Latency/Level: %d %v %q", e.StatusCode, e.Latency, e.Level)
+	}
+}
+
+func TestSetAliases(t *testing.T) {
+	t.Cleanup(func() { event.SetAliases(nil) })
+
+	if err := event.SetAliases(map[string][]string{"service": {"kubernetes.labels.app"}, "latency": {"took:s"}}); err != nil {
+		t.Fatal(err)
+	}
+	raw := map[string]any{
+		"kubernetes": map[string]any{"labels": map[string]any{"app": "cart"}},
+		"service":    "ignored",
+		"took":       float64(2),
+	}
+	e := event.FromRaw(raw, "docker")
+	if e.SrcService != "cart" || e.Latency != 2*time.Second {
+		t.Errorf("custom aliases not applied: %q %v", e.SrcService, e.Latency)
+	}
+
+	if err := event.SetAliases(map[string][]string{"nope": {"x"}}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+	event.SetAliases(nil)
+	if got := event.FromRaw(raw, "docker").SrcService; got != "ignored" {
+		t.Errorf("defaults not restored, SrcService = %q", got)
+	}
+}
+
+func TestEvent_TypedAccessors(t *testing.T) {
+	e := &event.Event{Attrs: map[string]any{
+		"http": map[string]any{"status": "404", "bytes": float64(12)},
+		"took": "250ms",
+	}}
+	if code, ok := e.GetInt("http.status"); !ok || code != 404 {
+		t.Errorf("GetInt: %d %v", code, ok)
+	}
+	if f, ok := e.GetFloat("http.bytes"); !ok || f != 12 {
+		t.Errorf("GetFloat: %v %v", f, ok)
+	}
+	if d, ok := e.GetDuration("took"); !ok || d != 250*time.Millisecond {
+		t.Errorf("GetDuration: %v %v", d, ok)
+	}
+	if _, ok := e.GetString("http.method"); ok {
+		t.Error("GetString: expected miss")
+	}
+}
+
+func TestToEvent_RoundTrip(t *testing.T) {
+	ts := time.Now()
+	orig := &event.Event{
+		Timestamp: ts,
+		Source:    "file",
+		Service:   "api",
+		Type:      event.TypeLog,
+		Level:     "warn",
+		Message:   "slow",
+		Attrs:     map[string]any{"format": "json", "dst_service": "db", "latency_ms": float64(120)},
+	}
+	n := event.Normalize(orig)
+	n.DstService = "postgres" // resolver enrichment
+	got := n.ToEvent()
+	if got.Message != "slow" || got.Service != "api" || got.Level != "warn" || !got.Timestamp.Equal(ts) {
+		t.Errorf("unexpected event: %+v", got)
+	}
+	if got.Attrs["dst_service"] != "postgres" || got.Attrs["latency_ms"] != float64(120) {
+		t.Errorf("unexpected attrs: %v", got.Attrs)
+	}
+	if _, ok := got.Attrs["message"]; ok {
+		t.Error("message should not be repeated in Attrs")
+	}
+
+	aliased := event.Normalize(&event.Event{Type: event.TypeLog, Attrs: map[string]any{"upstream": "10.0.0.7", "status": float64(200), "latency": "12ms"}})
+	aliased.DstService = "db"
+	got = aliased.ToEvent()
+	if got.Attrs["dst_service"] != "db" {
+		t.Errorf("enrichment of an aliased field is lost: %v", got.Attrs)
+	}
+	if _, ok := got.Attrs["status_code"]; ok {
+		t.Errorf("fields the aliases supply should not be repeated: %v", got.Attrs)
+	}
+	if _, ok := got.Attrs["latency_ms"]; ok {
+		t.Errorf("fields the aliases supply should not be repeated: %v", got.Attrs)
+	}
+
+	metric := &event.Event{Type: event.TypeMetric, Metric: "requests_total", Value: 3, Attrs: map[string]any{"code": "200"}}
+	back := event.Normalize(metric).ToEvent()
+	if back.Type != event.TypeMetric || back.Metric != "requests_total" || back.Value != 3 {
+		t.Errorf("metric round trip: %+v", back)
+	}
+	if _, ok := back.Attrs["status_code"]; ok {
+		t.Error("metric labels should not gain canonical keys")
+	}
+}
+
+func TestResolveKey(t *testing.T) {
+	raw := map[string]any{
+		"time":         "2024-05-01T10:00:00Z",
+		"service":      map[string]any{"name": "api"},
+		"status_code":  0, // ignored by ResolveStatusCode, so not the source
+		"http_status":  "503",
+		"request_time": 0.25,
+		"method":       "GET",
+		"path":         "/users",
+		"ts":           "not a time",
+	}
+	cases := map[string]string{
+		event.FieldTimestamp:  "time",
+		event.FieldService:    "service.name",
+		event.FieldStatusCode: "http_status",
+		event.FieldLatency:    "request_time",
+		event.FieldOperation:  "method+path",
+		event.FieldTraceID:    "",
+	}
+	for field, want := range cases {
+		if got := event.ResolveKey(raw, field); got != want {
+			t.Errorf("ResolveKey(%s) = %q, want %q", field, got, want)
+		}
+	}
+
+	raw["operation"] = "list"
+	if got := event.ResolveKey(raw, event.FieldOperation); got != "operation" {
+		t.Errorf("an explicit operation should win, got %q", got)
+	}
+}
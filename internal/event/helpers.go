@@ -7,101 +7,37 @@ import (
 
 // NewFromRaw builds a NormalizedEvent from a raw map, trying known field aliases.
 func NewFromRaw(raw map[string]any, sourceName string) (*NormalizedEvent, error) {
-	e := &NormalizedEvent{SourceName: sourceName, Raw: raw}
-
-	for _, key := range []string{"timestamp", "ts", "@timestamp", "time"} {
-		if v, ok := raw[key]; ok {
-			switch t := v.(type) {
-			case time.Time:
-				e.Timestamp = t
-			case string:
-				if p, err := time.Parse(time.RFC3339Nano, t); err == nil {
-					e.Timestamp = p
-				} else if p, err := time.Parse(time.RFC3339, t); err == nil {
-					e.Timestamp = p
-				}
-			}
-			if !e.Timestamp.IsZero() {
-				break
-			}
-		}
-	}
+	e := FromRaw(raw, sourceName)
 	if e.Timestamp.IsZero() {
 		e.Timestamp = time.Now()
 	}
-
-	for _, key := range []string{"src_service", "service", "service.name", "source"} {
-		if s, ok := stringVal(raw, key); ok {
-			e.SrcService = s
-			break
-		}
-	}
-	for _, key := range []string{"dst_service", "destination", "peer.service"} {
-		if s, ok := stringVal(raw, key); ok {
-			e.DstService = s
-			break
-		}
-	}
-
-	e.TraceID, _ = stringVal(raw, "trace_id")
-	e.SpanID, _ = stringVal(raw, "span_id")
-
-	for _, key := range []string{"operation", "method", "http.method", "request"} {
-		if s, ok := stringVal(raw, key); ok {
-			e.Operation = s
-			break
-		}
-	}
-
-	for _, key := range []string{"status_code", "status", "http.status_code", "code"} {
-		if v, ok := raw[key]; ok {
-			switch n := v.(type) {
-			case int:
-				e.StatusCode = n
-			case int64:
-				e.StatusCode = int(n)
-			case float64:
-				e.StatusCode = int(n)
-			}
-			if e.StatusCode != 0 {
-				break
-			}
-		}
-	}
-
-	for _, key := range []string{"latency", "duration", "elapsed", "response_time"} {
-		if v, ok := raw[key]; ok {
-			switch n := v.(type) {
-			case time.Duration:
-				e.Latency = n
-			case float64:
-				e.Latency = time.Duration(n * float64(time.Millisecond))
-			case int:
-				e.Latency = time.Duration(n) * time.Millisecond
-			case int64:
-				e.Latency = time.Duration(n) * time.Millisecond
-			}
-			if e.Latency != 0 {
-				break
-			}
-		}
-	}
-
-	for _, key := range []string{"level", "log.level", "severity"} {
-		if s, ok := stringVal(raw, key); ok {
-			e.Level = s
-			break
-		}
-	}
-	e.Format, _ = stringVal(raw, "format")
-
 	if err := e.Validate(); err != nil {
 		return nil, err
 	}
 	return e, nil
 }
 
-// normalize converts a parse-layer Event into a NormalizedEvent.
+// FromRaw applies the alias registry (see Aliases) without defaulting the
+// timestamp or validating, for parsers that fill in the gaps themselves.
+func FromRaw(raw map[string]any, sourceName string) *NormalizedEvent {
+	e := &NormalizedEvent{SourceName: sourceName, Raw: raw}
+	e.Timestamp = ResolveTimestamp(raw)
+	e.SrcService = ResolveString(raw, FieldService)
+	e.DstService = ResolveString(raw, FieldDstService)
+	e.TraceID = ResolveString(raw, FieldTraceID)
+	e.SpanID = ResolveString(raw, FieldSpanID)
+	e.Operation = ResolveOperation(raw)
+	e.StatusCode = ResolveStatusCode(raw)
+	e.Latency = ResolveLatency(raw)
+	e.Level = ResolveLevel(raw)
+	e.Format, _ = stringVal(raw, "format")
+	return e
+}
+
+// Normalize converts a parse-layer Event into a NormalizedEvent. Parsers
+// store the fields they resolved under the canonical keys, which lead
+// every alias list, so a line normalizes the same whether it went through
+// ParseEvent + Normalize or straight through ParseNormalized.
 func Normalize(evt *Event) *NormalizedEvent {
 	n := &NormalizedEvent{
 		Timestamp:  evt.Timestamp,
@@ -109,17 +45,19 @@ func Normalize(evt *Event) *NormalizedEvent {
 		Level:      evt.Level,
 		SourceName: evt.Source,
 		Raw:        make(map[string]any),
+		Ack:        evt.Ack,
+		Received:   evt.Received,
 	}
 	for k, v := range evt.Attrs {
 		n.Raw[k] = v
 	}
 	n.Format, _ = stringVal(evt.Attrs, "format")
-	n.TraceID, _ = stringVal(evt.Attrs, "trace_id")
-	n.SpanID, _ = stringVal(evt.Attrs, "span_id")
-	n.DstService, _ = stringVal(evt.Attrs, "dst_service")
-	n.Operation, _ = stringVal(evt.Attrs, "operation")
-	n.StatusCode = attrsStatusCode(evt.Attrs)
-	n.Latency = attrsLatency(evt.Attrs)
+	n.TraceID = ResolveString(evt.Attrs, FieldTraceID)
+	n.SpanID = ResolveString(evt.Attrs, FieldSpanID)
+	n.DstService = ResolveString(evt.Attrs, FieldDstService)
+	n.Operation = ResolveOperation(evt.Attrs)
+	n.StatusCode = ResolveStatusCode(evt.Attrs)
+	n.Latency = ResolveLatency(evt.Attrs)
 
 	if evt.Type == TypeMetric && evt.Metric != "" {
 		n.Operation = evt.Metric
@@ -131,35 +69,57 @@ func Normalize(evt *Event) *NormalizedEvent {
 	return n
 }
 
-func attrsStatusCode(attrs map[string]any) int {
-	for _, key := range []string{"status_code", "status", "http.status", "code", "http_status"} {
-		v, ok := attrs[key]
-		if !ok {
-			continue
-		}
-		switch n := v.(type) {
-		case float64:
-			return int(n)
-		case int:
-			return n
+// ToEvent is the inverse of Normalize, for consumers still written
+// against Event. For log events a resolved field is written back under its
+// canonical attribute key only when the attributes do not already resolve
+// to that value, so enrichment such as a resolved service shows up while
+// lines pass through unchanged.
+func (e *NormalizedEvent) ToEvent() Event {
+	evt := Event{
+		Timestamp: e.Timestamp,
+		Source:    e.SourceName,
+		Service:   e.SrcService,
+		Type:      TypeLog,
+		Level:     e.Level,
+		Attrs:     make(map[string]any, len(e.Raw)),
+		Ack:       e.Ack,
+		Received:  e.Received,
+	}
+	for k, v := range e.Raw {
+		switch k {
+		case "message":
+			evt.Message, _ = v.(string)
+		case "metric_value":
+			evt.Type = TypeMetric
+			evt.Metric = e.Operation
+			evt.Value, _ = v.(float64)
+		default:
+			evt.Attrs[k] = v
 		}
 	}
-	return 0
-}
-
-func attrsLatency(attrs map[string]any) time.Duration {
-	for _, key := range []string{"latency_ms", "duration_ms", "elapsed_ms", "latency", "duration", "elapsed"} {
-		v, ok := attrs[key]
-		if !ok {
-			continue
-		}
-		ms, ok := v.(float64)
-		if !ok {
-			continue
-		}
-		return time.Duration(ms * float64(time.Millisecond))
+	if evt.Type == TypeMetric {
+		return evt // attributes are series labels; keep them as they were
+	}
+	raw := e.Raw
+	if e.TraceID != "" && e.TraceID != ResolveString(raw, FieldTraceID) {
+		evt.Attrs["trace_id"] = e.TraceID
+	}
+	if e.SpanID != "" && e.SpanID != ResolveString(raw, FieldSpanID) {
+		evt.Attrs["span_id"] = e.SpanID
+	}
+	if e.DstService != "" && e.DstService != ResolveString(raw, FieldDstService) {
+		evt.Attrs["dst_service"] = e.DstService
+	}
+	if e.Operation != "" && e.Operation != ResolveOperation(raw) {
+		evt.Attrs["operation"] = e.Operation
+	}
+	if e.StatusCode != 0 && e.StatusCode != ResolveStatusCode(raw) {
+		evt.Attrs["status_code"] = float64(e.StatusCode)
+	}
+	if e.Latency != 0 && e.Latency != ResolveLatency(raw) {
+		evt.Attrs["latency_ms"] = float64(e.Latency) / float64(time.Millisecond)
 	}
-	return 0
+	return evt
 }
 
 // CorrelationKey returns TraceID if set, otherwise "src->dst:operation".
@@ -0,0 +1,122 @@
+package event
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lookup resolves a dotted path such as "http.response.status_code" in m.
+// A key holding the whole path wins over nested objects, and partially
+// flattened documents ({"http.response": {"status_code": 500}}) are
+// walked as well.
+func Lookup(m map[string]any, path string) (any, bool) {
+	if m == nil {
+		return nil, false
+	}
+	if v, ok := m[path]; ok {
+		return v, true
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+		if sub, ok := m[path[:i]].(map[string]any); ok {
+			if v, ok := Lookup(sub, path[i+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Get returns the attribute at a dotted path.
+func (e *Event) Get(path string) (any, bool) { return Lookup(e.Attrs, path) }
+
+// GetString returns the non-empty string attribute at path.
+func (e *Event) GetString(path string) (string, bool) { return lookupString(e.Attrs, path) }
+
+// GetInt returns the attribute at path as an int, parsing numeric strings.
+func (e *Event) GetInt(path string) (int, bool) { return lookupInt(e.Attrs, path) }
+
+// GetFloat returns the attribute at path as a float64, parsing numeric strings.
+func (e *Event) GetFloat(path string) (float64, bool) { return lookupFloat(e.Attrs, path) }
+
+// GetDuration returns the attribute at path as a duration. Bare numbers
+// are milliseconds; strings may carry a unit ("87ms", "0.2s").
+func (e *Event) GetDuration(path string) (time.Duration, bool) {
+	return lookupDuration(e.Attrs, path, time.Millisecond)
+}
+
+// Get returns the raw field at a dotted path.
+func (e *NormalizedEvent) Get(path string) (any, bool) { return Lookup(e.Raw, path) }
+
+// GetString returns the non-empty string field at path.
+func (e *NormalizedEvent) GetString(path string) (string, bool) { return lookupString(e.Raw, path) }
+
+// GetInt returns the field at path as an int, parsing numeric strings.
+func (e *NormalizedEvent) GetInt(path string) (int, bool) { return lookupInt(e.Raw, path) }
+
+// GetFloat returns the field at path as a float64, parsing numeric strings.
+func (e *NormalizedEvent) GetFloat(path string) (float64, bool) { return lookupFloat(e.Raw, path) }
+
+// GetDuration returns the field at path as a duration. Bare numbers are
+// milliseconds; strings may carry a unit ("87ms", "0.2s").
+func (e *NormalizedEvent) GetDuration(path string) (time.Duration, bool) {
+	return lookupDuration(e.Raw, path, time.Millisecond)
+}
+
+func lookupString(m map[string]any, path string) (string, bool) {
+	v, _ := Lookup(m, path)
+	s, ok := v.(string)
+	return s, ok && s != ""
+}
+
+func lookupFloat(m map[string]any, path string) (float64, bool) {
+	v, ok := Lookup(m, path)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func lookupInt(m map[string]any, path string) (int, bool) {
+	f, ok := lookupFloat(m, path)
+	return int(f), ok
+}
+
+// lookupDuration reads a duration; unit applies to bare numbers.
+func lookupDuration(m map[string]any, path string, unit time.Duration) (time.Duration, bool) {
+	v, ok := Lookup(m, path)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case time.Duration:
+		return n, true
+	case string:
+		s := strings.TrimSpace(n)
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.Duration(f * float64(unit)), true
+		}
+		// time.ParseDuration rejects bare numbers but accepts "87ms",
+		// "0.2s", "1m30s" and "µs"/"us".
+		d, err := time.ParseDuration(strings.ReplaceAll(s, " ", ""))
+		return d, err == nil
+	}
+	f, ok := lookupFloat(m, path)
+	return time.Duration(f * float64(unit)), ok
+}
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"collector/internal/clock"
+	"collector/internal/event"
 	"collector/internal/metrics"
 )
 
@@ -18,13 +20,30 @@ type NormalizedEvent struct {
 	OccurredAt time.Time
 }
 
+// FromEvent maps a pipeline event onto the graph; 5xx statuses count as
+// errors. It returns nil when either end of the call is unknown.
+func FromEvent(n *event.NormalizedEvent) *NormalizedEvent {
+	if n.SrcService == "" || n.DstService == "" {
+		return nil
+	}
+	return &NormalizedEvent{
+		SrcService: n.SrcService,
+		DstService: n.DstService,
+		Operation:  n.Operation,
+		IsError:    n.StatusCode >= 500,
+		Latency:    n.Latency,
+		OccurredAt: n.Timestamp,
+	}
+}
+
 func edgeKey(src, dst, op string) string {
 	return fmt.Sprintf("%s|%s|%s", src, dst, op)
 }
 
 type CallGraph struct {
-	mu  sync.RWMutex
-	cfg Config
+	mu    sync.RWMutex
+	cfg   Config
+	clock clock.Clock
 
 	nodes map[NodeID]struct{}
 	edges map[string]*Edge
@@ -47,6 +66,7 @@ func NewWithConfig(cfg Config) *CallGraph {
 	cfg.applyDefaults()
 	g := &CallGraph{
 		cfg:        cfg,
+		clock:      clock.Real(),
 		nodes:      make(map[NodeID]struct{}),
 		edges:      make(map[string]*Edge),
 		knownEdges: make(map[string]bool),
@@ -82,7 +102,7 @@ func (g *CallGraph) Feed(ev *NormalizedEvent) {
 	if !exists {
 		now := ev.OccurredAt
 		if now.IsZero() {
-			now = time.Now()
+			now = g.clock.Now()
 		}
 		edge = &Edge{
 			Src:       src,
@@ -95,7 +115,7 @@ func (g *CallGraph) Feed(ev *NormalizedEvent) {
 
 	ts := ev.OccurredAt
 	if ts.IsZero() {
-		ts = time.Now()
+		ts = g.clock.Now()
 	}
 	edge.CallCount++
 	edge.LatencySum += ev.Latency
@@ -168,6 +188,7 @@ func (g *CallGraph) emit(ev GraphEvent) {
 	select {
 	case g.events <- ev:
 	default:
+		metrics.GraphEventsDropped.Inc()
 	}
 }
 
@@ -240,7 +261,7 @@ func (g *CallGraph) Snapshot() CallGraphSnapshot {
 	return CallGraphSnapshot{
 		Nodes: nodes,
 		Edges: edges,
-		At:    time.Now(),
+		At:    g.clock.Now(),
 	}
 }
 
@@ -253,13 +274,16 @@ func (g *CallGraph) staleSweeper(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			g.sweepStale()
+			g.SweepStale()
 		}
 	}
 }
 
-func (g *CallGraph) sweepStale() {
-	deadline := time.Now().Add(-g.cfg.EdgeTTL)
+// SweepStale drops edges not seen for EdgeTTL. Start runs it every
+// StaleScanInterval; a replay calls it as event time advances instead.
+func (g *CallGraph) SweepStale() {
+	now := g.clock.Now()
+	deadline := now.Add(-g.cfg.EdgeTTL)
 
 	g.mu.Lock()
 	var gone []Edge
@@ -273,7 +297,6 @@ func (g *CallGraph) sweepStale() {
 	g.rebuildNodes()
 	g.mu.Unlock()
 
-	now := time.Now()
 	for _, e := range gone {
 		g.emit(GraphEvent{
 			Type:      GraphEventEdgeGone,
@@ -300,3 +323,9 @@ func (g *CallGraph) WithAnomalyDetector(d anomalyFeeder) *CallGraph {
 	g.detector = d
 	return g
 }
+
+// WithClock makes the graph read time from c instead of the wall clock.
+func (g *CallGraph) WithClock(c clock.Clock) *CallGraph {
+	g.clock = c
+	return g
+}
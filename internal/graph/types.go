@@ -2,6 +2,8 @@ package graph
 
 import (
 	"time"
+
+	"collector/internal/stats"
 )
 
 type NodeID = string
@@ -68,21 +70,7 @@ func (e *Edge) addLatency(d time.Duration) {
 }
 
 func calcP99(vals []time.Duration) time.Duration {
-	if len(vals) == 0 {
-		return 0
-	}
-
-	sorted := make([]time.Duration, len(vals))
-	copy(sorted, vals)
-
-	for i := 1; i < len(sorted); i++ {
-		for j := i; j > 0 && sorted[j] < sorted[j-1]; j-- {
-			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
-		}
-	}
-
-	idx := int(float64(len(sorted)-1) * 0.99)
-	return sorted[idx]
+	return stats.Percentile(vals, 0.99)
 }
 
 type GraphEvent struct {
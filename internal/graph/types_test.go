@@ -1,10 +1,11 @@
 package graph
 
 import (
-	"context"
 	"sort"
 	"testing"
 	"time"
+
+	"collector/internal/clock"
 )
 
 func makeEvent(src, dst, op string, latency time.Duration, isErr bool) *NormalizedEvent {
@@ -307,17 +308,27 @@ func TestGraph_NoCycle_DAG(t *testing.T) {
 	}
 }
 
+// staleGraph returns a graph on a fake clock that starts now, the time
+// makeEvent stamps events with.
+func staleGraph() (*CallGraph, *clock.Fake) {
+	clk := clock.NewFake(time.Now())
+	return New(64).WithClock(clk), clk
+}
+
 func TestGraph_Events_EdgeGone(t *testing.T) {
-	cfg := defaultConfig()
-	cfg.EdgeTTL = 50 * time.Millisecond
-	cfg.StaleScanInterval = 20 * time.Millisecond
-	g := NewWithConfig(cfg)
-	g.Start(context.Background())
+	g, clk := staleGraph()
 
 	g.Feed(makeEvent("A", "B", "op", 0, false))
-	time.Sleep(200 * time.Millisecond)
+	clk.Advance(4 * time.Minute)
+	g.SweepStale()
+	if evs := drainEvents(g.Events(), 20*time.Millisecond); len(filterByType(evs, GraphEventEdgeGone)) != 0 {
+		t.Fatal("edge expired before its TTL")
+	}
 
-	evs := drainEvents(g.Events(), 100*time.Millisecond)
+	clk.Advance(2 * time.Minute)
+	g.SweepStale()
+
+	evs := drainEvents(g.Events(), 20*time.Millisecond)
 	goneEvs := filterByType(evs, GraphEventEdgeGone)
 	if len(goneEvs) == 0 {
 		t.Fatal("expected EdgeGone event after TTL expiry")
@@ -325,17 +336,17 @@ func TestGraph_Events_EdgeGone(t *testing.T) {
 	if goneEvs[0].Edge.Src != "A" || goneEvs[0].Edge.Dst != "B" {
 		t.Errorf("wrong edge in EdgeGone event: %+v", goneEvs[0].Edge)
 	}
+	if !goneEvs[0].Timestamp.Equal(clk.Now()) {
+		t.Errorf("EdgeGone at %s, want the sweep time %s", goneEvs[0].Timestamp, clk.Now())
+	}
 }
 
 func TestGraph_EdgeGone_RemovesFromEdges(t *testing.T) {
-	cfg := defaultConfig()
-	cfg.EdgeTTL = 30 * time.Millisecond
-	cfg.StaleScanInterval = 10 * time.Millisecond
-	g := NewWithConfig(cfg)
-	g.Start(context.Background())
+	g, clk := staleGraph()
 
 	g.Feed(makeEvent("X", "Y", "op", 0, false))
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(time.Hour)
+	g.SweepStale()
 
 	if len(g.Edges()) != 0 {
 		t.Errorf("stale edge should be removed, got %d edges", len(g.Edges()))
@@ -343,23 +354,34 @@ func TestGraph_EdgeGone_RemovesFromEdges(t *testing.T) {
 }
 
 func TestGraph_EdgeGone_ReportedAsNewAfterRemoval(t *testing.T) {
-	cfg := defaultConfig()
-	cfg.EdgeTTL = 30 * time.Millisecond
-	cfg.StaleScanInterval = 10 * time.Millisecond
-	g := NewWithConfig(cfg)
-	g.Start(context.Background())
+	g, clk := staleGraph()
 
 	g.Feed(makeEvent("A", "B", "op", 0, false))
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(time.Hour)
+	g.SweepStale()
 
-	g.Feed(makeEvent("A", "B", "op", 0, false))
+	g.Feed(&NormalizedEvent{SrcService: "A", DstService: "B", Operation: "op", OccurredAt: clk.Now()})
 
-	evs := drainEvents(g.Events(), 100*time.Millisecond)
+	evs := drainEvents(g.Events(), 20*time.Millisecond)
 	if len(filterByType(evs, GraphEventNewEdge)) < 2 {
 		t.Errorf("expected 2 NewEdge events (initial + re-appear), got %d", len(filterByType(evs, GraphEventNewEdge)))
 	}
 }
 
+func TestGraph_Clock(t *testing.T) {
+	g, clk := staleGraph()
+	clk.Advance(48 * time.Hour)
+
+	g.Feed(&NormalizedEvent{SrcService: "A", DstService: "B", Operation: "op"})
+	e := g.Edges()[0]
+	if !e.FirstSeen.Equal(clk.Now()) || !e.LastSeen.Equal(clk.Now()) {
+		t.Errorf("events without a time should take the clock's: %+v", e)
+	}
+	if !g.Snapshot().At.Equal(clk.Now()) {
+		t.Error("snapshot should be stamped by the clock")
+	}
+}
+
 func TestCycleKey_Normalisation(t *testing.T) {
 	k1 := cycleKey([]NodeID{"A", "B", "C", "A"})
 	k2 := cycleKey([]NodeID{"B", "C", "A", "B"})
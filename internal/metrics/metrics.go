@@ -64,13 +64,93 @@ var (
 		Help: "Total error calls per service edge (src→dst)",
 	}, []string{"src", "dst"})
 
+	LogToMetricOverflow = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logshipper_log_to_metric_cardinality_overflow_total",
+		Help: "Derived metric samples folded into the overflow series by the cardinality limit",
+	}, []string{"metric"})
+
+	AggregateLateEvents = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "logshipper_aggregate_late_events_total",
+		Help: "Events dropped by the aggregate transform because their window had already closed",
+	})
+
+	TransformDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logshipper_transform_dropped_total",
+		Help: "Events removed from the main stream by a transform, by reason",
+	}, []string{"transform", "reason"})
+
+	Redactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logshipper_redactions_total",
+		Help: "Values scrubbed by the redact transform, by rule",
+	}, []string{"rule"})
+
 	EdgeLatencyMs = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "logshipper_edge_latency_ms",
 		Help:    "Call latency per service edge in milliseconds",
 		Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000},
 	}, []string{"src", "dst"})
+
+	GraphEventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "logshipper_graph_events_dropped_total",
+		Help: "Graph events (new edges, cycles) dropped because no reader kept up",
+	})
+
+	AnomalyEventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "logshipper_anomaly_events_dropped_total",
+		Help: "Anomaly events dropped because no reader kept up",
+	})
+
+	KafkaPendingRecords = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "logshipper_kafka_pending_records",
+		Help: "Records read from a partition whose offset is not committable yet",
+	}, []string{"topic"})
+
+	KafkaRecordsAbandoned = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logshipper_kafka_records_abandoned_total",
+		Help: "Unacknowledged records committed past because too many later ones were pending",
+	}, []string{"topic"})
 )
 
+// Per-component telemetry, labelled by kind (source, transform, sink or
+// route) and the component's name in the config.
+var (
+	ComponentReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logshipper_component_received_events_total",
+		Help: "Events taken in by a component",
+	}, []string{"kind", "component"})
+
+	ComponentSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logshipper_component_sent_events_total",
+		Help: "Events passed on by a component; for sinks, events written",
+	}, []string{"kind", "component"})
+
+	ComponentBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logshipper_component_received_bytes_total",
+		Help: "Raw message bytes read by a source",
+	}, []string{"kind", "component"})
+
+	ComponentErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logshipper_component_errors_total",
+		Help: "Components that stopped with an error",
+	}, []string{"kind", "component"})
+
+	ComponentLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "logshipper_component_latency_seconds",
+		Help:    "Time from a source reading an event to a component passing it on (sinks: writing it)",
+		Buckets: []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5, 30},
+	}, []string{"kind", "component"})
+
+	ChannelUtilization = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "logshipper_channel_utilization",
+		Help: "Fill ratio (0 to 1) of the buffered channel feeding a pipeline stage",
+	}, []string{"channel"})
+)
+
+// Register adds a dynamically created collector to the registry served by Handler.
+func Register(c prometheus.Collector) error {
+	return prometheus.DefaultRegisterer.Register(c)
+}
+
 func Handler() http.Handler {
 	return promhttp.Handler()
 }
@@ -0,0 +1,308 @@
+package parse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"collector/internal/event"
+)
+
+// CEF and LEEF are the ArcSight and IBM QRadar event formats emitted by
+// firewalls, proxies and other security appliances:
+//
+//	CEF:0|Vendor|Product|1.0|100|Blocked request|7|src=10.0.0.1 dst=10.0.0.2 request=/login
+//	LEEF:2.0|Vendor|Product|1.0|4625|^|src=10.0.0.1^dst=10.0.0.2^usrName=bob
+//
+// Either may be prefixed by a syslog header, which is kept in
+// Raw["syslog_header"].
+
+var cefHeaderNames = []string{"cef_version", "device_vendor", "device_product", "device_version", "signature_id", "name", "severity"}
+
+var leefHeaderNames = []string{"leef_version", "device_vendor", "device_product", "device_version", "event_id"}
+
+// cefKey finds the start of each extension key; values may contain spaces,
+// but an unescaped "=" always ends a key.
+var cefKey = regexp.MustCompile(`(?:^|\s)([A-Za-z0-9_.\[\]-]+)=`)
+
+var cefTimeLayouts = []string{
+	"Jan 02 2006 15:04:05.000 MST",
+	"Jan 02 2006 15:04:05 MST",
+	"Jan 02 2006 15:04:05.000",
+	"Jan 02 2006 15:04:05",
+	"Jan 2 2006 15:04:05",
+	time.RFC3339Nano,
+}
+
+// IsCEF reports whether line carries a CEF header.
+func IsCEF(line string) bool {
+	_, ok := formatIndex(line, "CEF:")
+	return ok
+}
+
+// IsLEEF reports whether line carries a LEEF header.
+func IsLEEF(line string) bool {
+	_, ok := formatIndex(line, "LEEF:")
+	return ok
+}
+
+// formatIndex locates "CEF:<digit>" or "LEEF:<digit>" at the start of
+// line or after a syslog header.
+func formatIndex(line, tag string) (int, bool) {
+	i := strings.Index(line, tag)
+	if i < 0 || i+len(tag) >= len(line) {
+		return 0, false
+	}
+	if i > 0 && line[i-1] != ' ' {
+		return 0, false
+	}
+	c := line[i+len(tag)]
+	return i, c >= '0' && c <= '9' && strings.Contains(line[i:], "|")
+}
+
+// DecodeCEF splits a CEF line into header fields and extensions.
+// Returns nil when line is not CEF.
+func DecodeCEF(line string) map[string]any {
+	i, ok := formatIndex(line, "CEF:")
+	if !ok {
+		return nil
+	}
+	parts := splitCEFHeader(line[i+len("CEF:"):], len(cefHeaderNames))
+	if len(parts) < len(cefHeaderNames) {
+		return nil
+	}
+
+	raw := make(map[string]any, len(parts)+8)
+	if i > 0 {
+		raw["syslog_header"] = strings.TrimSpace(line[:i])
+	}
+	for j, name := range cefHeaderNames {
+		raw[name] = parts[j]
+	}
+	if len(parts) > len(cefHeaderNames) {
+		for k, v := range decodeCEFExtension(parts[len(cefHeaderNames)]) {
+			raw[k] = v
+		}
+	}
+	return raw
+}
+
+// DecodeLEEF splits a LEEF 1.0 or 2.0 line into header fields and
+// attributes. Returns nil when line is not LEEF.
+func DecodeLEEF(line string) map[string]any {
+	i, ok := formatIndex(line, "LEEF:")
+	if !ok {
+		return nil
+	}
+	rest := line[i+len("LEEF:"):]
+	parts := strings.SplitN(rest, "|", len(leefHeaderNames)+1)
+	if len(parts) < len(leefHeaderNames) {
+		return nil
+	}
+
+	raw := make(map[string]any, len(parts)+8)
+	if i > 0 {
+		raw["syslog_header"] = strings.TrimSpace(line[:i])
+	}
+	for j, name := range leefHeaderNames {
+		raw[name] = parts[j]
+	}
+	if len(parts) <= len(leefHeaderNames) {
+		return raw
+	}
+
+	// LEEF 1.0 separates attributes with tabs; 2.0 names its delimiter in
+	// an extra header field, either literally or as hex (x5E, 0x5E).
+	ext, delim := parts[len(leefHeaderNames)], "\t"
+	if strings.HasPrefix(parts[0], "2") {
+		if d, tail, ok := strings.Cut(ext, "|"); ok {
+			delim, ext = leefDelimiter(d), tail
+		}
+	}
+	for _, kv := range strings.Split(ext, delim) {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		raw[strings.TrimSpace(k)] = v
+	}
+	return raw
+}
+
+func leefDelimiter(d string) string {
+	if d == "" {
+		return "\t"
+	}
+	lower := strings.ToLower(d)
+	if hex := strings.TrimPrefix(strings.TrimPrefix(lower, "0x"), "x"); hex != lower {
+		if n, err := strconv.ParseUint(hex, 16, 8); err == nil {
+			return string(rune(n))
+		}
+	}
+	return d
+}
+
+// splitCEFHeader splits on unescaped pipes, unescaping \| and \\ in the
+// first n fields and returning the remainder as field n+1.
+func splitCEFHeader(s string, n int) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if len(parts) == n {
+			return append(parts, s[i:])
+		}
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == '|':
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	if len(parts) == n {
+		return append(parts, "")
+	}
+	return parts
+}
+
+func decodeCEFExtension(s string) map[string]string {
+	out := make(map[string]string)
+	locs := cefKey.FindAllStringSubmatchIndex(s, -1)
+	for j, loc := range locs {
+		end := len(s)
+		if j+1 < len(locs) {
+			end = locs[j+1][0]
+		}
+		key := s[loc[2]:loc[3]]
+		out[key] = unescapeCEF(strings.TrimSpace(s[loc[1]:end]))
+	}
+	return out
+}
+
+var cefUnescaper = strings.NewReplacer(`\=`, "=", `\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+func unescapeCEF(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return cefUnescaper.Replace(s)
+}
+
+// ParseCEF fills evt from a decoded CEF line.
+func ParseCEF(evt *event.Event, raw map[string]any) {
+	fillEvent(evt, cefNormalized(raw, evt.Source))
+}
+
+// ParseCEFNormalized maps a decoded CEF line into a NormalizedEvent.
+func ParseCEFNormalized(raw map[string]any, sourceName string) *event.NormalizedEvent {
+	return withNow(cefNormalized(raw, sourceName))
+}
+
+// ParseLEEF fills evt from a decoded LEEF line.
+func ParseLEEF(evt *event.Event, raw map[string]any) {
+	fillEvent(evt, leefNormalized(raw, evt.Source))
+}
+
+// ParseLEEFNormalized maps a decoded LEEF line into a NormalizedEvent.
+func ParseLEEFNormalized(raw map[string]any, sourceName string) *event.NormalizedEvent {
+	return withNow(leefNormalized(raw, sourceName))
+}
+
+func cefNormalized(raw map[string]any, sourceName string) *event.NormalizedEvent {
+	n := &event.NormalizedEvent{Format: "cef", SourceName: sourceName, Raw: raw}
+	n.SrcService = firstString(raw, "shost", "src", "sourceTranslatedAddress")
+	n.DstService = firstString(raw, "dhost", "dst", "destinationTranslatedAddress")
+	n.Level = securityLevel(firstString(raw, "severity"))
+	n.Timestamp = securityTime(firstString(raw, "rt", "end", "start"))
+	n.Operation = securityOperation(raw, "requestMethod", "request", "name")
+	n.StatusCode = extractStatusCode(raw)
+	n.Latency = extractLatency(raw)
+	if _, ok := raw["message"]; !ok {
+		if msg := firstString(raw, "msg", "name"); msg != "" {
+			raw["message"] = msg
+		}
+	}
+	return n
+}
+
+func leefNormalized(raw map[string]any, sourceName string) *event.NormalizedEvent {
+	n := &event.NormalizedEvent{Format: "leef", SourceName: sourceName, Raw: raw}
+	n.SrcService = firstString(raw, "srcHost", "src", "srcPreNAT")
+	n.DstService = firstString(raw, "dstHost", "dst", "dstPreNAT")
+	n.Level = securityLevel(firstString(raw, "sev"))
+	n.Timestamp = securityTime(firstString(raw, "devTime"))
+	n.Operation = securityOperation(raw, "method", "url", "event_id")
+	n.StatusCode = extractStatusCode(raw)
+	n.Latency = extractLatency(raw)
+	if _, ok := raw["message"]; !ok {
+		if msg := firstString(raw, "msg", "event_id"); msg != "" {
+			raw["message"] = msg
+		}
+	}
+	return n
+}
+
+// securityLevel maps a 0-10 CEF/LEEF severity (or its CEF name) to a level.
+func securityLevel(sev string) string {
+	switch strings.ToLower(sev) {
+	case "":
+		return ""
+	case "low", "unknown":
+		return "info"
+	case "medium":
+		return "warn"
+	case "high", "very-high", "very high":
+		return "error"
+	}
+	n, err := strconv.Atoi(sev)
+	switch {
+	case err != nil:
+		return ""
+	case n >= 7:
+		return "error"
+	case n >= 4:
+		return "warn"
+	default:
+		return "info"
+	}
+}
+
+// securityTime parses epoch milliseconds or the "MMM dd yyyy HH:mm:ss"
+// layouts used by CEF rt and LEEF devTime.
+func securityTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC()
+	}
+	for _, layout := range cefTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// securityOperation prefers "method url" and falls back to the event name.
+func securityOperation(raw map[string]any, methodKey, urlKey, nameKey string) string {
+	method, url := firstString(raw, methodKey), firstString(raw, urlKey)
+	switch {
+	case method != "" && url != "":
+		return method + " " + url
+	case url != "":
+		return url
+	}
+	return firstString(raw, nameKey)
+}
+
+func withNow(n *event.NormalizedEvent) *event.NormalizedEvent {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+	return n
+}
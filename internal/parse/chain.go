@@ -0,0 +1,220 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+// Parser names accepted by NewParser.
+const (
+	ParserAuto     = "auto"
+	ParserJSON     = "json"
+	ParserECS      = "ecs"
+	ParserLogfmt   = "logfmt"
+	ParserTemplate = "template"
+	ParserRegex    = "regex"
+	ParserGrok     = "grok"
+	ParserCEF      = "cef"
+	ParserLEEF     = "leef"
+	ParserELB      = "elb"
+	ParserW3C      = "w3c"
+	ParserNone     = "none"
+)
+
+// TemplatePresets are well-known templates accepted by name wherever a
+// template string is expected.
+var TemplatePresets = map[string]string{
+	"nginx":  `$remote_addr - $remote_user [$time_local] "$method $request $protocol" $status $body_bytes_sent "$http_referer" "$http_user_agent"`,
+	"apache": `$remote_addr $ident $remote_user [$time_local] "$method $request $protocol" $status $body_bytes_sent`,
+}
+
+// Parser parses evt.Message in place, reporting whether it recognised the
+// line. Unrecognised lines must be left untouched.
+type Parser interface {
+	ParseEvent(evt *event.Event) bool
+}
+
+// ParserOptions carries the settings of the parsers that need them.
+type ParserOptions struct {
+	Template           string
+	Patterns           []string
+	PatternDefinitions map[string]string
+}
+
+// NewParser builds one stage of a parse chain.
+func NewParser(name string, opts ParserOptions) (Parser, error) {
+	switch name {
+	case ParserAuto:
+		return autoParser{}, nil
+	case ParserJSON:
+		return jsonParser{}, nil
+	case ParserECS:
+		return ecsParser{}, nil
+	case ParserLogfmt:
+		return logfmtParser{}, nil
+	case ParserCEF:
+		return cefParser{}, nil
+	case ParserLEEF:
+		return leefParser{}, nil
+	case ParserELB:
+		return elbParser{}, nil
+	case ParserW3C:
+		return NewW3CParser(), nil
+	case ParserNone:
+		return noneParser{}, nil
+	case ParserTemplate:
+		tmpl := opts.Template
+		if preset, ok := TemplatePresets[tmpl]; ok {
+			tmpl = preset
+		}
+		if tmpl == "" {
+			return nil, fmt.Errorf("parser %q requires a template", name)
+		}
+		return NewTemplateParser(tmpl)
+	case ParserRegex, ParserGrok:
+		return NewGrokParser(opts.Patterns, opts.PatternDefinitions)
+	}
+	return nil, fmt.Errorf("unknown parser %q", name)
+}
+
+// Chain runs a per-source list of parsers in order; the first that
+// recognises a line wins. A line no parser recognises is marked plain, so
+// events leaving a Chain always carry a format and skip ParseEvent's
+// auto-detection. Put ParserAuto last to fall back to auto-detection.
+type Chain struct {
+	Parsers []Parser
+	// KeepOriginal stores the unparsed line in Attrs["original"].
+	KeepOriginal bool
+	// Flatten, when set, flattens nested Attrs after parsing.
+	Flatten *Flattener
+}
+
+// Consume forwards control lines to parsers that track state across
+// lines (W3C "#Fields:" directives).
+func (c *Chain) Consume(evt *event.Event) bool {
+	for _, p := range c.Parsers {
+		if lc, ok := p.(interface{ Consume(*event.Event) bool }); ok && lc.Consume(evt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Chain) ParseEvent(evt *event.Event) bool {
+	original := evt.Message
+	matched := false
+	for _, p := range c.Parsers {
+		if p.ParseEvent(evt) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		MarkPlain(evt)
+		metrics.ParseTotal.WithLabelValues("plain").Inc()
+	}
+	if c.Flatten != nil {
+		c.Flatten.FlattenEvent(evt)
+	}
+	if c.KeepOriginal {
+		ensureAttrs(evt)
+		evt.Attrs["original"] = original
+	}
+	return true
+}
+
+type autoParser struct{}
+
+func (autoParser) ParseEvent(evt *event.Event) bool {
+	ParseEvent(evt)
+	return true
+}
+
+type noneParser struct{}
+
+func (noneParser) ParseEvent(evt *event.Event) bool {
+	MarkPlain(evt)
+	metrics.ParseTotal.WithLabelValues("plain").Inc()
+	return true
+}
+
+type jsonParser struct{}
+
+func (jsonParser) ParseEvent(evt *event.Event) bool {
+	var raw map[string]any
+	if !tryUnmarshalJSON(strings.TrimSpace(evt.Message), &raw) {
+		return false
+	}
+	if ParseMetric(evt, raw) {
+		metrics.ParseTotal.WithLabelValues("metric").Inc()
+		return true
+	}
+	ParseJSON(evt, raw)
+	metrics.ParseTotal.WithLabelValues("json").Inc()
+	return true
+}
+
+type ecsParser struct{}
+
+func (ecsParser) ParseEvent(evt *event.Event) bool {
+	var raw map[string]any
+	if !tryUnmarshalJSON(strings.TrimSpace(evt.Message), &raw) {
+		return false
+	}
+	ParseECS(evt, raw)
+	metrics.ParseTotal.WithLabelValues("ecs").Inc()
+	return true
+}
+
+// logfmtParser trusts the source, so unlike auto-detection a single
+// key=value pair is enough.
+type logfmtParser struct{}
+
+func (logfmtParser) ParseEvent(evt *event.Event) bool {
+	raw, pairs, _, ok := splitLogfmt(strings.TrimSpace(evt.Message))
+	if !ok || pairs == 0 {
+		return false
+	}
+	ParseLogfmt(evt, raw)
+	metrics.ParseTotal.WithLabelValues("logfmt").Inc()
+	return true
+}
+
+type cefParser struct{}
+
+func (cefParser) ParseEvent(evt *event.Event) bool {
+	raw := DecodeCEF(strings.TrimSpace(evt.Message))
+	if raw == nil {
+		return false
+	}
+	ParseCEF(evt, raw)
+	metrics.ParseTotal.WithLabelValues("cef").Inc()
+	return true
+}
+
+type leefParser struct{}
+
+func (leefParser) ParseEvent(evt *event.Event) bool {
+	raw := DecodeLEEF(strings.TrimSpace(evt.Message))
+	if raw == nil {
+		return false
+	}
+	ParseLEEF(evt, raw)
+	metrics.ParseTotal.WithLabelValues("leef").Inc()
+	return true
+}
+
+type elbParser struct{}
+
+func (elbParser) ParseEvent(evt *event.Event) bool {
+	raw, format := DecodeELB(strings.TrimSpace(evt.Message))
+	if raw == nil {
+		return false
+	}
+	ParseELB(evt, raw, format)
+	metrics.ParseTotal.WithLabelValues(format).Inc()
+	return true
+}
@@ -25,139 +25,63 @@ func IsECS(raw map[string]any) bool {
 	return false
 }
 
-func ParseECS(evt *event.Event, raw map[string]any) {
-	ensureAttrs(evt)
-	evt.Attrs["format"] = "ecs_json"
-
-	if evt.Type == "" {
-		evt.Type = event.TypeLog
-	}
-
-	if ts, ok := raw["@timestamp"].(string); ok {
-		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
-			evt.Timestamp = t
-		} else if t, err := time.Parse(time.RFC3339, ts); err == nil {
-			evt.Timestamp = t
-		}
-	}
-
-	if msg, ok := raw["message"].(string); ok && msg != "" {
-		evt.Message = msg
-	} else if msg, ok := raw["msg"].(string); ok && msg != "" {
-		evt.Message = msg
-	}
-
-	if lvl, ok := raw["log.level"].(string); ok && lvl != "" {
-		evt.Level = strings.ToLower(lvl)
-	} else if logObj, ok := raw["log"].(map[string]any); ok {
-		if lvl, ok := logObj["level"].(string); ok && lvl != "" {
-			evt.Level = strings.ToLower(lvl)
-		}
-	}
-
-	if svc := ecsService(raw); svc != "" {
-		evt.Service = svc
-	}
-
-	applyTimestamp(evt, raw)
+// ecsConsumed are the keys ParseECS folds into the Event's own fields.
+var ecsConsumed = map[string]bool{
+	"ts": true, "time": true, "@timestamp": true, "message": true, "msg": true,
+	"log.level": true, "log": true,
+}
 
-	for k, v := range raw {
-		switch k {
-		case "ts", "time", "@timestamp", "message", "msg", "log.level", "log":
-			continue
-		}
-		evt.Attrs[k] = v
-	}
+func ParseECS(evt *event.Event, raw map[string]any) {
+	fillStructured(evt, raw, ecsNormalized(raw, evt.Source), ecsConsumed)
 }
 
 // ParseECSNormalized maps ECS fields into a NormalizedEvent.
 func ParseECSNormalized(raw map[string]any, sourceName string) *event.NormalizedEvent {
+	return withNow(ecsNormalized(raw, sourceName))
+}
+
+// ecsNormalized reads the ECS field set by dotted path, so nested objects
+// and flattened keys ("http.response.status_code") are treated alike.
+func ecsNormalized(raw map[string]any, sourceName string) *event.NormalizedEvent {
 	n := &event.NormalizedEvent{
 		Format:     "ecs_json",
 		SourceName: sourceName,
 		Raw:        raw,
 	}
 
-	if ts, ok := raw["@timestamp"].(string); ok {
+	if ts := firstString(raw, "@timestamp"); ts != "" {
 		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
 			n.Timestamp = t.UTC()
-		} else if t, err := time.Parse(time.RFC3339, ts); err == nil {
-			n.Timestamp = t.UTC()
 		}
 	}
-	if n.Timestamp.IsZero() {
-		n.Timestamp = time.Now().UTC()
-	}
-
-	if logObj, ok := raw["log"].(map[string]any); ok {
-		if lvl, ok := logObj["level"].(string); ok {
-			n.Level = strings.ToLower(lvl)
-		}
-	}
-	if n.Level == "" {
-		if lvl, ok := raw["log.level"].(string); ok {
-			n.Level = strings.ToLower(lvl)
-		}
-	}
-
+	n.Level = strings.ToLower(firstString(raw, "log.level"))
 	n.SrcService = ecsService(raw)
+	n.TraceID = firstString(raw, "trace.id")
+	n.SpanID = firstString(raw, "span.id")
+	n.DstService = firstString(raw, "destination.address", "server.address")
 
-	if traceObj, ok := raw["trace"].(map[string]any); ok {
-		n.TraceID, _ = traceObj["id"].(string)
-	}
-	if spanObj, ok := raw["span"].(map[string]any); ok {
-		n.SpanID, _ = spanObj["id"].(string)
-	}
-
-	if httpObj, ok := raw["http"].(map[string]any); ok {
-		if respObj, ok := httpObj["response"].(map[string]any); ok {
-			if code, ok := respObj["status_code"].(float64); ok {
-				n.StatusCode = int(code)
-			}
+	if v, ok := event.Lookup(raw, "http.response.status_code"); ok {
+		if code, ok := v.(float64); ok {
+			n.StatusCode = int(code)
 		}
 	}
-
-	if evtObj, ok := raw["event"].(map[string]any); ok {
-		if ns, ok := evtObj["duration"].(float64); ok && ns > 0 {
+	if v, ok := event.Lookup(raw, "event.duration"); ok {
+		if ns, ok := v.(float64); ok && ns > 0 {
 			n.Latency = time.Duration(int64(ns))
 		}
 	}
 
-	var method, urlPath string
-	if httpObj, ok := raw["http"].(map[string]any); ok {
-		if reqObj, ok := httpObj["request"].(map[string]any); ok {
-			method, _ = reqObj["method"].(string)
-		}
-	}
-	if urlObj, ok := raw["url"].(map[string]any); ok {
-		urlPath, _ = urlObj["path"].(string)
-		if urlPath == "" {
-			urlPath, _ = urlObj["full"].(string)
-		}
-	}
+	method := strings.ToUpper(firstString(raw, "http.request.method"))
+	urlPath := firstString(raw, "url.path", "url.full")
 	if method != "" && urlPath != "" {
-		n.Operation = strings.ToUpper(method) + " " + urlPath
+		n.Operation = method + " " + urlPath
 	} else if method != "" {
-		n.Operation = strings.ToUpper(method)
-	}
-
-	if dstObj, ok := raw["destination"].(map[string]any); ok {
-		n.DstService, _ = dstObj["address"].(string)
-	}
-	if n.DstService == "" {
-		if srvObj, ok := raw["server"].(map[string]any); ok {
-			n.DstService, _ = srvObj["address"].(string)
-		}
+		n.Operation = method
 	}
 
 	return n
 }
 
 func ecsService(raw map[string]any) string {
-	if svcObj, ok := raw["service"].(map[string]any); ok {
-		if name, ok := svcObj["name"].(string); ok && name != "" {
-			return name
-		}
-	}
-	return ""
-}
\ No newline at end of file
+	return firstString(raw, "service.name")
+}
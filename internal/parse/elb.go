@@ -0,0 +1,184 @@
+package parse
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"collector/internal/event"
+)
+
+// Field layouts of AWS load balancer access logs. Application Load
+// Balancer lines start with the request type; Classic ELB lines start
+// with the timestamp and use "backend" where ALB says "target".
+var (
+	albFields = []string{
+		"type", "time", "elb", "client", "target",
+		"request_processing_time", "target_processing_time", "response_processing_time",
+		"elb_status_code", "target_status_code", "received_bytes", "sent_bytes",
+		"request", "user_agent", "ssl_cipher", "ssl_protocol", "target_group_arn",
+		"trace_id", "domain_name", "chosen_cert_arn", "matched_rule_priority",
+		"request_creation_time", "actions_executed", "redirect_url", "error_reason",
+		"target_list", "target_status_code_list", "classification", "classification_reason",
+	}
+	elbFields = []string{
+		"time", "elb", "client", "backend",
+		"request_processing_time", "backend_processing_time", "response_processing_time",
+		"elb_status_code", "backend_status_code", "received_bytes", "sent_bytes",
+		"request", "user_agent", "ssl_cipher", "ssl_protocol",
+	}
+)
+
+var albTypes = map[string]bool{"http": true, "https": true, "h2": true, "grpcs": true, "ws": true, "wss": true}
+
+// DecodeELB splits an ALB or Classic ELB access log line into named
+// fields. Returns the raw map and the format ("alb" or "elb"), or nil
+// when the line matches neither layout. "-" values are omitted.
+func DecodeELB(line string) (map[string]any, string) {
+	tokens := splitQuoted(line)
+
+	var names []string
+	var format string
+	switch {
+	case len(tokens) >= 13 && albTypes[tokens[0]] && isRFC3339(tokens[1]) && strings.Contains(tokens[3], ":"):
+		names, format = albFields, "alb"
+	case len(tokens) >= 12 && isRFC3339(tokens[0]) && strings.Contains(tokens[2], ":"):
+		names, format = elbFields, "elb"
+	default:
+		return nil, ""
+	}
+
+	raw := make(map[string]any, len(tokens))
+	for i, tok := range tokens {
+		if i >= len(names) || tok == "-" || tok == "" {
+			continue
+		}
+		raw[names[i]] = tok
+	}
+	return raw, format
+}
+
+// ParseELB fills evt from a decoded ALB/ELB access log line.
+func ParseELB(evt *event.Event, raw map[string]any, format string) {
+	fillEvent(evt, elbNormalized(raw, format, evt.Source))
+}
+
+// ParseELBNormalized maps a decoded ALB/ELB line into a NormalizedEvent:
+// client -> target becomes the edge, the load balancer's three processing
+// times add up to the latency.
+func ParseELBNormalized(raw map[string]any, format, sourceName string) *event.NormalizedEvent {
+	return withNow(elbNormalized(raw, format, sourceName))
+}
+
+func elbNormalized(raw map[string]any, format, sourceName string) *event.NormalizedEvent {
+	n := &event.NormalizedEvent{Format: format, SourceName: sourceName, Raw: raw}
+	n.Timestamp = extractTimestamp(raw)
+	n.SrcService = hostOnly(firstString(raw, "client"))
+	n.DstService = hostOnly(firstString(raw, "target", "backend"))
+	n.TraceID = firstString(raw, "trace_id")
+
+	if code, err := strconv.Atoi(firstString(raw, "elb_status_code")); err == nil {
+		n.StatusCode = code
+	}
+
+	var total float64
+	complete := true
+	for _, key := range []string{
+		"request_processing_time",
+		firstKey(raw, "target_processing_time", "backend_processing_time"),
+		"response_processing_time",
+	} {
+		sec, err := strconv.ParseFloat(firstString(raw, key), 64)
+		if err != nil || sec < 0 { // -1 when the target never answered
+			complete = false
+			break
+		}
+		total += sec
+	}
+	if complete {
+		n.Latency = time.Duration(total * float64(time.Second))
+	}
+
+	if method, path, ok := splitRequestLine(firstString(raw, "request")); ok {
+		n.Operation = method + " " + path
+	}
+	n.Level = levelFromStatus(n.StatusCode)
+	return n
+}
+
+// splitQuoted splits s on spaces, keeping double-quoted fields together
+// (without their quotes).
+func splitQuoted(s string) []string {
+	var out []string
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case ' ', '\t':
+			i++
+		case '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				out = append(out, s[i+1:])
+				return out
+			}
+			out = append(out, s[i+1:i+1+end])
+			i += end + 2
+		default:
+			end := strings.IndexAny(s[i:], " \t")
+			if end < 0 {
+				return append(out, s[i:])
+			}
+			out = append(out, s[i:i+end])
+			i += end
+		}
+	}
+	return out
+}
+
+// splitRequestLine turns "GET https://host:443/path?q HTTP/1.1" into
+// ("GET", "/path").
+func splitRequestLine(req string) (method, path string, ok bool) {
+	parts := strings.Fields(req)
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	path = parts[1]
+	if u, err := url.Parse(parts[1]); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	return parts[0], path, true
+}
+
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
+
+func firstKey(raw map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if _, ok := raw[k]; ok {
+			return k
+		}
+	}
+	return keys[0]
+}
+
+func levelFromStatus(code int) string {
+	switch {
+	case code >= 500:
+		return "error"
+	case code >= 400:
+		return "warn"
+	case code > 0:
+		return "info"
+	}
+	return ""
+}
+
+func isRFC3339(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
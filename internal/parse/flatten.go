@@ -0,0 +1,50 @@
+package parse
+
+import "collector/internal/event"
+
+// DefaultFlattenDepth is used when a Flattener's MaxDepth is zero.
+const DefaultFlattenDepth = 5
+
+// Flattener rewrites nested objects in Attrs as separator-joined keys:
+// {"http":{"status":500}} becomes {"http.status":500}. Objects deeper
+// than MaxDepth are kept whole under their joined key; arrays are kept
+// as-is.
+type Flattener struct {
+	Separator string
+	MaxDepth  int
+}
+
+// Flatten returns a flattened copy of m.
+func (f Flattener) Flatten(m map[string]any) map[string]any {
+	sep, depth := f.Separator, f.MaxDepth
+	if sep == "" {
+		sep = "."
+	}
+	if depth <= 0 {
+		depth = DefaultFlattenDepth
+	}
+	out := make(map[string]any, len(m))
+	flattenInto(out, "", m, sep, depth)
+	return out
+}
+
+// FlattenEvent flattens evt.Attrs in place.
+func (f Flattener) FlattenEvent(evt *event.Event) {
+	if len(evt.Attrs) > 0 {
+		evt.Attrs = f.Flatten(evt.Attrs)
+	}
+}
+
+func flattenInto(out map[string]any, prefix string, m map[string]any, sep string, depth int) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + sep + k
+		}
+		if sub, ok := v.(map[string]any); ok && depth > 1 && len(sub) > 0 {
+			flattenInto(out, key, sub, sep, depth-1)
+			continue
+		}
+		out[key] = v
+	}
+}
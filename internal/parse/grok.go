@@ -0,0 +1,298 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+// GrokPatterns is the standard pattern library available to every
+// GrokParser. Patterns may reference each other with %{NAME}.
+var GrokPatterns = map[string]string{
+	"USERNAME":   `[a-zA-Z0-9._-]+`,
+	"USER":       `%{USERNAME}`,
+	"INT":        `[+-]?[0-9]+`,
+	"BASE10NUM":  `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+	"NUMBER":     `%{BASE10NUM}`,
+	"POSINT":     `[1-9][0-9]*`,
+	"NONNEGINT":  `[0-9]+`,
+	"WORD":       `\w+`,
+	"NOTSPACE":   `\S+`,
+	"SPACE":      `\s*`,
+	"DATA":       `.*?`,
+	"GREEDYDATA": `.*`,
+	"QS":         `%{QUOTEDSTRING}`,
+
+	"QUOTEDSTRING": `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"UUID":         `[A-Fa-f0-9]{8}-[A-Fa-f0-9]{4}-[A-Fa-f0-9]{4}-[A-Fa-f0-9]{4}-[A-Fa-f0-9]{12}`,
+
+	"IPV4":     `(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])`,
+	"IPV6":     `(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}|(?:[0-9A-Fa-f]{1,4}:){1,7}:|(?:[0-9A-Fa-f]{1,4}:){1,6}:[0-9A-Fa-f]{1,4}|(?:[0-9A-Fa-f]{1,4}:){1,5}(?::[0-9A-Fa-f]{1,4}){1,2}|(?:[0-9A-Fa-f]{1,4}:){1,4}(?::[0-9A-Fa-f]{1,4}){1,3}|(?:[0-9A-Fa-f]{1,4}:){1,3}(?::[0-9A-Fa-f]{1,4}){1,4}|(?:[0-9A-Fa-f]{1,4}:){1,2}(?::[0-9A-Fa-f]{1,4}){1,5}|[0-9A-Fa-f]{1,4}:(?::[0-9A-Fa-f]{1,4}){1,6}|:(?:(?::[0-9A-Fa-f]{1,4}){1,7}|:)`,
+	"IP":       `%{IPV6}|%{IPV4}`,
+	"HOSTNAME": `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST": `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT": `%{IPORHOST}:%{POSINT}`,
+
+	"PATH":         `(?:/[^\s?#]*)+`,
+	"URIPARAM":     `\?[^\s#]*`,
+	"URIPATHPARAM": `%{PATH}(?:%{URIPARAM})?`,
+	"HTTPMETHOD":   `GET|HEAD|POST|PUT|DELETE|CONNECT|OPTIONS|TRACE|PATCH`,
+
+	"MONTH":             `\b(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|June?|July?|Aug(?:ust)?|Sep(?:tember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\b`,
+	"MONTHNUM":          `0?[1-9]|1[0-2]`,
+	"MONTHDAY":          `(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9]`,
+	"YEAR":              `[0-9]{4}`,
+	"HOUR":              `2[0123]|[01]?[0-9]`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}:%{SECOND}`,
+	"ISO8601_TIMEZONE":  `Z|[+-]%{HOUR}(?::?%{MINUTE})`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?(?:%{ISO8601_TIMEZONE})?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-]?%{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+
+	"LOGLEVEL": `(?i:trace|debug|info(?:rmation)?|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+
+	"COMMONAPACHELOG":   `%{IPORHOST:remote_addr} %{NOTSPACE:ident} %{NOTSPACE:auth} \[%{HTTPDATE:timestamp}\] "%{WORD:method} %{NOTSPACE:path}(?: HTTP/%{NUMBER:http_version})?" %{INT:status:int} (?:%{INT:bytes:int}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} "%{DATA:referrer}" "%{DATA:user_agent}"`,
+}
+
+// Capture types accepted as the third part of %{PATTERN:field:type}.
+const (
+	grokString   = "string"
+	grokInt      = "int"
+	grokFloat    = "float"
+	grokBool     = "bool"
+	grokDuration = "duration" // "12ms", "1.5s" -> milliseconds as float
+)
+
+const (
+	maxGrokDepth    = 32
+	grokGroupPrefix = "_grok"
+)
+
+var grokRef = regexp.MustCompile(`%\{(\w+)(?::([\w.@-]+))?(?::(\w+))?\}`)
+
+// GrokParser matches plain text lines against an ordered list of grok
+// expressions and returns the named captures of the first one that matches.
+// Expressions may mix %{PATTERN:field:type} references with plain RE2
+// syntax, including (?P<field>...) groups, so the same parser also serves
+// as the "regex" parser.
+type GrokParser struct {
+	exprs []*grokExpr
+}
+
+type grokExpr struct {
+	src    string
+	re     *regexp.Regexp
+	fields map[int]grokField // submatch index -> field
+}
+
+type grokField struct {
+	name string
+	typ  string
+}
+
+// NewGrokParser compiles patterns in order. custom adds to, or overrides,
+// the standard GrokPatterns library.
+func NewGrokParser(patterns []string, custom map[string]string) (*GrokParser, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("GrokParser: at least one pattern is required")
+	}
+	lib := make(map[string]string, len(GrokPatterns)+len(custom))
+	for k, v := range GrokPatterns {
+		lib[k] = v
+	}
+	for k, v := range custom {
+		lib[k] = v
+	}
+
+	p := &GrokParser{}
+	for _, src := range patterns {
+		expr, err := compileGrok(src, lib)
+		if err != nil {
+			return nil, fmt.Errorf("GrokParser: pattern %q: %w", src, err)
+		}
+		p.exprs = append(p.exprs, expr)
+	}
+	return p, nil
+}
+
+// Parse returns the typed captures of the first matching pattern, or nil
+// when none match. Optional groups that did not participate are omitted.
+func (p *GrokParser) Parse(line string) map[string]any {
+	line = strings.TrimSpace(line)
+	for _, expr := range p.exprs {
+		m := expr.re.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		fields := make(map[string]any, len(expr.fields))
+		for i, f := range expr.fields {
+			if m[2*i] < 0 {
+				continue
+			}
+			fields[f.name] = convertGrok(line[m[2*i]:m[2*i+1]], f.typ)
+		}
+		return fields
+	}
+	return nil
+}
+
+// ParseNormalized parses line and maps the captures into a NormalizedEvent
+// using the event.NewFromRaw alias rules. Returns nil if no pattern matches.
+func (p *GrokParser) ParseNormalized(line, sourceName string) *event.NormalizedEvent {
+	fields := p.Parse(line)
+	if fields == nil {
+		return nil
+	}
+	n := grokNormalized(fields, sourceName)
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+	return n
+}
+
+// ParseEvent parses evt.Message in place. It reports false, leaving evt
+// untouched, when no pattern matches so auto-detection can take over.
+func (p *GrokParser) ParseEvent(evt *event.Event) bool {
+	fields := p.Parse(evt.Message)
+	if fields == nil {
+		return false
+	}
+	fillEvent(evt, grokNormalized(fields, evt.Source))
+	metrics.ParseTotal.WithLabelValues("grok").Inc()
+	return true
+}
+
+func grokNormalized(fields map[string]any, sourceName string) *event.NormalizedEvent {
+	n := event.FromRaw(fields, sourceName)
+	n.Format = "grok"
+	if n.Timestamp.IsZero() {
+		n.Timestamp = grokTimestamp(fields)
+	}
+	return n
+}
+
+// grokTimestamp handles the layouts NewFromRaw does not, such as HTTPDATE.
+func grokTimestamp(fields map[string]any) time.Time {
+	for _, key := range []string{"timestamp", "ts", "@timestamp", "time"} {
+		s, ok := fields[key].(string)
+		if !ok {
+			continue
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.UTC()
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func compileGrok(src string, lib map[string]string) (*grokExpr, error) {
+	expr := &grokExpr{src: src, fields: make(map[int]grokField)}
+	var names []grokField
+
+	expanded, err := expandGrok(src, lib, &names, 0)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, err
+	}
+	expr.re = re
+
+	// Synthetic groups _grok0, _grok1, ... stand for %{X:field}; other named groups
+	// come from raw (?P<field>...) syntax and are kept as strings.
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if idx, ok := syntheticIndex(name); ok && idx < len(names) {
+			expr.fields[i] = names[idx]
+			continue
+		}
+		expr.fields[i] = grokField{name: name, typ: grokString}
+	}
+	return expr, nil
+}
+
+func expandGrok(src string, lib map[string]string, names *[]grokField, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("pattern references nest deeper than %d (cycle?)", maxGrokDepth)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range grokRef.FindAllStringSubmatchIndex(src, -1) {
+		b.WriteString(src[last:loc[0]])
+		last = loc[1]
+
+		name := src[loc[2]:loc[3]]
+		def, ok := lib[name]
+		if !ok {
+			return "", fmt.Errorf("unknown pattern %%{%s}", name)
+		}
+		inner, err := expandGrok(def, lib, names, depth+1)
+		if err != nil {
+			return "", err
+		}
+		if loc[4] < 0 {
+			b.WriteString("(?:" + inner + ")")
+			continue
+		}
+
+		f := grokField{name: src[loc[4]:loc[5]], typ: grokString}
+		if loc[6] >= 0 {
+			f.typ = src[loc[6]:loc[7]]
+			switch f.typ {
+			case grokString, grokInt, grokFloat, grokBool, grokDuration:
+			default:
+				return "", fmt.Errorf("unknown type %q for field %q", f.typ, f.name)
+			}
+		}
+		fmt.Fprintf(&b, "(?P<%s%d>%s)", grokGroupPrefix, len(*names), inner)
+		*names = append(*names, f)
+	}
+	b.WriteString(src[last:])
+	return b.String(), nil
+}
+
+func syntheticIndex(name string) (int, bool) {
+	digits, ok := strings.CutPrefix(name, grokGroupPrefix)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(digits)
+	return i, err == nil
+}
+
+// convertGrok applies the capture type, keeping the raw string when the
+// value does not convert.
+func convertGrok(s, typ string) any {
+	switch typ {
+	case grokInt:
+		if i, err := strconv.Atoi(s); err == nil {
+			return i
+		}
+	case grokFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case grokBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case grokDuration:
+		if d := extractLatency(map[string]any{"latency": s}); d != 0 {
+			return float64(d) / float64(time.Millisecond)
+		}
+	}
+	return s
+}
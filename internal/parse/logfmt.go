@@ -0,0 +1,107 @@
+package parse
+
+import (
+	"strconv"
+	"strings"
+
+	"collector/internal/event"
+)
+
+// ParseLogfmt fills evt from a decoded logfmt line
+// (level=info msg="x" service=api latency=12ms).
+func ParseLogfmt(evt *event.Event, raw map[string]any) {
+	fillStructured(evt, raw, logfmtNormalized(raw, evt.Source), jsonConsumed)
+}
+
+// ParseLogfmtNormalized maps a decoded logfmt line into a NormalizedEvent
+// using the same field aliases as JSON.
+func ParseLogfmtNormalized(raw map[string]any, sourceName string) *event.NormalizedEvent {
+	return withNow(logfmtNormalized(raw, sourceName))
+}
+
+func logfmtNormalized(raw map[string]any, sourceName string) *event.NormalizedEvent {
+	n := jsonNormalized(raw, sourceName)
+	n.Format = "logfmt"
+	return n
+}
+
+// decodeLogfmt splits s into key=value pairs for auto-detection. The line
+// is only accepted when it has at least two pairs and real pairs outnumber
+// bare keys, so ordinary prose stays plain text.
+func decodeLogfmt(s string) (map[string]any, bool) {
+	raw, pairs, bare, ok := splitLogfmt(s)
+	if !ok || pairs < 2 || bare >= pairs {
+		return nil, false
+	}
+	return raw, true
+}
+
+// splitLogfmt tokenizes s. Values may be double-quoted with Go-style
+// escapes; a bare key is recorded as true. ok is false when a key is
+// malformed or a quote is unterminated.
+func splitLogfmt(s string) (raw map[string]any, pairs, bare int, ok bool) {
+	raw = make(map[string]any)
+
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' {
+			if !isLogfmtKeyByte(s[i]) {
+				return nil, 0, 0, false
+			}
+			i++
+		}
+		key := s[start:i]
+		if key == "" {
+			return nil, 0, 0, false
+		}
+
+		if i >= len(s) || s[i] != '=' {
+			raw[key] = true
+			bare++
+			continue
+		}
+		i++ // '='
+
+		if i < len(s) && s[i] == '"' {
+			end, escaped := i+1, false
+			for ; end < len(s); end++ {
+				if escaped {
+					escaped = false
+					continue
+				}
+				if s[end] == '\\' {
+					escaped = true
+				} else if s[end] == '"' {
+					break
+				}
+			}
+			if end >= len(s) {
+				return nil, 0, 0, false // unterminated quote
+			}
+			v, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				v = s[i+1 : end]
+			}
+			raw[key] = v
+			i = end + 1
+		} else {
+			start = i
+			for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+				i++
+			}
+			raw[key] = s[start:i]
+		}
+		pairs++
+	}
+	return raw, pairs, bare, true
+}
+
+func isLogfmtKeyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("_-.@/", c) >= 0
+}
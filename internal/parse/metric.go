@@ -30,5 +30,8 @@ func ParseMetric(evt *event.Event, raw map[string]any) bool {
 	}
 
 	applyTimestamp(evt, raw)
+	if svc := extractService(raw); svc != "" {
+		evt.Service = svc
+	}
 	return true
 }
@@ -19,6 +19,11 @@ var (
 func ParseEvent(evt *event.Event) {
 	ensureAttrs(evt)
 
+	// Already handled by a source-level parser (see pipeline.WithParser).
+	if _, ok := evt.Attrs["format"]; ok {
+		return
+	}
+
 	s := strings.TrimSpace(evt.Message)
 	if s == "" {
 		evt.Attrs["format"] = "empty"
@@ -28,6 +33,10 @@ func ParseEvent(evt *event.Event) {
 
 	var raw map[string]any
 	if !tryUnmarshalJSON(s, &raw) {
+		if format := parseText(evt, s); format != "" {
+			metrics.ParseTotal.WithLabelValues(format).Inc()
+			return
+		}
 		MarkPlain(evt)
 		metrics.ParseTotal.WithLabelValues("plain").Inc()
 		return
@@ -54,13 +63,16 @@ func ParseNormalized(line, sourceName string) *event.NormalizedEvent {
 	}
 
 	var raw map[string]any
+	var n *event.NormalizedEvent
 	if !tryUnmarshalJSON(s, &raw) {
-		ParseSuccessTotal.Add(1)
-		return plainEvent(line, sourceName)
+		if n = normalizeText(s, sourceName); n == nil {
+			ParseSuccessTotal.Add(1)
+			return plainEvent(line, sourceName)
+		}
 	}
 
-	var n *event.NormalizedEvent
 	switch {
+	case n != nil:
 	case isMetricJSON(raw):
 		n = metricNormalized(raw, sourceName)
 	case IsECS(raw):
@@ -85,11 +97,145 @@ func ParseNormalized(line, sourceName string) *event.NormalizedEvent {
 	return n
 }
 
+// Fields returns the fields auto-detection decodes from line, before any
+// alias is resolved, with the format it detected. Plain text yields nil.
+// Unlike ParseEvent it records no metrics, for tools explaining how a line
+// was read.
+func Fields(line string) (map[string]any, string) {
+	s := strings.TrimSpace(line)
+	var raw map[string]any
+	switch {
+	case s == "":
+		return nil, "empty"
+	case tryUnmarshalJSON(s, &raw):
+		switch {
+		case isMetricJSON(raw):
+			return raw, "metric"
+		case IsECS(raw):
+			return raw, "ecs"
+		}
+		return raw, "json"
+	}
+	if raw := DecodeCEF(s); raw != nil {
+		return raw, "cef"
+	}
+	if raw := DecodeLEEF(s); raw != nil {
+		return raw, "leef"
+	}
+	if raw, format := DecodeELB(s); raw != nil {
+		return raw, format
+	}
+	if raw, ok := decodeLogfmt(s); ok {
+		return raw, "logfmt"
+	}
+	return nil, "plain"
+}
+
+// parseText tries the structured text formats in detection order and
+// returns the format label, or "" when s is plain text.
+func parseText(evt *event.Event, s string) string {
+	if raw := DecodeCEF(s); raw != nil {
+		ParseCEF(evt, raw)
+		return "cef"
+	}
+	if raw := DecodeLEEF(s); raw != nil {
+		ParseLEEF(evt, raw)
+		return "leef"
+	}
+	if raw, format := DecodeELB(s); raw != nil {
+		ParseELB(evt, raw, format)
+		return format
+	}
+	if raw, ok := decodeLogfmt(s); ok {
+		ParseLogfmt(evt, raw)
+		return "logfmt"
+	}
+	return ""
+}
+
+// normalizeText is the NormalizedEvent counterpart of parseText.
+func normalizeText(s, sourceName string) *event.NormalizedEvent {
+	if raw := DecodeCEF(s); raw != nil {
+		return ParseCEFNormalized(raw, sourceName)
+	}
+	if raw := DecodeLEEF(s); raw != nil {
+		return ParseLEEFNormalized(raw, sourceName)
+	}
+	if raw, format := DecodeELB(s); raw != nil {
+		return ParseELBNormalized(raw, format, sourceName)
+	}
+	if raw, ok := decodeLogfmt(s); ok {
+		return ParseLogfmtNormalized(raw, sourceName)
+	}
+	return nil
+}
+
 func tryUnmarshalJSON(s string, raw *map[string]any) bool {
 	if len(s) == 0 || (s[0] != '{' && s[0] != '[') {
 		return false
 	}
-	return json.Unmarshal([]byte(s), raw) == nil
+	if json.Unmarshal([]byte(s), raw) != nil {
+		return false
+	}
+	unwrapJSON(*raw, maxUnwrapDepth)
+	return true
+}
+
+// maxUnwrapDepth bounds how many levels of JSON-in-a-string unwrapJSON
+// follows.
+const maxUnwrapDepth = 4
+
+// unwrapJSON merges a JSON object carried as a string in "log", "message"
+// or "msg" into raw, as written by the Docker json-file driver or by
+// loggers wrapping another logger's output. The string may itself be
+// JSON-encoded, and the inner object may wrap further JSON. Inner keys win
+// over the envelope's.
+func unwrapJSON(raw map[string]any, depth int) {
+	if depth == 0 {
+		return
+	}
+	for _, key := range []string{"log", "message", "msg"} {
+		s, ok := raw[key].(string)
+		if !ok {
+			continue
+		}
+		inner, ok := decodeEmbeddedJSON(s, depth)
+		if !ok {
+			continue
+		}
+		delete(raw, key)
+		unwrapJSON(inner, depth-1)
+		for k, v := range inner {
+			raw[k] = v
+		}
+		return
+	}
+}
+
+// decodeEmbeddedJSON decodes s as a JSON object, first unquoting it while
+// it is a JSON string literal.
+func decodeEmbeddedJSON(s string, depth int) (map[string]any, bool) {
+	for ; depth > 0; depth-- {
+		s = strings.TrimSpace(s)
+		if len(s) < 2 {
+			return nil, false
+		}
+		switch s[0] {
+		case '{':
+			var m map[string]any
+			if json.Unmarshal([]byte(s), &m) != nil {
+				return nil, false
+			}
+			return m, true
+		case '"':
+			if json.Unmarshal([]byte(s), &s) != nil {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
 }
 
 func ensureAttrs(evt *event.Event) {
@@ -98,6 +244,56 @@ func ensureAttrs(evt *event.Event) {
 	}
 }
 
+// fillEvent copies a parsed line into evt: n.Raw lands in Attrs and the
+// resolved fields overwrite the source defaults when present. The format
+// label is taken from n.Format.
+func fillEvent(evt *event.Event, n *event.NormalizedEvent) {
+	ensureAttrs(evt)
+	if evt.Type == "" {
+		evt.Type = event.TypeLog
+	}
+	if !n.Timestamp.IsZero() {
+		evt.Timestamp = n.Timestamp
+	}
+	if n.Level != "" {
+		evt.Level = n.Level
+	}
+	if n.SrcService != "" {
+		evt.Service = n.SrcService
+	}
+	if msg := firstString(n.Raw, "message", "msg"); msg != "" {
+		evt.Message = msg
+	}
+	for k, v := range n.Raw {
+		evt.Attrs[k] = v
+	}
+	setCanonicalAttrs(evt, n)
+	evt.Attrs["format"] = n.Format
+}
+
+// setCanonicalAttrs stores alias-resolved fields under the keys that
+// event.Normalize reads, for formats whose captures are untyped strings.
+func setCanonicalAttrs(evt *event.Event, n *event.NormalizedEvent) {
+	if n.TraceID != "" {
+		evt.Attrs["trace_id"] = n.TraceID
+	}
+	if n.SpanID != "" {
+		evt.Attrs["span_id"] = n.SpanID
+	}
+	if n.DstService != "" {
+		evt.Attrs["dst_service"] = n.DstService
+	}
+	if n.Operation != "" {
+		evt.Attrs["operation"] = n.Operation
+	}
+	if n.StatusCode != 0 {
+		evt.Attrs["status_code"] = float64(n.StatusCode)
+	}
+	if n.Latency != 0 {
+		evt.Attrs["latency_ms"] = float64(n.Latency) / float64(time.Millisecond)
+	}
+}
+
 func isMetricJSON(raw map[string]any) bool {
 	_, hasMetric := raw["metric"]
 	_, hasValue := raw["value"]
@@ -6,6 +6,8 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"collector/internal/event"
 )
 
 // ── JSON parser ───────────────────────────────────────────────────────────────
@@ -202,6 +204,471 @@ func TestTemplateParser_NoMatch(t *testing.T) {
 	}
 }
 
+// ── grok parser ───────────────────────────────────────────────────────────────
+
+func TestGrokParser_CombinedApacheLog(t *testing.T) {
+	p, err := NewGrokParser([]string{"%{COMBINEDAPACHELOG}"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := `10.0.0.1 - frank [10/Oct/2024:13:55:36 +0000] "GET /api/users HTTP/1.1" 503 2326 "-" "curl/8.0"`
+
+	n := p.ParseNormalized(line, "test")
+	if n == nil {
+		t.Fatal("expected match")
+	}
+	if n.Format != "grok" {
+		t.Errorf("Format: want grok, got %q", n.Format)
+	}
+	if n.StatusCode != 503 {
+		t.Errorf("StatusCode: want 503, got %d", n.StatusCode)
+	}
+	if n.Operation != "GET /api/users" {
+		t.Errorf("Operation: want %q, got %q", "GET /api/users", n.Operation)
+	}
+	want := time.Date(2024, 10, 10, 13, 55, 36, 0, time.UTC)
+	if !n.Timestamp.Equal(want) {
+		t.Errorf("Timestamp: want %v, got %v", want, n.Timestamp)
+	}
+	if n.Raw["bytes"] != 2326 {
+		t.Errorf("bytes: want int 2326, got %#v", n.Raw["bytes"])
+	}
+}
+
+func TestGrokParser_TypedCapturesAndOrder(t *testing.T) {
+	p, err := NewGrokParser([]string{
+		`%{LOGLEVEL:level} %{WORD:service} took %{NUMBER:latency:float}ms`,
+		`%{LOGLEVEL:level} (?P<service>\w+) %{GREEDYDATA:message}`,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := p.ParseNormalized("WARN checkout took 12.5ms", "test")
+	if n == nil || n.Latency != 12500*time.Microsecond || n.SrcService != "checkout" || n.Level != "warn" {
+		t.Fatalf("first pattern: got %+v", n)
+	}
+
+	n = p.ParseNormalized("ERROR billing card declined", "test")
+	if n == nil || n.SrcService != "billing" || n.Raw["message"] != "card declined" {
+		t.Fatalf("second pattern: got %+v", n)
+	}
+
+	if p.ParseNormalized("no level here", "test") != nil {
+		t.Error("expected no match")
+	}
+}
+
+func TestGrokParser_ParseEvent(t *testing.T) {
+	p, _ := NewGrokParser([]string{`%{LOGLEVEL:level} %{IP:client} %{HTTPMETHOD:method} %{URIPATHPARAM:path} %{INT:status:int} %{NOTSPACE:duration:duration}`}, nil)
+
+	evt := event.Event{Source: "file", Service: "edge", Message: "INFO 192.168.1.5 POST /orders?id=7 201 0.25s"}
+	if !p.ParseEvent(&evt) {
+		t.Fatal("expected match")
+	}
+	n := event.Normalize(&evt)
+	if n.Format != "grok" || n.StatusCode != 201 || n.Operation != "POST /orders?id=7" {
+		t.Errorf("unexpected normalized event: %+v", n)
+	}
+	if n.Latency != 250*time.Millisecond {
+		t.Errorf("Latency: want 250ms, got %v", n.Latency)
+	}
+	if evt.Service != "edge" {
+		t.Errorf("Service should be kept when not captured, got %q", evt.Service)
+	}
+
+	// A source-level parse is not overridden by auto-detection.
+	ParseEvent(&evt)
+	if evt.Attrs["format"] != "grok" {
+		t.Errorf("format: want grok, got %v", evt.Attrs["format"])
+	}
+}
+
+func TestGrokParser_Errors(t *testing.T) {
+	for _, patterns := range [][]string{
+		nil,
+		{"%{NOPE:x}"},
+		{"%{INT:x:decimal}"},
+		{"%{LOOP}"},
+	} {
+		if _, err := NewGrokParser(patterns, map[string]string{"LOOP": "a%{LOOP}"}); err == nil {
+			t.Errorf("NewGrokParser(%q): expected error", patterns)
+		}
+	}
+}
+
+// ── logfmt parser ─────────────────────────────────────────────────────────────
+
+func TestDecodeLogfmt(t *testing.T) {
+	raw, ok := decodeLogfmt(`level=info msg="user \"bob\" logged in\n" service=api latency=12ms cached`)
+	if !ok {
+		t.Fatal("expected logfmt")
+	}
+	want := map[string]any{
+		"level":   "info",
+		"msg":     "user \"bob\" logged in\n",
+		"service": "api",
+		"latency": "12ms",
+		"cached":  true,
+	}
+	for k, v := range want {
+		if raw[k] != v {
+			t.Errorf("%s: want %#v, got %#v", k, v, raw[k])
+		}
+	}
+
+	for _, line := range []string{
+		"just a plain text log line",
+		"retry failed: a=b c=d",
+		"only=one",
+		`broken="unterminated value`,
+	} {
+		if _, ok := decodeLogfmt(line); ok {
+			t.Errorf("decodeLogfmt(%q): should not be logfmt", line)
+		}
+	}
+}
+
+func TestParseEvent_Logfmt(t *testing.T) {
+	evt := event.Event{Source: "stdin", Message: `time=2024-01-01T00:00:00Z level=WARN msg="slow call" service=api dst_service=db status=503 latency=1.5s`}
+	ParseEvent(&evt)
+
+	if evt.Attrs["format"] != "logfmt" {
+		t.Fatalf("format: want logfmt, got %v", evt.Attrs["format"])
+	}
+	if evt.Message != "slow call" || evt.Level != "warn" || evt.Service != "api" {
+		t.Errorf("unexpected event: %+v", evt)
+	}
+	if !evt.Timestamp.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Timestamp: got %v", evt.Timestamp)
+	}
+	n := event.Normalize(&evt)
+	if n.StatusCode != 503 || n.Latency != 1500*time.Millisecond || n.DstService != "db" {
+		t.Errorf("normalized: status=%d latency=%v dst=%q", n.StatusCode, n.Latency, n.DstService)
+	}
+}
+
+// ── security appliance formats ────────────────────────────────────────────────
+
+func TestParseNormalized_CEF(t *testing.T) {
+	line := `Sep 19 08:26:10 fw01 CEF:0|Security|threatmanager|1.0|100|worm\|virus stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 requestMethod=POST request=/upload msg=payload with spaces a\=b rt=1700000000000`
+	n := ParseNormalized(line, "test")
+
+	if n.Format != "cef" {
+		t.Fatalf("Format: want cef, got %q", n.Format)
+	}
+	if n.SrcService != "10.0.0.1" || n.DstService != "2.1.2.2" {
+		t.Errorf("edge: got %q -> %q", n.SrcService, n.DstService)
+	}
+	if n.Level != "error" || n.Operation != "POST /upload" {
+		t.Errorf("Level=%q Operation=%q", n.Level, n.Operation)
+	}
+	if n.Raw["name"] != "worm|virus stopped" || n.Raw["msg"] != "payload with spaces a=b" {
+		t.Errorf("unescaping: name=%q msg=%q", n.Raw["name"], n.Raw["msg"])
+	}
+	if n.Raw["syslog_header"] != "Sep 19 08:26:10 fw01" {
+		t.Errorf("syslog_header = %q", n.Raw["syslog_header"])
+	}
+	if !n.Timestamp.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("Timestamp = %v", n.Timestamp)
+	}
+}
+
+func TestParseNormalized_LEEF(t *testing.T) {
+	cases := []struct{ name, line string }{
+		{"1.0 tabs", "LEEF:1.0|Microsoft|MSExchange|4.0|15345|src=10.50.1.1\tdst=10.50.2.2\tsev=5\tusrName=bob"},
+		{"2.0 caret", "LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.50.1.1^dst=10.50.2.2^sev=5^usrName=bob"},
+		{"2.0 hex", "LEEF:2.0|Lancope|StealthWatch|1.0|41|x5E|src=10.50.1.1^dst=10.50.2.2^sev=5^usrName=bob"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := ParseNormalized(tc.line, "test")
+			if n.Format != "leef" || n.SrcService != "10.50.1.1" || n.DstService != "10.50.2.2" || n.Level != "warn" {
+				t.Errorf("got format=%q edge=%q->%q level=%q", n.Format, n.SrcService, n.DstService, n.Level)
+			}
+			if n.Raw["usrName"] != "bob" {
+				t.Errorf("usrName = %v", n.Raw["usrName"])
+			}
+		})
+	}
+}
+
+// ── load balancer access logs ─────────────────────────────────────────────────
+
+func TestParseNormalized_ALB(t *testing.T) {
+	line := `https 2024-03-15T12:34:56.789012Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 502 200 0 57 "GET https://www.example.com:443/api/users?id=1 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "-" 0 2024-03-15T12:34:56.600000Z "forward" "-" "-"`
+	n := ParseNormalized(line, "test")
+
+	if n.Format != "alb" {
+		t.Fatalf("Format: want alb, got %q", n.Format)
+	}
+	if n.SrcService != "192.168.131.39" || n.DstService != "10.0.0.1" {
+		t.Errorf("edge: got %q -> %q", n.SrcService, n.DstService)
+	}
+	if n.StatusCode != 502 || n.Level != "error" {
+		t.Errorf("StatusCode=%d Level=%q", n.StatusCode, n.Level)
+	}
+	if n.Latency != 171*time.Millisecond {
+		t.Errorf("Latency: want 171ms, got %v", n.Latency)
+	}
+	if n.Operation != "GET /api/users" || n.TraceID != "Root=1-58337281-1d84f3d73c47ec4e58577259" {
+		t.Errorf("Operation=%q TraceID=%q", n.Operation, n.TraceID)
+	}
+	if _, ok := n.Raw["chosen_cert_arn"]; ok {
+		t.Error(`"-" values should be omitted`)
+	}
+}
+
+func TestParseNormalized_ClassicELB(t *testing.T) {
+	line := `2024-03-15T12:34:56.789Z my-elb 192.168.1.5:51000 10.0.1.7:8080 0.000073 -1 0.000057 504 0 0 0 "POST http://my-elb.example.com:80/orders HTTP/1.1" "-" - -`
+	n := ParseNormalized(line, "test")
+	if n.Format != "elb" || n.DstService != "10.0.1.7" || n.StatusCode != 504 {
+		t.Errorf("got format=%q dst=%q status=%d", n.Format, n.DstService, n.StatusCode)
+	}
+	if n.Latency != 0 {
+		t.Errorf("Latency with a -1 processing time should be unknown, got %v", n.Latency)
+	}
+}
+
+func TestW3CParser_IISAndCloudFront(t *testing.T) {
+	p := NewW3CParser()
+
+	iis := []string{
+		"#Software: Microsoft Internet Information Services 10.0",
+		"#Fields: date time s-ip cs-method cs-uri-stem cs-uri-query s-port cs-username c-ip cs(User-Agent) sc-status time-taken",
+		"2024-03-15 12:34:56 10.0.0.5 GET /default.htm - 80 - 192.168.1.20 Mozilla/5.0 404 31",
+	}
+	for _, line := range iis[:2] {
+		if p.ParseNormalized(line, "iis.log") != nil {
+			t.Fatalf("directive %q should not produce an event", line)
+		}
+	}
+	n := p.ParseNormalized(iis[2], "iis.log")
+	if n == nil || n.Format != "w3c" {
+		t.Fatalf("IIS line: got %+v", n)
+	}
+	if n.SrcService != "192.168.1.20" || n.DstService != "10.0.0.5" || n.StatusCode != 404 || n.Latency != 31*time.Millisecond {
+		t.Errorf("IIS: edge=%q->%q status=%d latency=%v", n.SrcService, n.DstService, n.StatusCode, n.Latency)
+	}
+	if !n.Timestamp.Equal(time.Date(2024, 3, 15, 12, 34, 56, 0, time.UTC)) {
+		t.Errorf("IIS Timestamp = %v", n.Timestamp)
+	}
+
+	// A different file keeps its own layout.
+	if p.ParseNormalized(iis[2], "other.log") != nil {
+		t.Error("line without a #Fields header for its file should not parse")
+	}
+	p.ParseNormalized("#Fields: date time x-edge-location sc-bytes c-ip cs-method cs(Host) cs-uri-stem sc-status time-taken", "cf.log")
+	n = p.ParseNormalized("2024-03-15\t12:00:00\tFRA2\t1024\t203.0.113.9\tGET\td111.cloudfront.net\t/img.png\t200\t0.25", "cf.log")
+	if n == nil || n.Format != "cloudfront" || n.Latency != 250*time.Millisecond || n.DstService != "d111.cloudfront.net" {
+		t.Errorf("CloudFront: got %+v", n)
+	}
+}
+
+func TestW3CParser_ConsumesDirectives(t *testing.T) {
+	p := NewW3CParser()
+	header := event.Event{Source: "file", Message: "#Fields: c-ip cs-method cs-uri-stem sc-status", Attrs: map[string]any{"path": "/var/log/iis.log"}}
+	if !p.Consume(&header) {
+		t.Fatal("directive should be consumed")
+	}
+	evt := event.Event{Source: "file", Message: "10.1.1.1 GET /health 500", Attrs: map[string]any{"path": "/var/log/iis.log"}}
+	if p.Consume(&evt) || !p.ParseEvent(&evt) {
+		t.Fatal("data line should parse")
+	}
+	if evt.Attrs["format"] != "w3c" || evt.Service != "10.1.1.1" || evt.Level != "error" {
+		t.Errorf("unexpected event: %+v", evt)
+	}
+}
+
+// ── per-source parse chains ───────────────────────────────────────────────────
+
+func newChain(t *testing.T, keep bool, names ...string) *Chain {
+	t.Helper()
+	c := &Chain{KeepOriginal: keep}
+	for _, name := range names {
+		opts := ParserOptions{Template: "nginx", Patterns: []string{`%{LOGLEVEL:level} %{GREEDYDATA:msg}`}}
+		p, err := NewParser(name, opts)
+		if err != nil {
+			t.Fatalf("NewParser(%q): %v", name, err)
+		}
+		c.Parsers = append(c.Parsers, p)
+	}
+	return c
+}
+
+func TestChain_FallbackOrder(t *testing.T) {
+	c := newChain(t, false, ParserJSON, ParserLogfmt, ParserRegex)
+	cases := []struct {
+		line       string
+		wantFormat string
+		wantLevel  string
+	}{
+		{`{"level":"warn","msg":"disk"}`, "json", "warn"},
+		{`level=error`, "logfmt", "error"},
+		{`ERROR upstream timed out`, "grok", "error"},
+		// Would be syslog under auto-detection; the chain keeps it plain.
+		{`<34>Oct 11 22:14:15 host su: failed`, "plain", ""},
+	}
+	for _, tc := range cases {
+		evt := event.Event{Source: "file", Message: tc.line}
+		c.ParseEvent(&evt)
+		if evt.Attrs["format"] != tc.wantFormat || evt.Level != tc.wantLevel {
+			t.Errorf("%q: want format=%s level=%q, got %v %q", tc.line, tc.wantFormat, tc.wantLevel, evt.Attrs["format"], evt.Level)
+		}
+		if _, ok := evt.Attrs["original"]; ok {
+			t.Errorf("%q: original kept without keep_original", tc.line)
+		}
+	}
+}
+
+func TestChain_TemplatePresetAndKeepOriginal(t *testing.T) {
+	c := newChain(t, true, ParserTemplate, ParserNone)
+	line := `10.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /api HTTP/1.1" 503 12 "-" "curl/8"`
+	evt := event.Event{Source: "file", Message: line}
+	c.ParseEvent(&evt)
+	if evt.Attrs["format"] != "template" || evt.Attrs["status_code"] != float64(503) {
+		t.Errorf("unexpected attrs: %v", evt.Attrs)
+	}
+	if evt.Attrs["original"] != line {
+		t.Errorf("original: want raw line, got %v", evt.Attrs["original"])
+	}
+
+	other := event.Event{Source: "file", Message: "level=info msg=hi"}
+	c.ParseEvent(&other)
+	if other.Attrs["format"] != "plain" || other.Message != "level=info msg=hi" {
+		t.Errorf("none should keep the line as is: %+v", other)
+	}
+}
+
+func TestChain_AutoFallback(t *testing.T) {
+	c := newChain(t, false, ParserCEF, ParserAuto)
+	evt := event.Event{Source: "file", Message: `level=warn msg="slow" service=api`}
+	c.ParseEvent(&evt)
+	if evt.Attrs["format"] != "logfmt" || evt.Service != "api" {
+		t.Errorf("auto should detect logfmt, got %+v", evt)
+	}
+}
+
+func TestNewParser_Errors(t *testing.T) {
+	for _, name := range []string{"", "xml", ParserTemplate, ParserRegex} {
+		if _, err := NewParser(name, ParserOptions{}); err == nil {
+			t.Errorf("NewParser(%q): expected error", name)
+		}
+	}
+}
+
+// ── nested fields and embedded JSON ───────────────────────────────────────────
+
+func TestParseNormalized_NestedFields(t *testing.T) {
+	line := `{"time":"2024-06-01T12:00:00Z","service":{"name":"orders"},"http":{"method":"GET","path":"/items","status":503},"latency":{"ms":0},"upstream":"inventory","level":"warn"}`
+	n := ParseNormalized(line, "file")
+	if n.SrcService != "orders" || n.DstService != "inventory" {
+		t.Errorf("services: %q -> %q", n.SrcService, n.DstService)
+	}
+	if n.Operation != "GET /items" || n.StatusCode != 503 {
+		t.Errorf("Operation/StatusCode: %q %d", n.Operation, n.StatusCode)
+	}
+}
+
+func TestParseEvent_UnwrapsEmbeddedJSON(t *testing.T) {
+	inner := `{"level":"error","msg":"payment failed","service":"billing","status_code":502}`
+	encoded, _ := json.Marshal(inner)         // JSON string literal of inner
+	twice, _ := json.Marshal(string(encoded)) // and that literal encoded again
+
+	cases := map[string]string{
+		"docker log":     `{"log":` + string(encoded) + `,"stream":"stderr","time":"2024-06-01T12:00:00Z"}`,
+		"json-in-json":   `{"log":` + string(twice) + `,"stream":"stderr"}`,
+		"message nested": `{"message":` + string(mustJSON(t, `{"log":`+string(encoded)+`}`)) + `}`,
+	}
+	for name, line := range cases {
+		evt := event.Event{Source: "file", Message: line}
+		ParseEvent(&evt)
+		if evt.Message != "payment failed" || evt.Level != "error" || evt.Service != "billing" {
+			t.Errorf("%s: unexpected event %+v", name, evt)
+		}
+		if _, ok := evt.Attrs["log"]; ok {
+			t.Errorf("%s: envelope log field should be replaced", name)
+		}
+		if n := ParseNormalized(line, "file"); n.StatusCode != 502 || n.SrcService != "billing" {
+			t.Errorf("%s: normalized %+v", name, n)
+		}
+	}
+
+	evt := event.Event{Source: "file", Message: `{"log":"plain line\n","stream":"stdout"}`}
+	ParseEvent(&evt)
+	if evt.Message != "plain line\n" || evt.Attrs["stream"] != "stdout" {
+		t.Errorf("non-JSON log should be the message: %+v", evt)
+	}
+}
+
+func mustJSON(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestFlattener(t *testing.T) {
+	attrs := map[string]any{
+		"http": map[string]any{"request": map[string]any{"method": "GET"}, "status": float64(200)},
+		"tags": []any{"a", "b"},
+		"a":    map[string]any{"b": map[string]any{"c": map[string]any{"d": 1}}},
+	}
+	got := Flattener{Separator: "_", MaxDepth: 2}.Flatten(attrs)
+	if got["http_request"] == nil || got["http_status"] != float64(200) {
+		t.Errorf("unexpected flatten result: %v", got)
+	}
+	if _, ok := got["http_request"].(map[string]any); !ok {
+		t.Errorf("objects past MaxDepth should stay whole: %v", got["http_request"])
+	}
+	if _, ok := got["tags"].([]any); !ok {
+		t.Errorf("arrays should be kept: %v", got["tags"])
+	}
+
+	deep := Flattener{}.Flatten(attrs)
+	if deep["http.request.method"] != "GET" || deep["a.b.c.d"] != 1 {
+		t.Errorf("default flatten: %v", deep)
+	}
+
+	c := &Chain{Parsers: []Parser{jsonParser{}}, Flatten: &Flattener{}}
+	evt := event.Event{Source: "file", Message: `{"msg":"x","http":{"status":404}}`}
+	c.ParseEvent(&evt)
+	if evt.Attrs["http.status"] != float64(404) || event.Normalize(&evt).StatusCode != 404 {
+		t.Errorf("chain flatten: %v", evt.Attrs)
+	}
+}
+
+// Both parse paths must agree on the canonical fields for the same line.
+func TestParsePaths_Agree(t *testing.T) {
+	lines := []string{
+		`{"ts":"2024-06-01T12:00:00Z","level":"INFO","service":"api","trace_id":"t1","span_id":"s1","method":"GET","path":"/a","status":"200","latency":"87ms","upstream":"db"}`,
+		`{"@timestamp":"2024-06-01T12:00:00Z","log.level":"warn","service":{"name":"web"},"http":{"request":{"method":"post"},"response":{"status_code":500}},"url":{"path":"/b"},"event":{"duration":2500000},"trace":{"id":"t2"}}`,
+		`{"time":1717243200,"severity":"error","app":"worker","http":{"status_code":503},"duration_s":1.5,"operation":"job.run"}`,
+		`ts=2024-06-01T12:00:00Z level=debug service=cron msg="tick" latency=5ms status=204`,
+		`{"log":"{\"ts\":\"2024-06-01T12:00:00Z\",\"service\":\"inner\",\"status_code\":418}\n","stream":"stdout"}`,
+	}
+	for _, line := range lines {
+		direct := ParseNormalized(line, "file")
+		evt := event.Event{Source: "file", Message: line}
+		ParseEvent(&evt)
+		viaEvent := event.Normalize(&evt)
+
+		type canon struct {
+			Timestamp                                time.Time
+			Src, Dst, Trace, Span, Op, Level, Format string
+			Status                                   int
+			Latency                                  time.Duration
+		}
+		a := canon{direct.Timestamp, direct.SrcService, direct.DstService, direct.TraceID, direct.SpanID, direct.Operation, direct.Level, direct.Format, direct.StatusCode, direct.Latency}
+		b := canon{viaEvent.Timestamp, viaEvent.SrcService, viaEvent.DstService, viaEvent.TraceID, viaEvent.SpanID, viaEvent.Operation, viaEvent.Level, viaEvent.Format, viaEvent.StatusCode, viaEvent.Latency}
+		if a != b {
+			t.Errorf("paths disagree for %s\n direct: %+v\n event:  %+v", line, a, b)
+		}
+	}
+}
+
 // ── testdata integration ──────────────────────────────────────────────────────
 
 func TestParseNormalized_JSONTestdata(t *testing.T) {
@@ -273,6 +740,26 @@ func TestParseNormalized_Routing(t *testing.T) {
 			`{"timestamp":"2024-01-01T00:00:00Z","level":"info","service":"svc","message":"hello"}`,
 			"json",
 		},
+		{
+			"logfmt",
+			`level=info msg="hello" service=svc`,
+			"logfmt",
+		},
+		{
+			"CEF",
+			`CEF:0|Vendor|Product|1.0|100|Blocked|5|src=10.0.0.1 dst=10.0.0.2`,
+			"cef",
+		},
+		{
+			"LEEF",
+			"LEEF:1.0|Vendor|Product|1.0|42|src=10.0.0.1\tdst=10.0.0.2",
+			"leef",
+		},
+		{
+			"ELB",
+			`2024-03-15T12:34:56.789Z my-elb 192.168.1.5:51000 10.0.1.7:8080 0.1 0.2 0.1 200 200 0 0 "GET http://x/ HTTP/1.1" "-" - -`,
+			"elb",
+		},
 		{
 			"plain",
 			`just a plain text log line`,
@@ -293,4 +780,25 @@ func TestParseNormalized_Routing(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestFields(t *testing.T) {
+	cases := []struct {
+		line, format, key string
+	}{
+		{`{"service":"api","upstream":"db"}`, "json", "upstream"},
+		{`{"log":"{\"service\":\"api\"}\n","stream":"stdout"}`, "json", "service"},
+		{`{"metric":"requests","value":3}`, "metric", "metric"},
+		{`level=warn app=web target=api`, "logfmt", "target"},
+		{`just some text`, "plain", ""},
+		{`   `, "empty", ""},
+	}
+	for _, tc := range cases {
+		raw, format := Fields(tc.line)
+		if format != tc.format {
+			t.Errorf("%q: format %q, want %q", tc.line, format, tc.format)
+		}
+		if _, ok := raw[tc.key]; tc.key != "" && !ok {
+			t.Errorf("%q: missing key %q in %v", tc.line, tc.key, raw)
+		}
+	}
+}
@@ -47,6 +47,25 @@ func (p *TemplateParser) ParseNormalized(line, sourceName string) *event.Normali
 		return nil
 	}
 
+	n := templateNormalized(fields, sourceName)
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+	return n
+}
+
+// ParseEvent parses evt.Message in place, reporting false when the line
+// does not match the template.
+func (p *TemplateParser) ParseEvent(evt *event.Event) bool {
+	fields := p.Parse(evt.Message)
+	if fields == nil {
+		return false
+	}
+	fillEvent(evt, templateNormalized(fields, evt.Source))
+	return true
+}
+
+func templateNormalized(fields map[string]string, sourceName string) *event.NormalizedEvent {
 	n := &event.NormalizedEvent{
 		Format:     "template",
 		SourceName: sourceName,
@@ -55,12 +74,7 @@ func (p *TemplateParser) ParseNormalized(line, sourceName string) *event.Normali
 	for k, v := range fields {
 		n.Raw[k] = v
 	}
-
 	mapTemplateFields(n, fields)
-
-	if n.Timestamp.IsZero() {
-		n.Timestamp = time.Now().UTC()
-	}
 	return n
 }
 
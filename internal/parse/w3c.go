@@ -0,0 +1,159 @@
+package parse
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+// W3CParser parses W3C Extended Log Format files such as IIS and
+// CloudFront access logs. Each "#Fields:" directive names the columns of
+// the lines that follow it, so the layout is tracked per file: by
+// Attrs["path"] for file sources, Attrs["http_path"] for the http source,
+// otherwise by the event source.
+type W3CParser struct {
+	mu     sync.Mutex
+	fields map[string][]string
+}
+
+func NewW3CParser() *W3CParser {
+	return &W3CParser{fields: make(map[string][]string)}
+}
+
+// Directive records a "#Fields:" layout for key and reports whether line
+// is a directive at all ("#Version:", "#Date:", ...).
+func (p *W3CParser) Directive(key, line string) bool {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "#") {
+		return false
+	}
+	if spec, ok := strings.CutPrefix(line, "#Fields:"); ok {
+		p.mu.Lock()
+		p.fields[key] = strings.Fields(spec)
+		p.mu.Unlock()
+	}
+	return true
+}
+
+// Decode splits a data line using the layout last declared for key.
+// Returns nil before any "#Fields:" directive or when the column count
+// does not match. "-" values are omitted.
+func (p *W3CParser) Decode(key, line string) map[string]any {
+	p.mu.Lock()
+	names := p.fields[key]
+	p.mu.Unlock()
+	if len(names) == 0 {
+		return nil
+	}
+
+	// CloudFront separates columns with tabs, IIS with single spaces.
+	var values []string
+	if strings.Contains(line, "\t") {
+		values = strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+	} else {
+		values = strings.Fields(line)
+	}
+	if len(values) != len(names) {
+		return nil
+	}
+
+	raw := make(map[string]any, len(names))
+	for i, name := range names {
+		if values[i] != "-" && values[i] != "" {
+			raw[name] = values[i]
+		}
+	}
+	return raw
+}
+
+// Consume lets the pipeline drop directive lines once their layout has
+// been recorded.
+func (p *W3CParser) Consume(evt *event.Event) bool {
+	return p.Directive(w3cKey(evt), evt.Message)
+}
+
+// ParseEvent parses a data line in place, reporting false when no layout
+// is known for its file.
+func (p *W3CParser) ParseEvent(evt *event.Event) bool {
+	key := w3cKey(evt)
+	raw := p.Decode(key, evt.Message)
+	if raw == nil {
+		return false
+	}
+	n := p.normalized(key, raw, evt.Source)
+	fillEvent(evt, n)
+	metrics.ParseTotal.WithLabelValues(n.Format).Inc()
+	return true
+}
+
+// ParseNormalized parses line using the layout tracked for sourceName.
+// Directives and lines without a known layout return nil.
+func (p *W3CParser) ParseNormalized(line, sourceName string) *event.NormalizedEvent {
+	if p.Directive(sourceName, line) {
+		return nil
+	}
+	raw := p.Decode(sourceName, line)
+	if raw == nil {
+		return nil
+	}
+	return withNow(p.normalized(sourceName, raw, sourceName))
+}
+
+func (p *W3CParser) normalized(key string, raw map[string]any, sourceName string) *event.NormalizedEvent {
+	_, cloudfront := raw["x-edge-location"]
+	if !cloudfront {
+		p.mu.Lock()
+		for _, f := range p.fields[key] {
+			cloudfront = cloudfront || f == "x-edge-location"
+		}
+		p.mu.Unlock()
+	}
+
+	n := &event.NormalizedEvent{Format: "w3c", SourceName: sourceName, Raw: raw}
+	if cloudfront {
+		n.Format = "cloudfront"
+	}
+
+	if d, t := firstString(raw, "date"), firstString(raw, "time"); d != "" && t != "" {
+		if ts, err := time.Parse("2006-01-02 15:04:05", d+" "+t); err == nil {
+			n.Timestamp = ts.UTC()
+		}
+	}
+	n.SrcService = firstString(raw, "c-ip")
+	n.DstService = firstString(raw, "cs-host", "cs(Host)", "x-host-header", "s-computername", "s-sitename", "s-ip")
+	n.TraceID = firstString(raw, "x-edge-request-id")
+
+	method, stem := firstString(raw, "cs-method"), firstString(raw, "cs-uri-stem")
+	switch {
+	case method != "" && stem != "":
+		n.Operation = method + " " + stem
+	case stem != "":
+		n.Operation = stem
+	}
+	if code, err := strconv.Atoi(firstString(raw, "sc-status")); err == nil {
+		n.StatusCode = code
+	}
+	// IIS reports time-taken in milliseconds, CloudFront in seconds.
+	if v, err := strconv.ParseFloat(firstString(raw, "time-taken"), 64); err == nil {
+		unit := time.Millisecond
+		if cloudfront {
+			unit = time.Second
+		}
+		n.Latency = time.Duration(v * float64(unit))
+	}
+	n.Level = levelFromStatus(n.StatusCode)
+	return n
+}
+
+func w3cKey(evt *event.Event) string {
+	for _, key := range []string{"path", "http_path"} {
+		if path, ok := evt.Attrs[key].(string); ok && path != "" {
+			return path
+		}
+	}
+	return evt.Source
+}
@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log"
@@ -27,12 +28,27 @@ type Transformer interface {
 	Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error
 }
 
+// Route connects a side output of a transform (for example throttle
+// overflow) to its own sink. Its events are normalized and resolved like
+// those of the main sink. The pipeline closes In once the transform stops.
+type Route struct {
+	Name string
+	In   chan event.Event
+	Sink Sink
+}
+
 type Pipeline struct {
 	Sources        []Source
 	Transform      Transformer
 	Sink           Sink
 	NormalizedSink NormalizedSink
 	Resolver       resolve.Resolver // optional, enriches DstService/SrcService
+	Routes         []Route
+
+	// TransformName and SinkName label their telemetry; sources are
+	// labelled by wrapping them with Observed.
+	TransformName string
+	SinkName      string
 }
 
 func (p *Pipeline) Run(ctx context.Context) error {
@@ -49,7 +65,14 @@ func (p *Pipeline) Run(ctx context.Context) error {
 	sourceChan := make(chan event.Event, 100)
 	parsedChan := make(chan event.Event, 100)
 	normalChan := make(chan *event.NormalizedEvent, 100)
-	legacyChan := make(chan event.Event, 100)
+
+	transformStage := newStage(KindTransform, cmp.Or(p.TransformName, "transform"))
+	sinkStage := newStage(KindSink, cmp.Or(p.SinkName, "sink"))
+	channels := map[string]func() float64{
+		"sources":    utilization(sourceChan),
+		"parsed":     utilization(parsedChan),
+		"normalized": utilization(normalChan),
+	}
 
 	errCh := make(chan error, 8)
 	var wg sync.WaitGroup
@@ -86,6 +109,9 @@ func (p *Pipeline) Run(ctx context.Context) error {
 				parse.ParseEvent(&evt)
 				select {
 				case parsedChan <- evt:
+					if p.Transform != nil {
+						transformStage.in.Inc()
+					}
 				case <-ctx.Done():
 					return
 				}
@@ -93,12 +119,34 @@ func (p *Pipeline) Run(ctx context.Context) error {
 		}
 	}()
 
+	var routeWG sync.WaitGroup
+	for _, r := range p.Routes {
+		route := r
+		channels["route/"+route.Name] = utilization(route.In)
+		routeWG.Add(1)
+		go func() {
+			defer routeWG.Done()
+			st := newStage(KindRoute, route.Name)
+			if err := LegacySink(route.Sink).Run(ctx, p.normalizeRoute(ctx, st, route.In)); err != nil && err != context.Canceled {
+				st.errors.Inc()
+				log.Printf("route %s: sink stopped: %v", route.Name, err)
+			}
+		}()
+	}
+	defer routeWG.Wait()
+
+	if p.Transform == nil {
+		p.closeRoutes()
+	}
+
 	transformedChan := parsedChan
 	if p.Transform != nil {
 		tc := make(chan event.Event, 100)
 		go func() {
 			defer close(tc)
+			defer p.closeRoutes()
 			if err := p.Transform.Run(ctx, parsedChan, tc); err != nil && err != context.Canceled {
+				transformStage.errors.Inc()
 				select {
 				case errCh <- err:
 				default:
@@ -107,28 +155,12 @@ func (p *Pipeline) Run(ctx context.Context) error {
 			}
 		}()
 		transformedChan = tc
+		channels["transformed"] = utilization(tc)
 	}
+	go sampleChannels(ctx, channels)
 
 	go func() {
 		defer close(normalChan)
-		if p.NormalizedSink == nil {
-			defer close(legacyChan)
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case evt, ok := <-transformedChan:
-					if !ok {
-						return
-					}
-					select {
-					case legacyChan <- evt:
-					case <-ctx.Done():
-						return
-					}
-				}
-			}
-		}
 		for {
 			select {
 			case <-ctx.Done():
@@ -137,10 +169,15 @@ func (p *Pipeline) Run(ctx context.Context) error {
 				if !ok {
 					return
 				}
+				if p.Transform != nil {
+					transformStage.sent(evt.Received)
+				}
 				n := event.Normalize(&evt)
-				p.resolve(ctx, n)
+				Resolve(ctx, p.Resolver, n)
+				n.Ack = sinkStage.done(n.Received, n.Ack)
 				select {
 				case normalChan <- n:
+					sinkStage.in.Inc()
 				case <-ctx.Done():
 					return
 				}
@@ -148,14 +185,14 @@ func (p *Pipeline) Run(ctx context.Context) error {
 		}
 	}()
 
-	var sinkErr error
-	if p.NormalizedSink != nil {
-		sinkErr = p.NormalizedSink.Run(ctx, normalChan)
-	} else {
-		sinkErr = p.Sink.Run(ctx, legacyChan)
+	sink := p.NormalizedSink
+	if sink == nil {
+		sink = LegacySink(p.Sink)
 	}
+	sinkErr := sink.Run(ctx, normalChan)
 
 	if sinkErr != nil && sinkErr != context.Canceled {
+		sinkStage.errors.Inc()
 		select {
 		case errCh <- sinkErr:
 		default:
@@ -174,19 +211,120 @@ func (p *Pipeline) Run(ctx context.Context) error {
 	return sinkErr
 }
 
-// resolve enriches DstService and SrcService using the configured Resolver.
-func (p *Pipeline) resolve(ctx context.Context, n *event.NormalizedEvent) {
-	if p.Resolver == nil {
+// normalizeRoute normalizes and resolves the events of a route as for the
+// main sink, counting them into st on their way to its sink.
+func (p *Pipeline) normalizeRoute(ctx context.Context, st *stage, in <-chan event.Event) <-chan *event.NormalizedEvent {
+	out := make(chan *event.NormalizedEvent)
+	go func() {
+		defer close(out)
+		for evt := range in {
+			st.in.Inc()
+			n := event.Normalize(&evt)
+			Resolve(ctx, p.Resolver, n)
+			n.Ack = st.done(n.Received, n.Ack)
+			select {
+			case out <- n:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out
+}
+
+func (p *Pipeline) closeRoutes() {
+	for _, r := range p.Routes {
+		close(r.In)
+	}
+}
+
+// Resolve enriches DstService and SrcService using r, which may be nil.
+func Resolve(ctx context.Context, r resolve.Resolver, n *event.NormalizedEvent) {
+	if r == nil {
 		return
 	}
 	if n.DstService != "" {
-		if svc, ok := p.Resolver.Resolve(ctx, n.DstService); ok {
+		if svc, ok := r.Resolve(ctx, n.DstService); ok {
 			n.DstService = svc
 		}
 	}
 	if n.SrcService == "" {
-		if svc, ok := p.Resolver.Resolve(ctx, n.SourceName); ok {
+		if svc, ok := r.Resolve(ctx, n.SourceName); ok {
 			n.SrcService = svc
 		}
 	}
-}
\ No newline at end of file
+}
+
+// LineParser parses a raw source line held in evt.Message, reporting
+// whether it recognised the line.
+type LineParser interface {
+	ParseEvent(evt *event.Event) bool
+}
+
+// LineConsumer is implemented by stateful parsers whose control lines
+// (such as W3C "#Fields:" directives) only update parser state and are
+// not forwarded as events.
+type LineConsumer interface {
+	Consume(evt *event.Event) bool
+}
+
+// WithParser wraps src so that every event it emits is first offered to p.
+// Events p leaves without a format fall through to the default auto-detection.
+func WithParser(src Source, p LineParser) Source {
+	return &parsedSource{src: src, parser: p}
+}
+
+type parsedSource struct {
+	src    Source
+	parser LineParser
+}
+
+func (s *parsedSource) Run(ctx context.Context, out chan<- event.Event) error {
+	ch := make(chan event.Event, 100)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		errc <- s.src.Run(ctx, ch)
+	}()
+
+	consumer, _ := s.parser.(LineConsumer)
+	for evt := range ch {
+		if consumer != nil && consumer.Consume(&evt) {
+			evt.Done()
+			continue
+		}
+		s.parser.ParseEvent(&evt)
+		select {
+		case out <- evt:
+		case <-ctx.Done():
+			// Left unacknowledged: the source reads it again on restart.
+		}
+	}
+	return <-errc
+}
+
+// LegacySink adapts a Sink written against event.Event to the normalized
+// stream, so it sees resolver enrichment like any NormalizedSink. Events
+// are converted back with NormalizedEvent.ToEvent.
+func LegacySink(s Sink) NormalizedSink {
+	return &legacySink{sink: s}
+}
+
+type legacySink struct {
+	sink Sink
+}
+
+func (s *legacySink) Run(ctx context.Context, in <-chan *event.NormalizedEvent) error {
+	ch := make(chan event.Event, 100)
+	go func() {
+		defer close(ch)
+		for n := range in {
+			select {
+			case ch <- n.ToEvent():
+			case <-ctx.Done():
+				// Left unacknowledged: the source reads it again on restart.
+				return
+			}
+		}
+	}()
+	return s.sink.Run(ctx, ch)
+}
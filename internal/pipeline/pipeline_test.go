@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"collector/internal/event"
+	"collector/internal/resolve"
+)
+
+type events []event.Event
+
+func (s events) Run(ctx context.Context, out chan<- event.Event) error {
+	for _, evt := range s {
+		out <- evt
+	}
+	return nil
+}
+
+// jsonSink writes events as the stdout sink does.
+type jsonSink struct{ buf *bytes.Buffer }
+
+func (s jsonSink) Run(ctx context.Context, in <-chan event.Event) error {
+	enc := json.NewEncoder(s.buf)
+	for evt := range in {
+		if err := enc.Encode(evt); err != nil {
+			return err
+		}
+		evt.Done()
+	}
+	return nil
+}
+
+// TestLegacySink_Golden pins what a plain Sink receives: the lines it wrote
+// before the pipeline normalized every event, with the format the parse
+// stage adds.
+func TestLegacySink_Golden(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	p := &Pipeline{
+		Sources: []Source{events{
+			{Timestamp: ts, Source: "file", Service: "api", Type: event.TypeLog, Level: "info", Message: "GET /users",
+				Attrs: map[string]any{"latency": "12ms", "status": float64(200), "upstream": "db", "method": "GET", "path": "/users", "traceId": "abc"}},
+			{Timestamp: ts, Source: "file", Service: "api", Type: event.TypeLog, Level: "error", Message: "timeout",
+				Attrs: map[string]any{"dst_service": "cache", "latency_ms": float64(250), "status_code": float64(504)}},
+			{Timestamp: ts, Source: "file", Service: "api", Type: event.TypeMetric, Metric: "queue_depth", Value: 7,
+				Attrs: map[string]any{"status": "ok"}},
+		}},
+		Sink: jsonSink{&buf},
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"ts":"2024-05-01T10:00:00Z","source":"file","service":"api","type":"log","level":"info","message":"GET /users","attrs":{"format":"plain","latency":"12ms","method":"GET","path":"/users","status":200,"traceId":"abc","upstream":"db"}}
+{"ts":"2024-05-01T10:00:00Z","source":"file","service":"api","type":"log","level":"error","message":"timeout","attrs":{"dst_service":"cache","format":"plain","latency_ms":250,"status_code":504}}
+{"ts":"2024-05-01T10:00:00Z","source":"file","service":"api","type":"metric","attrs":{"format":"empty","status":"ok"},"metric":"queue_depth","value":7}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output changed:\n got %s\nwant %s", got, want)
+	}
+}
+
+// overflowAll sends every event to its route, as a throttle over its rate
+// does.
+type overflowAll struct{ route chan<- event.Event }
+
+func (t overflowAll) Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	for evt := range in {
+		t.route <- evt
+	}
+	return nil
+}
+
+func TestPipeline_RouteIsResolved(t *testing.T) {
+	var main, overflow bytes.Buffer
+	route := make(chan event.Event, 1)
+	p := &Pipeline{
+		Sources: []Source{events{
+			{Source: "file", Service: "api", Type: event.TypeLog, Message: "call", Attrs: map[string]any{"upstream": "10.0.0.7"}},
+		}},
+		Transform: overflowAll{route},
+		Sink:      jsonSink{&main},
+		Resolver:  resolve.NewStaticResolver(map[string]string{"10.0.0.7": "db"}),
+		Routes:    []Route{{Name: "overflow", In: route, Sink: jsonSink{&overflow}}},
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var got event.Event
+	if err := json.Unmarshal(overflow.Bytes(), &got); err != nil {
+		t.Fatalf("overflow output %q: %v", overflow.String(), err)
+	}
+	if got.Attrs["dst_service"] != "db" || got.Attrs["format"] != "plain" {
+		t.Errorf("routed event was not normalized and resolved: %v", got.Attrs)
+	}
+	if main.Len() != 0 {
+		t.Errorf("main sink got %q", main.String())
+	}
+}
@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+// Component kinds, the "kind" label of the per-component metrics.
+const (
+	KindSource    = "source"
+	KindTransform = "transform"
+	KindSink      = "sink"
+	KindRoute     = "route"
+)
+
+// Observed wraps src so that its events are counted and timed under name,
+// and stamps them with the time they were read (see event.Event.Received).
+func Observed(name string, src Source) Source {
+	return &observedSource{name: name, src: src}
+}
+
+type observedSource struct {
+	name string
+	src  Source
+}
+
+func (s *observedSource) Run(ctx context.Context, out chan<- event.Event) error {
+	st := newStage(KindSource, s.name)
+	bytes := metrics.ComponentBytes.WithLabelValues(KindSource, s.name)
+
+	ch := make(chan event.Event, 100)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		errc <- s.src.Run(ctx, ch)
+	}()
+
+	for evt := range ch {
+		if evt.Received.IsZero() {
+			evt.Received = time.Now()
+		}
+		st.in.Inc()
+		bytes.Add(float64(len(evt.Message)))
+		metrics.EventsReceived.WithLabelValues(evt.Source).Inc()
+		select {
+		case out <- evt:
+			st.sent(evt.Received)
+		case <-ctx.Done():
+			// Left unacknowledged: the source reads it again on restart.
+		}
+	}
+	err := <-errc
+	if err != nil && err != context.Canceled {
+		st.errors.Inc()
+	}
+	return err
+}
+
+// stage counts the events into and out of one named component.
+type stage struct {
+	in, out, errors prometheus.Counter
+	latency         prometheus.Observer
+}
+
+func newStage(kind, name string) *stage {
+	return &stage{
+		in:      metrics.ComponentReceived.WithLabelValues(kind, name),
+		out:     metrics.ComponentSent.WithLabelValues(kind, name),
+		errors:  metrics.ComponentErrors.WithLabelValues(kind, name),
+		latency: metrics.ComponentLatency.WithLabelValues(kind, name),
+	}
+}
+
+// sent counts an event passed on, and how long after it was read.
+func (s *stage) sent(received time.Time) {
+	s.out.Inc()
+	if !received.IsZero() {
+		s.latency.Observe(time.Since(received).Seconds())
+	}
+}
+
+// done wraps ack so that a sink's acknowledgement counts as the event
+// being sent on.
+func (s *stage) done(received time.Time, ack func()) func() {
+	return func() {
+		s.sent(received)
+		if ack != nil {
+			ack()
+		}
+	}
+}
+
+// utilization reports how full c is, from 0 to 1.
+func utilization[T any](c chan T) func() float64 {
+	return func() float64 { return float64(len(c)) / float64(cap(c)) }
+}
+
+// sampleChannels updates the channel utilization gauges every second
+// until ctx is done.
+func sampleChannels(ctx context.Context, channels map[string]func() float64) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		for name, fill := range channels {
+			metrics.ChannelUtilization.WithLabelValues(name).Set(fill())
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
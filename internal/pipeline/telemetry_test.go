@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+type lines []string
+
+func (l lines) Run(ctx context.Context, out chan<- event.Event) error {
+	for _, msg := range l {
+		out <- event.Event{Source: "test", Service: "api", Type: event.TypeLog, Message: msg}
+	}
+	return nil
+}
+
+// dropOdd passes on every other event, acknowledging the ones it drops.
+type dropOdd struct{}
+
+func (dropOdd) Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	i := 0
+	for evt := range in {
+		if i++; i%2 == 0 {
+			evt.Done()
+			continue
+		}
+		out <- evt
+	}
+	return nil
+}
+
+type doneSink struct{ err error }
+
+func (s doneSink) Run(ctx context.Context, in <-chan event.Event) error {
+	for evt := range in {
+		evt.Done()
+	}
+	return s.err
+}
+
+func TestPipeline_Telemetry(t *testing.T) {
+	p := &Pipeline{
+		Sources:       []Source{Observed("telemetry-in", lines{"a", "bb", "ccc"})},
+		Transform:     dropOdd{},
+		Sink:          doneSink{err: errors.New("disk full")},
+		TransformName: "telemetry-drop",
+		SinkName:      "telemetry-out",
+	}
+	counts := []struct {
+		metric     *prometheus.CounterVec
+		kind, name string
+		want       float64
+	}{
+		{metrics.ComponentReceived, KindSource, "telemetry-in", 3},
+		{metrics.ComponentSent, KindSource, "telemetry-in", 3},
+		{metrics.ComponentBytes, KindSource, "telemetry-in", 6},
+		{metrics.ComponentReceived, KindTransform, "telemetry-drop", 3},
+		{metrics.ComponentSent, KindTransform, "telemetry-drop", 2},
+		{metrics.ComponentReceived, KindSink, "telemetry-out", 2},
+		{metrics.ComponentSent, KindSink, "telemetry-out", 2},
+		{metrics.ComponentErrors, KindSink, "telemetry-out", 1},
+	}
+	before := make([]float64, len(counts))
+	for i, c := range counts {
+		before[i] = testutil.ToFloat64(c.metric.WithLabelValues(c.kind, c.name))
+	}
+
+	if err := p.Run(context.Background()); err == nil {
+		t.Fatal("want the sink's error")
+	}
+	for i, c := range counts {
+		if got := testutil.ToFloat64(c.metric.WithLabelValues(c.kind, c.name)) - before[i]; got != c.want {
+			t.Errorf("%s %s: got %v, want %v", c.kind, c.name, got, c.want)
+		}
+	}
+	if n := testutil.CollectAndCount(metrics.ComponentLatency, "logshipper_component_latency_seconds"); n == 0 {
+		t.Error("no latency observed")
+	}
+}
+
+type nopParser struct{}
+
+func (nopParser) ParseEvent(*event.Event) bool { return false }
+
+// TestSourceWrappers_ShutdownLeavesEventUnacked checks that an event
+// discarded at shutdown is not acknowledged, so that its source does not
+// commit past it.
+func TestSourceWrappers_ShutdownLeavesEventUnacked(t *testing.T) {
+	for name, wrap := range map[string]func(Source) Source{
+		"observed": func(src Source) Source { return Observed("shutdown-in", src) },
+		"parsed":   func(src Source) Source { return WithParser(src, nopParser{}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			acked := false
+			src := events{{Source: "test", Type: event.TypeLog, Message: "a", Ack: func() { acked = true }}}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if err := wrap(src).Run(ctx, make(chan event.Event)); err != nil {
+				t.Fatal(err)
+			}
+			if acked {
+				t.Error("an event never delivered was acknowledged")
+			}
+		})
+	}
+}
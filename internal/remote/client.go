@@ -0,0 +1,156 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"collector/internal/anomaly"
+	"collector/internal/graph"
+)
+
+const (
+	maxBackoff = 10 * time.Second
+	clientBuf  = 256
+)
+
+// Client follows the stream of a remote collector. It implements
+// tui.Backend, reconnecting with backoff whenever the stream drops.
+type Client struct {
+	base string
+	http *http.Client
+
+	mu   sync.RWMutex
+	snap graph.CallGraphSnapshot
+
+	graphEvents chan graph.GraphEvent
+	anomalies   chan anomaly.AnomalyEvent
+}
+
+// Dial connects to the collector at addr ("host:port" or a URL) and waits
+// for its first snapshot. The stream is followed until ctx is done.
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	base := strings.TrimRight(addr, "/")
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	c := &Client{
+		base:        base,
+		http:        &http.Client{},
+		graphEvents: make(chan graph.GraphEvent, clientBuf),
+		anomalies:   make(chan anomaly.AnomalyEvent, clientBuf),
+	}
+	body, err := c.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(body)
+	if err := c.next(dec); err != nil {
+		body.Close()
+		return nil, fmt.Errorf("remote %s: %w", addr, err)
+	}
+	go c.follow(ctx, body, dec)
+	return c, nil
+}
+
+func (c *Client) open(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+StreamPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("remote: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("remote: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("remote: %s: %s", c.base+StreamPath, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// follow reads frames until the stream fails, then reconnects.
+func (c *Client) follow(ctx context.Context, body io.ReadCloser, dec *json.Decoder) {
+	defer close(c.graphEvents)
+	defer close(c.anomalies)
+
+	backoff := time.Second
+	for {
+		var err error
+		for err == nil {
+			err = c.next(dec)
+		}
+		// The TUI owns the terminal, so a lost stream is not logged; the
+		// screens stop updating until it is back.
+		body.Close()
+		if ctx.Err() != nil {
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if body, err = c.open(ctx); err == nil {
+				dec = json.NewDecoder(body)
+				backoff = time.Second
+				break
+			}
+			backoff = min(2*backoff, maxBackoff)
+		}
+	}
+}
+
+// next reads and applies one frame. Events are dropped when the TUI is
+// not keeping up; the next snapshot still brings it up to date.
+func (c *Client) next(dec *json.Decoder) error {
+	var f Frame
+	if err := dec.Decode(&f); err != nil {
+		return err
+	}
+	switch {
+	case f.Snapshot != nil:
+		c.mu.Lock()
+		c.snap = *f.Snapshot
+		c.mu.Unlock()
+	case f.GraphEvent != nil:
+		select {
+		case c.graphEvents <- *f.GraphEvent:
+		default:
+		}
+	case f.Anomaly != nil:
+		select {
+		case c.anomalies <- *f.Anomaly:
+		default:
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the latest snapshot received.
+func (c *Client) Snapshot() graph.CallGraphSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snap
+}
+
+func (c *Client) GraphEvents() <-chan graph.GraphEvent { return c.graphEvents }
+
+func (c *Client) AnomalyEvents() <-chan anomaly.AnomalyEvent { return c.anomalies }
+
+// ErrMuteUnsupported is returned by Mute: the stream is read-only, so a
+// remote collector cannot be changed from the TUI.
+var ErrMuteUnsupported = errors.New("remote: muting is not available on a remote collector")
+
+// Mute always fails with ErrMuteUnsupported.
+func (c *Client) Mute(edgeKey string, until time.Time) error {
+	return ErrMuteUnsupported
+}
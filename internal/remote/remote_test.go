@@ -0,0 +1,107 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"collector/internal/anomaly"
+	"collector/internal/graph"
+)
+
+func startServer(t *testing.T) (*graph.CallGraph, *anomaly.ZScoreDetector, *httptest.Server) {
+	t.Helper()
+	g := graph.New(16)
+	det := anomaly.NewZScoreDetector(20, 3.0, 16).WithMinSamples(10).WithCooldown(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	srv := NewServer(g, det).WithInterval(20 * time.Millisecond)
+	go srv.Run(ctx)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return g, det, ts
+}
+
+func TestClient_Stream(t *testing.T) {
+	g, det, ts := startServer(t)
+	g.Feed(&graph.NormalizedEvent{SrcService: "api", DstService: "db", Operation: "query", Latency: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := Dial(ctx, strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap := c.Snapshot(); len(snap.Edges) != 1 || snap.Edges[0].CallCount != 1 {
+		t.Fatalf("first snapshot: %+v", snap)
+	}
+
+	// Dial returned after the first frame, so the client is subscribed.
+	g.Feed(&graph.NormalizedEvent{SrcService: "api", DstService: "cache", Operation: "get"})
+	select {
+	case ev := <-c.GraphEvents():
+		if ev.Type != graph.GraphEventNewEdge || ev.Edge.Dst != "cache" {
+			t.Errorf("graph event = %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no graph event received")
+	}
+
+	for i := 0; i < 20; i++ {
+		det.Feed("api|db|query", "latency", 10)
+	}
+	det.Feed("api|db|query", "latency", 10000)
+	select {
+	case ev := <-c.AnomalyEvents():
+		if ev.EdgeKey != "api|db|query" || ev.Metric != "latency" {
+			t.Errorf("anomaly = %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no anomaly received")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(c.Snapshot().Edges) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("periodic snapshots should pick up the new edge")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClient_MuteIsUnsupported(t *testing.T) {
+	_, _, ts := startServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := Dial(ctx, ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Mute("api|db|query", time.Now().Add(time.Hour)); !errors.Is(err, ErrMuteUnsupported) {
+		t.Errorf("Mute: %v", err)
+	}
+
+	// The stream is read-only: nothing else is served next to it.
+	resp, err := http.Post(ts.URL+"/tui/mute", "application/json", strings.NewReader(`{"edge_key":"api|db|query"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST /tui/mute: status %d", resp.StatusCode)
+	}
+}
+
+func TestDial_Unreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	if _, err := Dial(context.Background(), ts.URL); err == nil {
+		t.Error("want an error from a server without the stream")
+	}
+}
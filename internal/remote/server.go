@@ -0,0 +1,165 @@
+// Package remote streams a collector's call graph and anomalies over HTTP
+// so the TUI can attach to a headless collector.
+//
+// GET StreamPath returns newline-delimited JSON frames: a snapshot on
+// connect and every interval after, plus graph events and anomalies as
+// they happen. The stream is read-only: muting an edge needs access to the
+// collector itself.
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"collector/internal/anomaly"
+	"collector/internal/graph"
+)
+
+const StreamPath = "/tui/stream"
+
+// Frame types.
+const (
+	FrameSnapshot   = "snapshot"
+	FrameGraphEvent = "graph_event"
+	FrameAnomaly    = "anomaly"
+)
+
+// DefaultInterval is how often snapshots are sent, matching the TUI tick.
+const DefaultInterval = 500 * time.Millisecond
+
+// subscriberBuf bounds the events queued for one slow client; further
+// events are dropped for it rather than stalling the others.
+const subscriberBuf = 256
+
+// Frame is one message on the stream.
+type Frame struct {
+	Type       string                   `json:"type"`
+	Snapshot   *graph.CallGraphSnapshot `json:"snapshot,omitempty"`
+	GraphEvent *graph.GraphEvent        `json:"graph_event,omitempty"`
+	Anomaly    *anomaly.AnomalyEvent    `json:"anomaly,omitempty"`
+}
+
+// Server fans the graph and detector events out to every attached client.
+// It becomes the only reader of both event channels, so Run must be
+// started for clients to see events.
+type Server struct {
+	graph    *graph.CallGraph
+	detector *anomaly.ZScoreDetector
+	interval time.Duration
+
+	mu   sync.Mutex
+	subs map[chan Frame]struct{}
+}
+
+// NewServer serves g and det.
+func NewServer(g *graph.CallGraph, det *anomaly.ZScoreDetector) *Server {
+	return &Server{
+		graph:    g,
+		detector: det,
+		interval: DefaultInterval,
+		subs:     make(map[chan Frame]struct{}),
+	}
+}
+
+// WithInterval sets how often snapshots are streamed.
+func (s *Server) WithInterval(d time.Duration) *Server {
+	s.interval = d
+	return s
+}
+
+// Run forwards graph events and anomalies to subscribers until ctx is done.
+func (s *Server) Run(ctx context.Context) {
+	var anomalies <-chan anomaly.AnomalyEvent
+	if s.detector != nil {
+		anomalies = s.detector.Events()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-s.graph.Events():
+			s.broadcast(Frame{Type: FrameGraphEvent, GraphEvent: &ev})
+		case ev := <-anomalies:
+			s.broadcast(Frame{Type: FrameAnomaly, Anomaly: &ev})
+		}
+	}
+}
+
+// Handler serves StreamPath.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(StreamPath, s.serveStream)
+	return mux
+}
+
+func (s *Server) subscribe() chan Frame {
+	ch := make(chan Frame, subscriberBuf)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *Server) unsubscribe(ch chan Frame) {
+	s.mu.Lock()
+	delete(s.subs, ch)
+	s.mu.Unlock()
+}
+
+func (s *Server) broadcast(f Frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- f:
+		default:
+		}
+	}
+}
+
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub := s.subscribe()
+	defer s.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	send := func(f Frame) bool {
+		if err := enc.Encode(f); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	snapshot := func() Frame {
+		snap := s.graph.Snapshot()
+		return Frame{Type: FrameSnapshot, Snapshot: &snap}
+	}
+
+	if !send(snapshot()) {
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if !send(snapshot()) {
+				return
+			}
+		case f := <-sub:
+			if !send(f) {
+				return
+			}
+		}
+	}
+}
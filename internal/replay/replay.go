@@ -0,0 +1,174 @@
+// Package replay feeds a captured log through the parsers, the resolver,
+// the call graph and the anomaly detector in event time, so an incident
+// can be reproduced after the fact with the same anomalies.
+//
+// Lines are processed one at a time on the calling goroutine: the clock is
+// moved to each event's timestamp before it reaches the graph, and alerts
+// are collected before the next line is read. Transforms are not applied;
+// a replay sees every captured line.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"time"
+
+	"collector/internal/anomaly"
+	"collector/internal/clock"
+	"collector/internal/event"
+	"collector/internal/graph"
+	"collector/internal/parse"
+	"collector/internal/pipeline"
+	"collector/internal/resolve"
+)
+
+// DefaultSweepInterval matches the graph's default stale scan.
+const DefaultSweepInterval = 30 * time.Second
+
+const maxLineSize = 1 << 20
+
+// Replayer drives a call graph and its anomaly detector from a captured
+// log.
+type Replayer struct {
+	graph    *graph.CallGraph
+	detector *anomaly.ZScoreDetector
+	clock    *clock.Replay
+
+	Parser   pipeline.LineParser // optional source parse chain, tried before auto-detection
+	Resolver resolve.Resolver    // optional
+	Service  string              // service of the source the log was captured from
+
+	// Speed is the pace relative to the capture: 0 replays as fast as
+	// possible, 10 ten times faster than the events happened.
+	Speed float64
+	// SweepInterval is how much event time passes between stale edge
+	// sweeps; 0 uses DefaultSweepInterval.
+	SweepInterval time.Duration
+	// OnGraphEvent, if set, sees new edges, cycles and expired edges.
+	OnGraphEvent func(graph.GraphEvent)
+}
+
+// New attaches det to g and puts both on a clock driven by the replayed
+// events. Neither should be fed from anywhere else.
+func New(g *graph.CallGraph, det *anomaly.ZScoreDetector) *Replayer {
+	clk := clock.NewReplay()
+	g.WithClock(clk).WithAnomalyDetector(det)
+	det.WithClock(clk)
+	return &Replayer{graph: g, detector: det, clock: clk}
+}
+
+// Stats summarises a replay.
+type Stats struct {
+	Lines     int
+	Calls     int // events with both ends known, fed to the graph
+	Anomalies int
+	First     time.Time
+	Last      time.Time
+}
+
+// Run replays r until EOF or until ctx is done, calling onAnomaly for every
+// alert in the order it fired.
+func (rp *Replayer) Run(ctx context.Context, r io.Reader, onAnomaly func(anomaly.AnomalyEvent)) (Stats, error) {
+	var st Stats
+	sweepEvery := rp.SweepInterval
+	if sweepEvery <= 0 {
+		sweepEvery = DefaultSweepInterval
+	}
+	consumer, _ := rp.Parser.(pipeline.LineConsumer)
+
+	var nextSweep time.Time
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLineSize)
+	for sc.Scan() {
+		if err := ctx.Err(); err != nil {
+			return st, err
+		}
+		st.Lines++
+
+		// Lines without a timestamp of their own happened "now", which
+		// during a replay is the last timestamp seen.
+		evt := event.Event{
+			Timestamp: rp.clock.Now(),
+			Source:    "replay",
+			Service:   rp.Service,
+			Type:      event.TypeLog,
+			Message:   sc.Text(),
+			Level:     "info",
+		}
+		if consumer != nil && consumer.Consume(&evt) {
+			continue
+		}
+		if rp.Parser != nil {
+			rp.Parser.ParseEvent(&evt)
+		}
+		parse.ParseEvent(&evt)
+		n := event.Normalize(&evt)
+		pipeline.Resolve(ctx, rp.Resolver, n)
+
+		if err := rp.advance(ctx, n.Timestamp); err != nil {
+			return st, err
+		}
+		now := rp.clock.Now()
+		if st.First.IsZero() {
+			st.First = now
+		}
+		st.Last = now
+		switch {
+		case nextSweep.IsZero():
+			nextSweep = now.Add(sweepEvery)
+		case !now.Before(nextSweep):
+			rp.graph.SweepStale()
+			nextSweep = now.Add(sweepEvery)
+		}
+
+		if ge := graph.FromEvent(n); ge != nil {
+			st.Calls++
+			rp.graph.Feed(ge)
+		}
+		st.Anomalies += rp.drain(onAnomaly)
+	}
+	return st, sc.Err()
+}
+
+// Snapshot returns the call graph as the replay has left it.
+func (rp *Replayer) Snapshot() graph.CallGraphSnapshot {
+	return rp.graph.Snapshot()
+}
+
+// advance moves the clock to ts, first waiting out the gap scaled by Speed.
+func (rp *Replayer) advance(ctx context.Context, ts time.Time) error {
+	prev := rp.clock.Now()
+	if rp.Speed > 0 && !prev.IsZero() && ts.After(prev) {
+		t := time.NewTimer(time.Duration(float64(ts.Sub(prev)) / rp.Speed))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	rp.clock.Set(ts)
+	return nil
+}
+
+// drain empties both event channels so nothing raised by the last event
+// can be dropped for lack of buffer space.
+func (rp *Replayer) drain(onAnomaly func(anomaly.AnomalyEvent)) int {
+	fired := 0
+	for {
+		select {
+		case ev := <-rp.detector.Events():
+			fired++
+			if onAnomaly != nil {
+				onAnomaly(ev)
+			}
+		case ev := <-rp.graph.Events():
+			if rp.OnGraphEvent != nil {
+				rp.OnGraphEvent(ev)
+			}
+		default:
+			return fired
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"collector/internal/anomaly"
+	"collector/internal/graph"
+)
+
+var start = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// incidentLog is a day of api→db traffic at one call a minute with a
+// latency spike every six hours.
+func incidentLog() string {
+	var b strings.Builder
+	for i := 0; i < 24*60; i++ {
+		latency := 10 + i%3
+		if i > 0 && i%360 == 0 {
+			latency = 5000
+		}
+		fmt.Fprintf(&b, `{"ts":%q,"service":"api","dst_service":"db","operation":"query","latency_ms":%d}`+"\n",
+			start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), latency)
+	}
+	return b.String()
+}
+
+func newReplayer(cooldown time.Duration) *Replayer {
+	det := anomaly.NewZScoreDetector(50, 3.0, 16).WithMinSamples(20).WithCooldown(cooldown)
+	return New(graph.New(16), det)
+}
+
+func run(t *testing.T, rp *Replayer, log string) ([]anomaly.AnomalyEvent, Stats) {
+	t.Helper()
+	var fired []anomaly.AnomalyEvent
+	st, err := rp.Run(context.Background(), strings.NewReader(log), func(ev anomaly.AnomalyEvent) {
+		fired = append(fired, ev)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fired, st
+}
+
+func TestReplay_EventTime(t *testing.T) {
+	fired, st := run(t, newReplayer(30*time.Second), incidentLog())
+
+	if st.Lines != 24*60 || st.Calls != 24*60 {
+		t.Errorf("stats = %+v", st)
+	}
+	if !st.First.Equal(start) || !st.Last.Equal(start.Add(24*time.Hour-time.Minute)) {
+		t.Errorf("event time %s to %s", st.First, st.Last)
+	}
+	if len(fired) != 3 {
+		t.Fatalf("want the 3 spikes, got %d anomalies", len(fired))
+	}
+	for i, ev := range fired {
+		want := start.Add(time.Duration(i+1) * 6 * time.Hour)
+		if ev.Metric != "latency" || !ev.Timestamp.Equal(want) {
+			t.Errorf("anomaly %d = %s at %s, want latency at %s", i, ev.Metric, ev.Timestamp, want)
+		}
+	}
+}
+
+func TestReplay_Deterministic(t *testing.T) {
+	log := incidentLog()
+	first, _ := run(t, newReplayer(30*time.Second), log)
+	second, _ := run(t, newReplayer(30*time.Second), log)
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Errorf("replays differ:\n%v\n%v", first, second)
+	}
+}
+
+func TestReplay_CooldownInEventTime(t *testing.T) {
+	// A seven hour cooldown spans the gap between the first two spikes
+	// but not the third, though the whole replay takes milliseconds.
+	fired, _ := run(t, newReplayer(7*time.Hour), incidentLog())
+	if len(fired) != 2 {
+		t.Fatalf("want 2 anomalies, got %d", len(fired))
+	}
+	if want := start.Add(18 * time.Hour); !fired[1].Timestamp.Equal(want) {
+		t.Errorf("second anomaly at %s, want %s", fired[1].Timestamp, want)
+	}
+}
+
+func TestReplay_EdgeTTL(t *testing.T) {
+	rp := newReplayer(30 * time.Second)
+	log := fmt.Sprintf(`{"ts":%q,"service":"api","dst_service":"db"}
+{"ts":%q,"service":"api","dst_service":"cache"}
+{"ts":%q,"service":"api","dst_service":"cache"}
+`, start.Format(time.RFC3339),
+		start.Add(3*time.Minute).Format(time.RFC3339),
+		start.Add(6*time.Minute).Format(time.RFC3339))
+	run(t, rp, log)
+
+	// The default TTL is five minutes of event time.
+	edges := rp.graph.Edges()
+	if len(edges) != 1 || edges[0].Dst != "cache" {
+		t.Errorf("edges after replay = %+v", edges)
+	}
+}
+
+func TestReplay_LinesWithoutTimestamp(t *testing.T) {
+	rp := newReplayer(30 * time.Second)
+	log := fmt.Sprintf(`{"ts":%q,"service":"api","dst_service":"db"}
+{"service":"api","dst_service":"cache"}
+`, start.Format(time.RFC3339))
+	run(t, rp, log)
+
+	for _, e := range rp.graph.Edges() {
+		if !e.LastSeen.Equal(start) {
+			t.Errorf("%s last seen %s, want the previous event's time", e.Dst, e.LastSeen)
+		}
+	}
+}
+
+func TestReplay_Speed(t *testing.T) {
+	rp := newReplayer(30 * time.Second)
+	rp.Speed = 100
+	log := fmt.Sprintf("{\"ts\":%q}\n{\"ts\":%q}\n",
+		start.Format(time.RFC3339), start.Add(5*time.Second).Format(time.RFC3339))
+
+	began := time.Now()
+	run(t, rp, log)
+	if d := time.Since(began); d < 40*time.Millisecond {
+		t.Errorf("5s at 100x took %s, want about 50ms", d)
+	}
+}
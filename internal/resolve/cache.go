@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"collector/internal/clock"
 )
 
 type cacheEntry struct {
@@ -17,6 +19,7 @@ type CachingResolver struct {
 	inner   Resolver
 	ttl     time.Duration
 	maxSize int
+	clock   clock.Clock
 
 	mu    sync.RWMutex
 	cache map[string]cacheEntry
@@ -28,13 +31,20 @@ func NewCachingResolver(r Resolver, ttl time.Duration, maxSize int) *CachingReso
 		inner:   r,
 		ttl:     ttl,
 		maxSize: maxSize,
+		clock:   clock.Real(),
 		cache:   make(map[string]cacheEntry),
 	}
 }
 
+// WithClock makes cache entries expire by c instead of the wall clock.
+func (c *CachingResolver) WithClock(clk clock.Clock) *CachingResolver {
+	c.clock = clk
+	return c
+}
+
 func (c *CachingResolver) Resolve(ctx context.Context, host string) (string, bool) {
 	c.mu.RLock()
-	if e, ok := c.cache[host]; ok && time.Now().Before(e.expiresAt) {
+	if e, ok := c.cache[host]; ok && c.clock.Now().Before(e.expiresAt) {
 		c.mu.RUnlock()
 		return e.service, e.ok
 	}
@@ -51,7 +61,7 @@ func (c *CachingResolver) Resolve(ctx context.Context, host string) (string, boo
 	c.cache[host] = cacheEntry{
 		service:   service,
 		ok:        ok,
-		expiresAt: time.Now().Add(c.ttl),
+		expiresAt: c.clock.Now().Add(c.ttl),
 	}
 
 	return service, ok
@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 	"time"
+
+	"collector/internal/clock"
 )
 
 var ctx = context.Background()
@@ -116,10 +118,17 @@ func TestCachingResolver_TTLExpiry(t *testing.T) {
 		calls:    &calls,
 	}
 
-	cr := NewCachingResolver(inner, 10*time.Millisecond, 100)
+	clk := clock.NewFake(time.Now())
+	cr := NewCachingResolver(inner, time.Hour, 100).WithClock(clk)
 
 	cr.Resolve(ctx, "host")
-	time.Sleep(20 * time.Millisecond)
+	clk.Advance(59 * time.Minute)
+	cr.Resolve(ctx, "host")
+	if calls != 1 {
+		t.Fatalf("expected the entry to be cached within its TTL, got %d inner calls", calls)
+	}
+
+	clk.Advance(2 * time.Minute)
 	cr.Resolve(ctx, "host")
 
 	if calls != 2 {
@@ -0,0 +1,76 @@
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"collector/internal/event"
+)
+
+// Encodings of an event written by the file and kafka sinks.
+const (
+	EncodingJSON   = "json"   // the whole event as JSON
+	EncodingLogfmt = "logfmt" // the event as key=value pairs, attributes last
+	EncodingRaw    = "raw"    // the message alone
+)
+
+func checkEncoding(encoding string) error {
+	switch encoding {
+	case "", EncodingJSON, EncodingLogfmt, EncodingRaw:
+		return nil
+	}
+	return fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// encode renders evt without a trailing newline.
+func encode(evt event.Event, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingRaw:
+		return []byte(evt.Message), nil
+	case EncodingLogfmt:
+		return encodeLogfmt(evt), nil
+	}
+	return json.Marshal(evt)
+}
+
+func encodeLogfmt(evt event.Event) []byte {
+	var b strings.Builder
+	pair := func(k, v string) {
+		if v == "" {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		if strings.ContainsAny(v, " \t\"=\\") || !strconv.CanBackquote(v) {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(v)
+	}
+	if !evt.Timestamp.IsZero() {
+		pair("ts", evt.Timestamp.UTC().Format(time.RFC3339Nano))
+	}
+	pair("level", evt.Level)
+	pair("service", evt.Service)
+	pair("source", evt.Source)
+	if evt.Type == event.TypeMetric {
+		pair("metric", evt.Metric)
+		pair("value", strconv.FormatFloat(evt.Value, 'g', -1, 64))
+	}
+	pair("msg", evt.Message)
+	keys := make([]string, 0, len(evt.Attrs))
+	for k := range evt.Attrs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		pair(k, fmt.Sprint(evt.Attrs[k]))
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,479 @@
+package sinks
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+// Fsync policies of the file sink.
+const (
+	FsyncNever    = "never"    // leave syncing to the OS
+	FsyncRotate   = "rotate"   // sync a segment before it is closed
+	FsyncInterval = "interval" // sync on every flush, and before closing
+	FsyncAlways   = "always"   // sync after every event
+)
+
+const (
+	defaultFileFlushInterval = time.Second
+	defaultFileIdleTimeout   = time.Minute
+	fileRetentionInterval    = time.Minute
+	sealedTimeFormat         = "20060102T150405Z"
+)
+
+// sealedSuffix matches what sealing adds to a file name: the time it was
+// closed, a counter against clashes and the compression extension.
+var sealedSuffix = regexp.MustCompile(`\.\d{8}T\d{6}Z(-\d+)?(\.gz|\.zst)?$`)
+
+// FileSink appends events to local files, one line each. Path is a
+// template: {{field}} takes an event field (service, source, level, type,
+// metric or an attribute) and %Y, %m, %d, %H, %M and %S the event time in
+// UTC, so "/data/{{service}}/%Y-%m-%d.ndjson" keeps a file per service
+// and day.
+//
+// A file is closed when it reaches MaxSize, is RotateInterval old, has
+// had no writes for IdleTimeout, or the sink stops. Closing seals it as a
+// segment: it is renamed with the time as a suffix
+// ("2024-05-01.ndjson.20240501T235959Z"), compressed in the background,
+// and the segments beyond MaxFiles or older than MaxAge (across every file
+// of the template) are removed. The next event starts the file afresh.
+//
+// Events are acknowledged once written to the file and, with FsyncInterval
+// or FsyncAlways, synced to disk. Events that cannot be encoded or whose
+// file cannot be opened are logged, dropped and acknowledged; those lost
+// to a failed write are left unacknowledged.
+type FileSink struct {
+	Path           string
+	Encoding       string        // EncodingJSON (default), EncodingLogfmt or EncodingRaw
+	MaxSize        int64         // bytes; 0 means no limit
+	RotateInterval time.Duration // 0 means no limit
+	Compression    string        // of segments: "none" (default), "gzip" or "zstd"
+	MaxFiles       int           // segments kept, newest first; 0 means all
+	MaxAge         time.Duration // 0 means segments are kept however old
+	Fsync          string        // default FsyncRotate
+	FlushInterval  time.Duration // buffered writes are flushed this often; default 1s
+	IdleTimeout    time.Duration // default 1m
+	Clock          clock.Clock   // nil means the wall clock
+
+	open map[string]*segment
+}
+
+// segment is a file being written.
+type segment struct {
+	path    string
+	f       *os.File
+	w       *bufio.Writer
+	size    int64
+	opened  time.Time
+	written time.Time
+	acks    []func() // of the events written since the last flush
+}
+
+func (s *FileSink) Run(ctx context.Context, in <-chan event.Event) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	flushEvery := s.FlushInterval
+	if flushEvery <= 0 {
+		flushEvery = defaultFileFlushInterval
+	}
+	s.open = make(map[string]*segment)
+
+	// Sealed segments are compressed and pruned off the write path.
+	sealed := make(chan string, 64)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.maintain(sealed)
+	}()
+	defer func() {
+		for _, seg := range s.open {
+			s.seal(seg, sealed)
+		}
+		close(sealed)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(flushEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.flush(sealed)
+		case evt, ok := <-in:
+			if !ok {
+				return nil
+			}
+			s.write(evt, sealed)
+		}
+	}
+}
+
+func (s *FileSink) check() error {
+	if s.Path == "" {
+		return fmt.Errorf("file sink: path is required")
+	}
+	if err := checkEncoding(s.Encoding); err != nil {
+		return fmt.Errorf("file sink: %w", err)
+	}
+	switch s.Compression {
+	case "", "none", "gzip", "zstd":
+	default:
+		return fmt.Errorf("file sink: unsupported compression %q (none, gzip or zstd)", s.Compression)
+	}
+	switch s.Fsync {
+	case "", FsyncNever, FsyncRotate, FsyncInterval, FsyncAlways:
+	default:
+		return fmt.Errorf("file sink: unknown fsync policy %q", s.Fsync)
+	}
+	return nil
+}
+
+func (s *FileSink) write(evt event.Event, sealed chan<- string) {
+	line, err := encode(evt, s.Encoding)
+	if err != nil {
+		log.Printf("file sink: dropping event: %v", err)
+		evt.Done()
+		return
+	}
+	line = append(line, '\n')
+
+	now := clock.Or(s.Clock).Now()
+	path := s.render(evt, now)
+	seg := s.open[path]
+	if seg != nil && s.due(seg, now, len(line)) {
+		s.seal(seg, sealed)
+		seg = nil
+	}
+	if seg == nil {
+		if seg, err = s.openSegment(path, now); err != nil {
+			log.Printf("file sink: dropping event: %v", err)
+			evt.Done()
+			return
+		}
+	}
+
+	if _, err := seg.w.Write(line); err != nil {
+		s.fail(seg, err)
+		return
+	}
+	seg.size += int64(len(line))
+	seg.written = now
+	if evt.Ack != nil {
+		seg.acks = append(seg.acks, evt.Ack)
+	}
+	if s.Fsync == FsyncAlways {
+		s.sync(seg, true)
+	}
+}
+
+// due reports whether seg must be rotated before n more bytes.
+func (s *FileSink) due(seg *segment, now time.Time, n int) bool {
+	if s.MaxSize > 0 && seg.size > 0 && seg.size+int64(n) > s.MaxSize {
+		return true
+	}
+	return s.RotateInterval > 0 && now.Sub(seg.opened) >= s.RotateInterval
+}
+
+func (s *FileSink) openSegment(path string, now time.Time) (*segment, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	seg := &segment{path: path, f: f, w: bufio.NewWriter(f), opened: now, written: now}
+	if fi, err := f.Stat(); err == nil {
+		seg.size = fi.Size()
+	}
+	s.open[path] = seg
+	return seg, nil
+}
+
+// flush writes out every buffer, and closes the files gone idle.
+func (s *FileSink) flush(sealed chan<- string) {
+	idle := s.IdleTimeout
+	if idle <= 0 {
+		idle = defaultFileIdleTimeout
+	}
+	now := clock.Or(s.Clock).Now()
+	for _, seg := range s.open {
+		if now.Sub(seg.written) >= idle || s.due(seg, now, 0) {
+			s.seal(seg, sealed)
+			continue
+		}
+		s.sync(seg, s.Fsync == FsyncInterval)
+	}
+}
+
+// sync flushes seg's buffer, syncs the file when asked, and acknowledges
+// the events written.
+func (s *FileSink) sync(seg *segment, fsync bool) bool {
+	err := seg.w.Flush()
+	if err == nil && fsync {
+		err = seg.f.Sync()
+	}
+	if err != nil {
+		s.fail(seg, err)
+		return false
+	}
+	ackAll(seg.acks)
+	seg.acks = seg.acks[:0]
+	return true
+}
+
+// seal closes seg and hands it on, renamed, for compression and retention.
+func (s *FileSink) seal(seg *segment, sealed chan<- string) {
+	if !s.sync(seg, s.Fsync != FsyncNever) {
+		return
+	}
+	delete(s.open, seg.path)
+	if err := seg.f.Close(); err != nil {
+		log.Printf("file sink: %v", err)
+		return
+	}
+	name := sealedName(seg.path, clock.Or(s.Clock).Now())
+	if err := os.Rename(seg.path, name); err != nil {
+		log.Printf("file sink: %v", err)
+		return
+	}
+	sealed <- name
+}
+
+// fail gives up on seg after a write error. Its events since the last
+// flush are dropped, unacknowledged.
+func (s *FileSink) fail(seg *segment, err error) {
+	log.Printf("file sink: %s: %v", seg.path, err)
+	delete(s.open, seg.path)
+	seg.f.Close()
+	seg.acks = nil
+}
+
+// sealedName is path with the time appended, and a counter should a
+// segment of that name exist.
+func sealedName(path string, now time.Time) string {
+	base := path + "." + now.UTC().Format(sealedTimeFormat)
+	name := base
+	for i := 1; exists(name) || exists(name+".gz") || exists(name+".zst"); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// maintain compresses the segments sent on sealed and applies retention
+// after each one, and every minute for MaxAge, until sealed is closed.
+func (s *FileSink) maintain(sealed <-chan string) {
+	ticker := time.NewTicker(fileRetentionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case name, ok := <-sealed:
+			if !ok {
+				return
+			}
+			if err := s.compress(name); err != nil {
+				log.Printf("file sink: compress %s: %v", name, err)
+			}
+			s.retain()
+		case <-ticker.C:
+			s.retain()
+		}
+	}
+}
+
+// compress replaces the segment name with its compressed copy.
+func (s *FileSink) compress(name string) error {
+	var ext string
+	var newWriter func(io.Writer) (io.WriteCloser, error)
+	switch s.Compression {
+	case "gzip":
+		ext = ".gz"
+		newWriter = func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+	case "zstd":
+		ext = ".zst"
+		newWriter = func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }
+	default:
+		return nil
+	}
+
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := name + ext + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // after a failure; gone by rename otherwise
+	zw, err := newWriter(dst)
+	if err == nil {
+		_, err = io.Copy(zw, src)
+		err = errors.Join(err, zw.Close())
+	}
+	if err == nil && s.Fsync != FsyncNever {
+		err = dst.Sync()
+	}
+	if err = errors.Join(err, dst.Close()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name+ext); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// retain removes the segments of every file of the template beyond
+// MaxFiles, newest first, or older than MaxAge.
+func (s *FileSink) retain() {
+	if s.MaxFiles <= 0 && s.MaxAge <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(pathGlob(s.Path) + ".*")
+	if err != nil {
+		log.Printf("file sink: retention: %v", err)
+		return
+	}
+	type segmentFile struct {
+		name    string
+		modTime time.Time
+	}
+	var segments []segmentFile
+	for _, name := range matches {
+		if !sealedSuffix.MatchString(name) {
+			continue
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segmentFile{name, fi.ModTime()})
+	}
+	slices.SortFunc(segments, func(a, b segmentFile) int {
+		return b.modTime.Compare(a.modTime)
+	})
+
+	now := clock.Or(s.Clock).Now()
+	for i, seg := range segments {
+		if (s.MaxFiles > 0 && i >= s.MaxFiles) || (s.MaxAge > 0 && now.Sub(seg.modTime) > s.MaxAge) {
+			if err := os.Remove(seg.name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				log.Printf("file sink: retention: %v", err)
+			}
+		}
+	}
+}
+
+// render fills in the path template for evt.
+func (s *FileSink) render(evt event.Event, now time.Time) string {
+	t := evt.Timestamp
+	if t.IsZero() {
+		t = now
+	}
+	t = t.UTC()
+	return expandPath(s.Path,
+		func(field string) string { return pathElement(keyField(field, evt)) },
+		func(verb byte) (string, bool) {
+			switch verb {
+			case 'Y':
+				return t.Format("2006"), true
+			case 'm':
+				return t.Format("01"), true
+			case 'd':
+				return t.Format("02"), true
+			case 'H':
+				return t.Format("15"), true
+			case 'M':
+				return t.Format("04"), true
+			case 'S':
+				return t.Format("05"), true
+			}
+			return "", false
+		})
+}
+
+// pathGlob turns the path template into a pattern matching every path it
+// renders to.
+func pathGlob(tmpl string) string {
+	var b strings.Builder
+	for _, r := range tmpl {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return expandPath(b.String(),
+		func(string) string { return "*" },
+		func(verb byte) (string, bool) { return "*", strings.IndexByte("YmdHMS", verb) >= 0 })
+}
+
+// expandPath replaces the {{field}} and %verb placeholders of tmpl; "%%"
+// is a literal "%", and unknown verbs are kept as they are.
+func expandPath(tmpl string, field func(string) string, verb func(byte) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		switch {
+		case strings.HasPrefix(tmpl[i:], "{{"):
+			end := strings.Index(tmpl[i:], "}}")
+			if end < 0 {
+				b.WriteString(tmpl[i:])
+				return b.String()
+			}
+			b.WriteString(field(strings.TrimSpace(tmpl[i+2 : i+end])))
+			i += end + 1
+		case tmpl[i] == '%' && i+1 < len(tmpl):
+			if tmpl[i+1] == '%' {
+				b.WriteByte('%')
+				i++
+			} else if v, ok := verb(tmpl[i+1]); ok {
+				b.WriteString(v)
+				i++
+			} else {
+				b.WriteByte('%')
+			}
+		default:
+			b.WriteByte(tmpl[i])
+		}
+	}
+	return b.String()
+}
+
+// pathElement makes v safe as (part of) one path element.
+func pathElement(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, v)
+	if v == "" || v == "." || v == ".." {
+		return "_"
+	}
+	return v
+}
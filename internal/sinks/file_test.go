@@ -0,0 +1,173 @@
+package sinks
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+func runFileSink(t *testing.T, s *FileSink, events ...event.Event) {
+	t.Helper()
+	in := make(chan event.Event, len(events))
+	for _, evt := range events {
+		in <- evt
+	}
+	close(in)
+	if err := s.Run(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func logEvent(service, msg string, acks *int) event.Event {
+	return event.Event{
+		Timestamp: time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC),
+		Service:   service,
+		Type:      event.TypeLog,
+		Message:   msg,
+		Ack:       func() { *acks++ },
+	}
+}
+
+func TestFileSink_RotatesAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	var acks int
+	s := &FileSink{
+		Path:        filepath.Join(dir, "{{service}}", "%Y-%m-%d.log"),
+		Encoding:    EncodingRaw,
+		MaxSize:     25, // two lines
+		Compression: "gzip",
+		Clock:       clock.NewFake(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)),
+	}
+	runFileSink(t, s,
+		logEvent("api", "message-01", &acks),
+		logEvent("api", "message-02", &acks),
+		logEvent("web", "message-03", &acks),
+		logEvent("api", "message-04", &acks),
+		logEvent("api", "message-05", &acks),
+		logEvent("api", "message-06", &acks),
+	)
+	if acks != 6 {
+		t.Errorf("%d events acknowledged, want 6", acks)
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "api", "*"))
+	slices.Sort(segments)
+	want := []string{
+		"2024-05-01.log.20240502T000000Z-1.gz",
+		"2024-05-01.log.20240502T000000Z-2.gz",
+		"2024-05-01.log.20240502T000000Z.gz",
+	}
+	var names []string
+	var lines []string
+	for _, seg := range segments {
+		names = append(names, filepath.Base(seg))
+		lines = append(lines, strings.Fields(gunzip(t, seg))...)
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("segments %v, want %v", names, want)
+	}
+	slices.Sort(lines)
+	if want := []string{"message-01", "message-02", "message-04", "message-05", "message-06"}; !slices.Equal(lines, want) {
+		t.Errorf("lines %v, want %v", lines, want)
+	}
+	if web, _ := filepath.Glob(filepath.Join(dir, "web", "*.gz")); len(web) != 1 {
+		t.Errorf("web segments %v", web)
+	}
+}
+
+func gunzip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFileSink_Retention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	old := filepath.Join(dir, "app.log.20240401T000000Z")
+	if err := os.WriteFile(old, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(old, now.Add(-31*24*time.Hour), now.Add(-31*24*time.Hour))
+	unrelated := filepath.Join(dir, "app.log.bak")
+	os.WriteFile(unrelated, nil, 0o644)
+
+	var acks int
+	s := &FileSink{
+		Path:     filepath.Join(dir, "app.log"),
+		Encoding: EncodingLogfmt,
+		MaxSize:  1, // a segment per event
+		MaxFiles: 2,
+		MaxAge:   30 * 24 * time.Hour,
+		Clock:    clock.NewFake(now),
+	}
+	runFileSink(t, s,
+		logEvent("api", "one", &acks),
+		logEvent("api", "two", &acks),
+		logEvent("api", "three", &acks),
+	)
+
+	left, _ := filepath.Glob(filepath.Join(dir, "app.log.2*"))
+	if len(left) != 2 || slices.Contains(left, old) {
+		t.Errorf("segments left: %v", left)
+	}
+	if !exists(unrelated) {
+		t.Error("retention removed a file that is not a segment")
+	}
+	if exists(filepath.Join(dir, "app.log")) {
+		t.Error("the active file was not sealed on shutdown")
+	}
+	data, _ := os.ReadFile(left[0])
+	if !strings.HasPrefix(string(data), "ts=2024-05-01T23:59:00Z service=api msg=") {
+		t.Errorf("logfmt line %q", data)
+	}
+}
+
+func TestFileSink_Config(t *testing.T) {
+	for _, s := range []*FileSink{
+		{},
+		{Path: "x", Compression: "lz4"},
+		{Path: "x", Fsync: "sometimes"},
+		{Path: "x", Encoding: "xml"},
+	} {
+		if err := s.Run(context.Background(), nil); err == nil {
+			t.Errorf("%+v: want an error", s)
+		}
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	evt := event.Event{
+		Timestamp: time.Date(2024, 5, 1, 10, 4, 5, 0, time.FixedZone("CEST", 2*3600)),
+		Service:   "../etc",
+		Attrs:     map[string]any{"tenant": "acme"},
+	}
+	s := &FileSink{Path: "/data/{{ tenant }}/{{service}}/%Y/%m/%d/%H%M%S-%%-%q-{{missing}}.ndjson"}
+	if got, want := s.render(evt, time.Time{}), "/data/acme/.._etc/2024/05/01/080405-%-%q-_.ndjson"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+	if got, want := pathGlob(s.Path), "/data/*/*/*/*/*/***-%-%q-*.ndjson"; got != want {
+		t.Errorf("pathGlob = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"collector/internal/event"
+)
+
+const (
+	defaultKafkaLinger       = 100 * time.Millisecond
+	defaultKafkaBatchBytes   = 1 << 20
+	defaultKafkaFlushTimeout = 10 * time.Second
+)
+
+// KafkaSink produces events to a topic. Each record is keyed by Key, a
+// template such as "{service}" (fields service, source, level, type and
+// metric, or any attribute), so that events with the same key keep their
+// order within a partition. An event is acknowledged once the brokers have
+// its record. One that cannot be encoded is logged, dropped and
+// acknowledged, since reading it again would not help; one the brokers do
+// not take is logged and left unacknowledged, so that a kafka source
+// upstream does not commit past it.
+type KafkaSink struct {
+	Brokers       []string
+	Topic         string
+	Key           string        // template; empty means no key
+	Encoding      string        // EncodingJSON (default), EncodingLogfmt or EncodingRaw
+	BatchBytes    int           // largest record batch; default 1 MiB
+	FlushInterval time.Duration // linger before a batch is sent; default 100ms
+	Compression   string        // "none", "gzip", "snappy", "lz4" or "zstd" (default)
+	Acks          string        // "all" (default), "leader" or "none"
+
+	// Opts are added to the client options, e.g. for TLS or SASL.
+	Opts []kgo.Opt
+}
+
+func (s *KafkaSink) Run(ctx context.Context, in <-chan event.Event) error {
+	opts, err := s.options()
+	if err != nil {
+		return err
+	}
+	cl, err := kgo.NewClient(append(opts, s.Opts...)...)
+	if err != nil {
+		return fmt.Errorf("kafka sink: %w", err)
+	}
+	defer cl.Close()
+
+	// Records are produced with a context of their own, so that those
+	// buffered when ctx ends still get flushed; it ends only if the flush
+	// takes too long.
+	produceCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() { time.AfterFunc(defaultKafkaFlushTimeout, cancel) })
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush(produceCtx, cl)
+			return nil
+		case evt, ok := <-in:
+			if !ok {
+				s.flush(produceCtx, cl)
+				return nil
+			}
+			rec, err := s.record(evt)
+			if err != nil {
+				log.Printf("kafka sink: dropping event: %v", err)
+				evt.Done()
+				continue
+			}
+			cl.Produce(produceCtx, rec, func(r *kgo.Record, err error) {
+				if err != nil {
+					if !errors.Is(err, context.Canceled) {
+						log.Printf("kafka sink: %s: %v", r.Topic, err)
+					}
+					return // not delivered, so not acknowledged
+				}
+				evt.Done()
+			})
+		}
+	}
+}
+
+func (s *KafkaSink) flush(ctx context.Context, cl *kgo.Client) {
+	if err := cl.Flush(ctx); err != nil {
+		log.Printf("kafka sink: flush: %v", err)
+	}
+}
+
+func (s *KafkaSink) options() ([]kgo.Opt, error) {
+	if len(s.Brokers) == 0 || s.Topic == "" {
+		return nil, fmt.Errorf("kafka sink: brokers and topic are required")
+	}
+	if err := checkEncoding(s.Encoding); err != nil {
+		return nil, fmt.Errorf("kafka sink: %w", err)
+	}
+
+	var codec kgo.CompressionCodec
+	switch s.Compression {
+	case "", "zstd":
+		codec = kgo.ZstdCompression()
+	case "gzip":
+		codec = kgo.GzipCompression()
+	case "snappy":
+		codec = kgo.SnappyCompression()
+	case "lz4":
+		codec = kgo.Lz4Compression()
+	case "none":
+		codec = kgo.NoCompression()
+	default:
+		return nil, fmt.Errorf("kafka sink: unknown compression %q", s.Compression)
+	}
+	batchBytes := s.BatchBytes
+	if batchBytes <= 0 {
+		batchBytes = defaultKafkaBatchBytes
+	}
+	linger := s.FlushInterval
+	if linger <= 0 {
+		linger = defaultKafkaLinger
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(s.Brokers...),
+		kgo.DefaultProduceTopic(s.Topic),
+		kgo.ProducerBatchCompression(codec),
+		kgo.ProducerBatchMaxBytes(int32(batchBytes)),
+		kgo.ProducerLinger(linger),
+	}
+	switch s.Acks {
+	case "", "all":
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	case "leader":
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()), kgo.DisableIdempotentWrite())
+	case "none":
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()), kgo.DisableIdempotentWrite())
+	default:
+		return nil, fmt.Errorf("kafka sink: unknown acks %q", s.Acks)
+	}
+	return opts, nil
+}
+
+func (s *KafkaSink) record(evt event.Event) (*kgo.Record, error) {
+	rec := &kgo.Record{Topic: s.Topic, Timestamp: evt.Timestamp}
+	if s.Key != "" {
+		rec.Key = []byte(expandKey(s.Key, evt))
+	}
+	value, err := encode(evt, s.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	rec.Value = value
+	return rec, nil
+}
+
+// expandKey replaces each {field} of tmpl with that field of evt; unknown
+// fields become empty.
+func expandKey(tmpl string, evt event.Event) string {
+	var b strings.Builder
+	for {
+		open := strings.IndexByte(tmpl, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(tmpl[open:], '}')
+		if end < 0 {
+			break
+		}
+		b.WriteString(tmpl[:open])
+		b.WriteString(keyField(tmpl[open+1:open+end], evt))
+		tmpl = tmpl[open+end+1:]
+	}
+	b.WriteString(tmpl)
+	return b.String()
+}
+
+func keyField(name string, evt event.Event) string {
+	switch name {
+	case "service":
+		return evt.Service
+	case "source":
+		return evt.Source
+	case "level":
+		return evt.Level
+	case "type":
+		return evt.Type
+	case "metric":
+		return evt.Metric
+	}
+	if v, ok := evt.Attrs[name]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
@@ -0,0 +1,248 @@
+package sinks
+
+import (
+	"context"
+	"math"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"collector/internal/event"
+	"collector/internal/sources"
+)
+
+func TestKafkaSink(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, "logs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	var acked atomic.Int32
+	in := make(chan event.Event, 4)
+	for _, svc := range []string{"api", "web", "api"} {
+		in <- event.Event{
+			Timestamp: time.Unix(1700000000, 0).UTC(),
+			Service:   svc,
+			Type:      event.TypeLog,
+			Message:   svc + " started",
+			Ack:       func() { acked.Add(1) },
+		}
+	}
+	close(in)
+	sink := &KafkaSink{Brokers: cluster.ListenAddrs(), Topic: "logs", Key: "{service}-{region}", Compression: "gzip"}
+	if err := sink.Run(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	if n := acked.Load(); n != 3 {
+		t.Errorf("%d events acknowledged, want 3", n)
+	}
+
+	cl, err := kgo.NewClient(
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics("logs"),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	partitions := make(map[string]int32)
+	var records int
+	for records < 3 && ctx.Err() == nil {
+		cl.PollFetches(ctx).EachRecord(func(r *kgo.Record) {
+			records++
+			var evt event.Event
+			if err := json.Unmarshal(r.Value, &evt); err != nil {
+				t.Fatal(err)
+			}
+			if string(r.Key) != evt.Service+"-" || evt.Message != evt.Service+" started" {
+				t.Errorf("key %q, event %+v", r.Key, evt)
+			}
+			if p, ok := partitions[evt.Service]; ok && p != r.Partition {
+				t.Errorf("%s events in partitions %d and %d", evt.Service, p, r.Partition)
+			}
+			partitions[evt.Service] = r.Partition
+		})
+	}
+	if records != 3 {
+		t.Errorf("consumed %d records, want 3", records)
+	}
+}
+
+func TestExpandKey(t *testing.T) {
+	evt := event.Event{Service: "api", Level: "error", Attrs: map[string]any{"region": "eu", "shard": 3}}
+	for tmpl, want := range map[string]string{
+		"{service}":         "api",
+		"{service}/{level}": "api/error",
+		"{region}-{shard}":  "eu-3",
+		"static":            "static",
+		"{missing}x":        "x",
+		"unclosed {service": "unclosed {service",
+	} {
+		if got := expandKey(tmpl, evt); got != want {
+			t.Errorf("expandKey(%q) = %q, want %q", tmpl, got, want)
+		}
+	}
+}
+
+// TestKafkaSink_FailedRecordIsNotCommitted relays a consumer group through
+// the sink: a record the sink cannot produce stays unacknowledged, so the
+// group resumes at it even though a later record was delivered.
+func TestKafkaSink_FailedRecordIsNotCommitted(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "in", "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	brokers := cluster.ListenAddrs()
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.DefaultProduceTopic("in"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"small", strings.Repeat("x", 4096), "also small"} {
+		if err := producer.ProduceSync(context.Background(), &kgo.Record{Value: []byte(v)}).FirstErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	producer.Close()
+
+	newSource := func() *sources.KafkaSource {
+		return &sources.KafkaSource{Brokers: brokers, Topics: []string{"in"}, Group: "relay", StartOffset: "earliest"}
+	}
+	srcCtx, stopSource := context.WithCancel(context.Background())
+	srcDone := make(chan error, 1)
+	read := make(chan event.Event, 10)
+	go func() { srcDone <- newSource().Run(srcCtx, read) }()
+
+	// Count the acknowledgements on their way through.
+	var acked atomic.Int32
+	relayed := make(chan event.Event, 10)
+	go func() {
+		defer close(relayed)
+		for evt := range read {
+			ack := evt.Ack
+			evt.Ack = func() { acked.Add(1); ack() }
+			relayed <- evt
+		}
+	}()
+	sinkCtx, stopSink := context.WithCancel(context.Background())
+	sinkDone := make(chan error, 1)
+	sink := &KafkaSink{Brokers: brokers, Topic: "out", Encoding: EncodingRaw, BatchBytes: 1024, FlushInterval: time.Millisecond}
+	go func() { sinkDone <- sink.Run(sinkCtx, relayed) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for acked.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond) // for a wrong third ack to show up
+	if n := acked.Load(); n != 2 {
+		t.Fatalf("%d events acknowledged, want the 2 produced", n)
+	}
+	stopSource()
+	if err := <-srcDone; err != nil {
+		t.Fatal(err)
+	}
+	stopSink()
+	<-sinkDone
+
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newSource().Run(ctx, out)
+	select {
+	case evt := <-out:
+		if evt.Attrs["kafka_offset"] != int64(1) {
+			t.Errorf("group resumed at offset %v, want 1", evt.Attrs["kafka_offset"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("group resumed past the record that was never produced")
+	}
+}
+
+func TestKafkaSink_UnencodableRecordIsCommittedPast(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "in", "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	brokers := cluster.ListenAddrs()
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.DefaultProduceTopic("in"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	produce := func(values ...string) {
+		for _, v := range values {
+			if err := producer.ProduceSync(context.Background(), &kgo.Record{Value: []byte(v)}).FirstErr(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	produce("bad", "good", "also good")
+
+	newSource := func() *sources.KafkaSource {
+		return &sources.KafkaSource{Brokers: brokers, Topics: []string{"in"}, Group: "relay", StartOffset: "earliest"}
+	}
+	srcCtx, stopSource := context.WithCancel(context.Background())
+	srcDone := make(chan error, 1)
+	read := make(chan event.Event, 10)
+	go func() { srcDone <- newSource().Run(srcCtx, read) }()
+
+	// The first record picks up an attribute JSON cannot encode.
+	var acked atomic.Int32
+	relayed := make(chan event.Event, 10)
+	go func() {
+		defer close(relayed)
+		for evt := range read {
+			if evt.Message == "bad" {
+				evt.Attrs["ratio"] = math.NaN()
+			}
+			ack := evt.Ack
+			evt.Ack = func() { acked.Add(1); ack() }
+			relayed <- evt
+		}
+	}()
+	sinkCtx, stopSink := context.WithCancel(context.Background())
+	sinkDone := make(chan error, 1)
+	sink := &KafkaSink{Brokers: brokers, Topic: "out", FlushInterval: time.Millisecond}
+	go func() { sinkDone <- sink.Run(sinkCtx, relayed) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for acked.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := acked.Load(); n != 3 {
+		t.Fatalf("%d events acknowledged, want all 3", n)
+	}
+	stopSource()
+	if err := <-srcDone; err != nil {
+		t.Fatal(err)
+	}
+	stopSink()
+	<-sinkDone
+
+	produce("next")
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newSource().Run(ctx, out)
+	select {
+	case evt := <-out:
+		if evt.Attrs["kafka_offset"] != int64(3) {
+			t.Errorf("group resumed at offset %v, want 3", evt.Attrs["kafka_offset"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing read after the restart")
+	}
+}
@@ -0,0 +1,135 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"collector/internal/event"
+)
+
+// PromExporterSink aggregates metric events into Prometheus gauges and
+// counters and exposes them through the collector's metrics registry, so
+// they are served with the internal metrics on the /metrics endpoint the
+// app starts at the sink's address.
+//
+// Counter increments (see isCounter) accumulate; other values are gauges
+// keeping the last value seen. Series are labelled as metricLabels
+// describes.
+type PromExporterSink struct {
+	Labels     map[string]string // Attrs key -> label name
+	Registerer prometheus.Registerer
+
+	mu     sync.Mutex
+	series map[string]*exportedSeries
+}
+
+type exportedSeries struct {
+	name    string
+	labels  map[string]string
+	value   float64
+	counter bool
+}
+
+func (s *PromExporterSink) Run(ctx context.Context, in <-chan event.Event) error {
+	reg := s.Registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if err := reg.Register(s); err != nil {
+		return fmt.Errorf("prometheus_exporter: register: %w", err)
+	}
+	defer reg.Unregister(s)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt, ok := <-in:
+			if !ok {
+				return nil
+			}
+			s.Observe(&evt)
+			evt.Done()
+		}
+	}
+}
+
+// Observe folds one metric event into the exported series.
+func (s *PromExporterSink) Observe(evt *event.Event) {
+	if evt.Type != event.TypeMetric || evt.Metric == "" {
+		return
+	}
+	name := sanitizeName(evt.Metric)
+	labels := metricLabels(evt, s.Labels)
+	counter := isCounter(evt)
+
+	var key strings.Builder
+	key.WriteString(name)
+	for _, k := range sortedKeys(labels) {
+		key.WriteString("," + k + "=" + labels[k])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.series == nil {
+		s.series = make(map[string]*exportedSeries)
+	}
+	ser, ok := s.series[key.String()]
+	if !ok {
+		ser = &exportedSeries{name: name, labels: labels, counter: counter}
+		s.series[key.String()] = ser
+	}
+	if ser.counter {
+		ser.value += evt.Value
+	} else {
+		ser.value = evt.Value
+	}
+}
+
+// Describe sends no descriptors: the set of series is only known at runtime,
+// which makes this an unchecked collector.
+func (s *PromExporterSink) Describe(chan<- *prometheus.Desc) {}
+
+func (s *PromExporterSink) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// All series of one family must share label names, so each family is
+	// emitted with the union of its labels and missing values left empty.
+	families := make(map[string][]*exportedSeries)
+	for _, ser := range s.series {
+		families[ser.name] = append(families[ser.name], ser)
+	}
+
+	for name, members := range families {
+		labelSet := make(map[string]string)
+		for _, ser := range members {
+			for k := range ser.labels {
+				labelSet[k] = ""
+			}
+		}
+		labelNames := sortedKeys(labelSet)
+
+		valueType := prometheus.GaugeValue
+		if members[0].counter {
+			valueType = prometheus.CounterValue
+		}
+		desc := prometheus.NewDesc(name, "Exported from collector metric events", labelNames, nil)
+
+		for _, ser := range members {
+			values := make([]string, len(labelNames))
+			for i, l := range labelNames {
+				values[i] = ser.labels[l]
+			}
+			m, err := prometheus.NewConstMetric(desc, valueType, ser.value, values...)
+			if err != nil {
+				continue
+			}
+			ch <- m
+		}
+	}
+}
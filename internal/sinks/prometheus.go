@@ -0,0 +1,93 @@
+package sinks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"collector/internal/event"
+)
+
+// defaultLabels are the attributes that become labels when no labels map
+// is configured: "le" keeps the buckets of a histogram apart. Anything else
+// has to be listed, so that free-form attributes cannot multiply series.
+var defaultLabels = []string{"le"}
+
+// metricLabels builds the Prometheus label set for a metric event: the
+// service, and the attributes labelMap maps to label names, or
+// defaultLabels when it is empty.
+func metricLabels(evt *event.Event, labelMap map[string]string) map[string]string {
+	labels := make(map[string]string, len(labelMap)+1)
+	if evt.Service != "" {
+		labels["service"] = evt.Service
+	}
+
+	if len(labelMap) > 0 {
+		for attr, name := range labelMap {
+			if v, ok := labelValue(evt.Attrs[attr]); ok {
+				labels[sanitizeName(name)] = v
+			}
+		}
+		return labels
+	}
+
+	for _, k := range defaultLabels {
+		if v, ok := labelValue(evt.Attrs[k]); ok {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+func labelValue(v any) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, val != ""
+	case bool:
+		return fmt.Sprintf("%t", val), true
+	case float64:
+		return fmt.Sprintf("%g", val), true
+	case int:
+		return fmt.Sprintf("%d", val), true
+	case int64:
+		return fmt.Sprintf("%d", val), true
+	}
+	return "", false
+}
+
+// sanitizeName rewrites s into a valid Prometheus metric or label name.
+func sanitizeName(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// isCounter reports whether a metric event is an increment to accumulate,
+// as log_to_metric marks them with Attrs["metric_type"] "counter". Other
+// values, including cumulative counters an application reports itself, are
+// passed on as they are.
+func isCounter(evt *event.Event) bool {
+	t, _ := evt.Attrs["metric_type"].(string)
+	return strings.EqualFold(t, "counter")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,262 @@
+package sinks
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"collector/internal/event"
+)
+
+func metricEvent(name string, value float64, attrs map[string]any) event.Event {
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+	attrs["format"] = "metric_json"
+	return event.Event{
+		Timestamp: time.Unix(1700000000, 0),
+		Service:   "api",
+		Type:      event.TypeMetric,
+		Metric:    name,
+		Value:     value,
+		Attrs:     attrs,
+	}
+}
+
+// decodedSeries is a flattened TimeSeries used for assertions.
+type decodedSeries struct {
+	labels map[string]string
+	value  float64
+	ts     int64
+}
+
+func decodeWriteRequest(t *testing.T, b []byte) []decodedSeries {
+	t.Helper()
+	var out []decodedSeries
+	for len(b) > 0 {
+		_, _, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		tsBytes, n := protowire.ConsumeBytes(b)
+		b = b[n:]
+
+		ser := decodedSeries{labels: map[string]string{}}
+		for len(tsBytes) > 0 {
+			num, _, n := protowire.ConsumeTag(tsBytes)
+			tsBytes = tsBytes[n:]
+			inner, n := protowire.ConsumeBytes(tsBytes)
+			tsBytes = tsBytes[n:]
+			switch num {
+			case 1:
+				_, _, n := protowire.ConsumeTag(inner)
+				name, m := protowire.ConsumeString(inner[n:])
+				inner = inner[n+m:]
+				_, _, n = protowire.ConsumeTag(inner)
+				value, _ := protowire.ConsumeString(inner[n:])
+				ser.labels[name] = value
+			case 2:
+				_, _, n := protowire.ConsumeTag(inner)
+				bits, m := protowire.ConsumeFixed64(inner[n:])
+				ser.value = math.Float64frombits(bits)
+				inner = inner[n+m:]
+				_, _, n = protowire.ConsumeTag(inner)
+				v, _ := protowire.ConsumeVarint(inner[n:])
+				ser.ts = int64(v)
+			}
+		}
+		out = append(out, ser)
+	}
+	return out
+}
+
+func TestRemoteWriteSink_EncodesBatch(t *testing.T) {
+	var mu sync.Mutex
+	var got []decodedSeries
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("Content-Encoding = %q", r.Header.Get("Content-Encoding"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		raw, err := snappy.Decode(nil, body)
+		if err != nil {
+			t.Errorf("snappy decode: %v", err)
+		}
+		mu.Lock()
+		got = append(got, decodeWriteRequest(t, raw)...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink := &RemoteWriteSink{
+		Endpoint: srv.URL,
+		Labels:   map[string]string{"region": "dc"},
+	}
+	in := make(chan event.Event, 4)
+	in <- metricEvent("requests_total", 2, map[string]any{"region": "eu", "ignored": "x", "metric_type": "counter"})
+	in <- metricEvent("requests_total", 3, map[string]any{"region": "eu", "metric_type": "counter"})
+	in <- metricEvent("queue.depth", 7, nil)
+	in <- event.Event{Type: event.TypeLog, Message: "not a metric"}
+	close(in)
+
+	if err := sink.Run(context.Background(), in); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 3 {
+		t.Fatalf("got %d series, want 3", len(got))
+	}
+	if got[0].labels["__name__"] != "requests_total" || got[0].labels["dc"] != "eu" {
+		t.Errorf("unexpected labels %v", got[0].labels)
+	}
+	if _, ok := got[0].labels["ignored"]; ok {
+		t.Errorf("unmapped attr leaked into labels: %v", got[0].labels)
+	}
+	if got[1].value != 5 {
+		t.Errorf("counter should accumulate to 5, got %v", got[1].value)
+	}
+	if got[2].labels["__name__"] != "queue_depth" || got[2].value != 7 {
+		t.Errorf("gauge: %v = %v", got[2].labels, got[2].value)
+	}
+	if got[2].ts != 1700000000000 {
+		t.Errorf("timestamp = %d", got[2].ts)
+	}
+}
+
+func TestRemoteWriteSink_RetriesServerErrors(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sink := &RemoteWriteSink{Endpoint: srv.URL, MaxRetries: 2}
+	in := make(chan event.Event, 1)
+	in <- metricEvent("up", 1, nil)
+	close(in)
+
+	if err := sink.Run(context.Background(), in); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestRemoteWriteSink_AcksOnlyPermanentDrops(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		want   int
+	}{
+		{http.StatusBadRequest, 1},         // rejected for good
+		{http.StatusServiceUnavailable, 0}, // may be taken later
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		acks := 0
+		evt := metricEvent("up", 1, nil)
+		evt.Ack = func() { acks++ }
+		in := make(chan event.Event, 1)
+		in <- evt
+		close(in)
+		if err := (&RemoteWriteSink{Endpoint: srv.URL, MaxRetries: -1}).Run(context.Background(), in); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		srv.Close()
+		if acks != tt.want {
+			t.Errorf("status %d: %d acks, want %d", tt.status, acks, tt.want)
+		}
+	}
+}
+
+func TestPromExporterSink_Aggregates(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	sink := &PromExporterSink{Labels: map[string]string{"queue": "queue"}}
+	if err := reg.Register(sink); err != nil {
+		t.Fatal(err)
+	}
+
+	counter := func(queue string) map[string]any {
+		attrs := map[string]any{"metric_type": "counter"}
+		if queue != "" {
+			attrs["queue"] = queue
+		}
+		return attrs
+	}
+	for _, evt := range []event.Event{
+		metricEvent("jobs_total", 1, counter("a")),
+		metricEvent("jobs_total", 4, counter("a")),
+		metricEvent("jobs_total", 2, counter("")),
+		metricEvent("temperature", 20, nil),
+		metricEvent("temperature", 18.5, nil),
+		// Cumulative, as an application reports it: not summed.
+		metricEvent("uptime_seconds_total", 100, nil),
+		metricEvent("uptime_seconds_total", 160, nil),
+	} {
+		sink.Observe(&evt)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	byName := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			key := f.GetName()
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "queue" && l.GetValue() != "" {
+					key += "{queue=" + l.GetValue() + "}"
+				}
+			}
+			if m.Counter != nil {
+				byName[key] = m.GetCounter().GetValue()
+			} else {
+				byName[key] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	if byName["jobs_total{queue=a}"] != 5 {
+		t.Errorf("jobs_total{queue=a} = %v, want 5", byName["jobs_total{queue=a}"])
+	}
+	if byName["jobs_total"] != 2 {
+		t.Errorf("jobs_total = %v, want 2", byName["jobs_total"])
+	}
+	if byName["temperature"] != 18.5 {
+		t.Errorf("temperature = %v, want 18.5", byName["temperature"])
+	}
+	if byName["uptime_seconds_total"] != 160 {
+		t.Errorf("uptime_seconds_total = %v, want the last value 160", byName["uptime_seconds_total"])
+	}
+}
+
+func TestMetricLabels_Default(t *testing.T) {
+	evt := metricEvent("latency_bucket", 1, map[string]any{"le": "0.5", "request_id": "r-123", "user": "u-9"})
+	got := metricLabels(&evt, nil)
+	if len(got) != 2 || got["service"] != "api" || got["le"] != "0.5" {
+		t.Errorf("labels %v, want service and le only", got)
+	}
+}
@@ -0,0 +1,252 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"collector/internal/event"
+)
+
+const (
+	defaultRemoteWriteBatch    = 500
+	defaultRemoteWriteInterval = 5 * time.Second
+	defaultRemoteWriteRetries  = 3
+	remoteWriteBackoff         = 500 * time.Millisecond
+)
+
+// RemoteWriteSink ships metric events to a Prometheus remote-write endpoint
+// as snappy-compressed protobuf WriteRequests. Log events are ignored.
+//
+// Counter increments (see isCounter) are accumulated per series so the
+// endpoint always receives monotonically increasing values; other values
+// are sent as-is. Series are labelled as metricLabels describes.
+type RemoteWriteSink struct {
+	Endpoint      string
+	Headers       map[string]string
+	Labels        map[string]string // Attrs key -> label name
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxRetries    int
+	Client        *http.Client
+
+	counters map[string]float64
+	batch    []remoteSample
+	acks     []func() // of the events in batch, called once it is sent
+}
+
+type remoteSample struct {
+	labels    []remoteLabel // sorted by name, includes __name__
+	value     float64
+	timestamp int64 // unix millis
+}
+
+type remoteLabel struct {
+	name  string
+	value string
+}
+
+func (s *RemoteWriteSink) Run(ctx context.Context, in <-chan event.Event) error {
+	if s.Endpoint == "" {
+		return fmt.Errorf("prometheus_remote_write: endpoint is required")
+	}
+	s.applyDefaults()
+
+	ticker := time.NewTicker(s.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush(context.Background())
+			return nil
+		case <-ticker.C:
+			s.flush(ctx)
+		case evt, ok := <-in:
+			if !ok {
+				s.flush(context.Background())
+				return nil
+			}
+			if !s.add(&evt) {
+				evt.Done()
+				continue
+			}
+			if evt.Ack != nil {
+				s.acks = append(s.acks, evt.Ack)
+			}
+			if len(s.batch) >= s.BatchSize {
+				s.flush(ctx)
+			}
+		}
+	}
+}
+
+func (s *RemoteWriteSink) applyDefaults() {
+	if s.BatchSize <= 0 {
+		s.BatchSize = defaultRemoteWriteBatch
+	}
+	if s.FlushInterval <= 0 {
+		s.FlushInterval = defaultRemoteWriteInterval
+	}
+	if s.MaxRetries < 0 {
+		s.MaxRetries = 0
+	} else if s.MaxRetries == 0 {
+		s.MaxRetries = defaultRemoteWriteRetries
+	}
+	if s.Client == nil {
+		s.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if s.counters == nil {
+		s.counters = make(map[string]float64)
+	}
+}
+
+// add converts a metric event into a sample and appends it to the batch.
+func (s *RemoteWriteSink) add(evt *event.Event) bool {
+	if evt.Type != event.TypeMetric || evt.Metric == "" {
+		return false
+	}
+
+	name := sanitizeName(evt.Metric)
+	labels := metricLabels(evt, s.Labels)
+	labels["__name__"] = name
+
+	sample := remoteSample{value: evt.Value}
+	var key strings.Builder
+	for _, k := range sortedKeys(labels) {
+		sample.labels = append(sample.labels, remoteLabel{name: k, value: labels[k]})
+		key.WriteString(k + "=" + labels[k] + ",")
+	}
+
+	if isCounter(evt) {
+		s.counters[key.String()] += evt.Value
+		sample.value = s.counters[key.String()]
+	}
+
+	ts := evt.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	sample.timestamp = ts.UnixMilli()
+
+	s.batch = append(s.batch, sample)
+	return true
+}
+
+func (s *RemoteWriteSink) flush(ctx context.Context) {
+	if len(s.batch) == 0 {
+		return
+	}
+	body := snappy.Encode(nil, encodeWriteRequest(s.batch))
+	n := len(s.batch)
+	s.batch = s.batch[:0]
+	acks := s.acks
+	s.acks = nil
+
+	backoff := remoteWriteBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.send(ctx, body)
+		if err == nil {
+			ackAll(acks)
+			return
+		}
+		if !retry {
+			// Rejected for good: sending them again would not help.
+			log.Printf("prometheus_remote_write: dropping %d samples: %v", n, err)
+			ackAll(acks)
+			return
+		}
+		if attempt >= s.MaxRetries {
+			// Not acknowledged: the endpoint may take them later.
+			log.Printf("prometheus_remote_write: dropping %d samples: %v", n, err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			// Not acknowledged, so that sources able to replay them do.
+			log.Printf("prometheus_remote_write: dropping %d samples: %v", n, ctx.Err())
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send performs one POST. The returned bool tells the caller whether the
+// failure is worth retrying (network errors, 429 and 5xx).
+func (s *RemoteWriteSink) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("remote write returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// encodeWriteRequest serialises samples as a prometheus.WriteRequest:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(samples []remoteSample) []byte {
+	var out []byte
+	for _, smp := range samples {
+		var ts []byte
+		for _, l := range smp.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, lb)
+		}
+
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+		sb = protowire.AppendFixed64(sb, math.Float64bits(smp.value))
+		sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, uint64(smp.timestamp))
+
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sb)
+
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, ts)
+	}
+	return out
+}
+
+func ackAll(acks []func()) {
+	for _, ack := range acks {
+		ack()
+	}
+}
@@ -21,6 +21,7 @@ func (s *StdoutSink) Run(ctx context.Context, in <-chan event.Event) error {
 		if err := encoder.Encode(evt); err != nil {
 			return err
 		}
+		evt.Done()
 	}
 
 	return nil
@@ -5,8 +5,8 @@ import (
 	"context"
 	"io"
 	"log"
-	"time"
 
+	"collector/internal/clock"
 	"collector/internal/event"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
@@ -16,6 +16,7 @@ import (
 type DockerSource struct {
 	Service     string
 	ContainerID string
+	Clock       clock.Clock // stamps events; nil means the wall clock
 }
 
 func (ds *DockerSource) Run(ctx context.Context, out chan<- event.Event) error {
@@ -59,7 +60,7 @@ func (ds *DockerSource) Run(ctx context.Context, out chan<- event.Event) error {
 
 		e := event.Event{
 			Type: event.TypeLog,
-			Timestamp: time.Now().UTC(),
+			Timestamp: clock.Or(ds.Clock).Now().UTC(),
 			Source:    "docker",
 			Service:   ds.Service,
 
@@ -0,0 +1,159 @@
+package sources
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+// ExecSource runs a command and reads its stdout and stderr as separate
+// streams of lines, for e.g. "kubectl logs -f". Lines carry the stream in
+// Attrs["stream"] and a level hint (info for stdout, StderrLevel for
+// stderr) that a parsed level overrides.
+//
+// A long-running command is restarted with backoff when it exits; with
+// Interval set, the command is run on that schedule instead, for periodic
+// commands. Cancelling the context stops the whole process group.
+type ExecSource struct {
+	Service     string
+	Command     []string      // program and arguments
+	Env         []string      // "NAME=value" entries added to the collector's environment
+	Dir         string        // working directory; empty means the collector's
+	Interval    time.Duration // run every Interval instead of restarting on exit
+	StderrLevel string        // default "error"
+	Clock       clock.Clock   // stamps events; nil means the wall clock
+}
+
+func (s *ExecSource) Run(ctx context.Context, out chan<- event.Event) error {
+	if len(s.Command) == 0 {
+		return fmt.Errorf("exec source: no command")
+	}
+	if s.Interval > 0 {
+		return s.schedule(ctx, out)
+	}
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := s.run(ctx, out)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("exec source: %s exited (%v), restarting in %s", s.Command[0], exitStatus(err), backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// schedule runs the command every Interval, skipping runs while the
+// previous one is still going.
+func (s *ExecSource) schedule(ctx context.Context, out chan<- event.Event) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.run(ctx, out); err != nil && ctx.Err() == nil {
+			log.Printf("exec source: %s: %v", s.Command[0], exitStatus(err))
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// run runs the command once, until it exits and both streams are read.
+// The command writes straight into pipes of ours, so Wait returns when it
+// exits even if a child it left behind still holds them; killing the
+// process group then lets the streams end.
+func (s *ExecSource) run(ctx context.Context, out chan<- event.Event) error {
+	cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+	cmd.Env = append(os.Environ(), s.Env...)
+	cmd.Dir = s.Dir
+	killGroupOnCancel(cmd)
+
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer stdout.Close()
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		stdoutW.Close()
+		return err
+	}
+	defer stderr.Close()
+	cmd.Stdout, cmd.Stderr = stdoutW, stderrW
+	err = cmd.Start()
+	stdoutW.Close()
+	stderrW.Close()
+	if err != nil {
+		return err
+	}
+
+	stderrLevel := s.StderrLevel
+	if stderrLevel == "" {
+		stderrLevel = "error"
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.stream(ctx, stdout, "stdout", "info", cmd.Process.Pid, out)
+	}()
+	go func() {
+		defer wg.Done()
+		s.stream(ctx, stderr, "stderr", stderrLevel, cmd.Process.Pid, out)
+	}()
+	err = cmd.Wait()
+	killGroup(cmd)
+	wg.Wait()
+	return err
+}
+
+func (s *ExecSource) stream(ctx context.Context, r io.Reader, name, level string, pid int, out chan<- event.Event) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), defaultMaxLineLength)
+	for sc.Scan() {
+		evt := event.Event{
+			Timestamp: clock.Or(s.Clock).Now().UTC(),
+			Source:    "exec",
+			Service:   s.Service,
+			Type:      event.TypeLog,
+			Level:     level,
+			Message:   sc.Text(),
+			Attrs:     map[string]any{"stream": name, "command": s.Command[0], "pid": pid},
+		}
+		if !send(ctx, out, evt) {
+			break
+		}
+	}
+	if err := sc.Err(); err != nil && ctx.Err() == nil {
+		log.Printf("exec source: %s %s: %v", s.Command[0], name, err)
+	}
+	// Keep draining so the command does not block on a full pipe.
+	io.Copy(io.Discard, r)
+}
+
+func exitStatus(err error) string {
+	if err == nil {
+		return "status 0"
+	}
+	return err.Error()
+}
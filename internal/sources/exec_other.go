@@ -0,0 +1,16 @@
+//go:build !unix
+
+package sources
+
+import (
+	"os/exec"
+	"time"
+)
+
+// Without process groups, cancellation kills the command itself only, and
+// a child it leaves holding its output keeps the streams open.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = 5 * time.Second
+}
+
+func killGroup(cmd *exec.Cmd) {}
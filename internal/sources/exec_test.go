@@ -0,0 +1,124 @@
+//go:build unix
+
+package sources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"collector/internal/event"
+)
+
+func TestExecSource_Streams(t *testing.T) {
+	dir := t.TempDir()
+	src := &ExecSource{
+		Service:  "job",
+		Command:  []string{"sh", "-c", `echo "out $GREETING $(basename $PWD)"; echo oops >&2`},
+		Env:      []string{"GREETING=hi"},
+		Dir:      dir,
+		Interval: time.Hour,
+	}
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go src.Run(ctx, out)
+
+	got := map[string]event.Event{}
+	for i := 0; i < 2; i++ {
+		evt := receive(t, out)
+		got[evt.Attrs["stream"].(string)] = evt
+	}
+	if evt := got["stdout"]; evt.Message != "out hi "+filepath.Base(dir) || evt.Level != "info" || evt.Service != "job" || evt.Source != "exec" {
+		t.Errorf("stdout: %+v", evt)
+	}
+	if evt := got["stderr"]; evt.Message != "oops" || evt.Level != "error" {
+		t.Errorf("stderr: %+v", evt)
+	}
+}
+
+func TestExecSource_Restarts(t *testing.T) {
+	src := &ExecSource{Command: []string{"echo", "tick"}}
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go src.Run(ctx, out)
+
+	receive(t, out)
+	receive(t, out) // after the first backoff
+}
+
+func TestExecSource_CancelKillsGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	src := &ExecSource{Command: []string{"sh", "-c", "sleep 60 & echo $! > " + pidFile + "; echo started; wait"}}
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx, out) }()
+
+	receive(t, out)
+	data, _ := os.ReadFile(pidFile)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for running(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child %d still running", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExecSource_GrandchildHoldingOutput(t *testing.T) {
+	// The background sleep inherits stdout and stderr, so they stay open
+	// after the shell exits.
+	src := &ExecSource{Command: []string{"sh", "-c", "sleep 60 & echo done"}, Interval: time.Hour}
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	finished := make(chan error, 1)
+	go func() { finished <- src.run(ctx, out) }()
+	if evt := receive(t, out); evt.Message != "done" {
+		t.Errorf("got %q", evt.Message)
+	}
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("run waited for the grandchild")
+	}
+}
+
+// running reports whether pid is alive and not a zombie waiting to be
+// reaped by whichever process adopted it.
+func running(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
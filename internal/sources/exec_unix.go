@@ -0,0 +1,27 @@
+//go:build unix
+
+package sources
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killGroupOnCancel starts the command in its own process group and makes
+// context cancellation terminate the whole group, so that children of a
+// shell command stop with it. What ignores SIGTERM is killed 5s later.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		time.AfterFunc(5*time.Second, func() { syscall.Kill(pgid, syscall.SIGKILL) })
+		return syscall.Kill(pgid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 5 * time.Second
+}
+
+// killGroup kills what is left of the command's process group.
+func killGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -3,8 +3,8 @@ package sources
 import (
 	"context"
 	"log"
-	"time"
 
+	"collector/internal/clock"
 	"collector/internal/event"
 	"github.com/hpcloud/tail"
 )
@@ -12,6 +12,7 @@ import (
 type FileSource struct {
 	Service string
 	Path    string
+	Clock   clock.Clock // stamps events; nil means the wall clock
 }
 
 func (fs *FileSource) Run(ctx context.Context, out chan<- event.Event) error {
@@ -43,7 +44,7 @@ func (fs *FileSource) Run(ctx context.Context, out chan<- event.Event) error {
 			}
 
 			e := event.Event{
-				Timestamp: time.Now().UTC(),
+				Timestamp: clock.Or(fs.Clock).Now().UTC(),
 				Source:    "file",
 				Service:   fs.Service,
 				Type:      event.TypeLog,
@@ -0,0 +1,206 @@
+package sources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+// HTTPSource accepts logs POSTed as a JSON array (one event per element),
+// NDJSON or plain lines, optionally gzip-encoded. A request may name its
+// service with a "service" query parameter or an X-Service header; the
+// URL path and remote address are kept as the attributes http_path and
+// remote_addr; the path is not a url alias, so that it does not become the
+// operation of events that carry none.
+//
+// Requests beyond MaxConnections are refused with 429, and a request whose
+// events the pipeline does not take within Timeout gets 503; both carry a
+// Retry-After header.
+type HTTPSource struct {
+	Service        string
+	Address        string
+	AuthToken      string        // when set, requests need "Authorization: Bearer <token>"
+	MaxLength      int           // largest request body in bytes; default 10 MiB
+	MaxConnections int           // requests handled at once; 0 means no limit
+	Timeout        time.Duration // wait for a full pipeline; default 5s
+	TLS            *tls.Config   // optional
+	Listener       net.Listener  // used instead of listening on Address when set
+	Clock          clock.Clock   // stamps events; nil means the wall clock
+}
+
+func (s *HTTPSource) Run(ctx context.Context, out chan<- event.Event) error {
+	ln, err := listen(s.Listener, s.Address, s.TLS)
+	if err != nil {
+		return fmt.Errorf("http source: %w", err)
+	}
+	srv := &http.Server{
+		Handler:           s.handler(ctx, out),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("http source listening on %s", ln.Addr())
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("http source: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("http source shutdown: %v", err)
+	}
+	return nil
+}
+
+func (s *HTTPSource) handler(ctx context.Context, out chan<- event.Event) http.Handler {
+	maxBody := int64(s.MaxLength)
+	if maxBody <= 0 {
+		maxBody = defaultMaxBodyLength
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	var slots chan struct{}
+	if s.MaxConnections > 0 {
+		slots = make(chan struct{}, s.MaxConnections)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if s.AuthToken != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.AuthToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		lines, err := readBody(http.MaxBytesReader(w, r.Body, maxBody), r.Header, maxBody)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		service := r.URL.Query().Get("service")
+		if service == "" {
+			service = r.Header.Get("X-Service")
+		}
+		if service == "" {
+			service = s.Service
+		}
+		now := clock.Or(s.Clock).Now().UTC()
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		for i, line := range lines {
+			evt := event.Event{
+				Timestamp: now,
+				Source:    "http",
+				Service:   service,
+				Type:      event.TypeLog,
+				Message:   line,
+				Attrs:     map[string]any{"remote_addr": r.RemoteAddr, "http_path": r.URL.Path},
+			}
+			select {
+			case out <- evt:
+			case <-deadline.C:
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, fmt.Sprintf("pipeline is full; accepted %d of %d events", i, len(lines)), http.StatusServiceUnavailable)
+				return
+			case <-ctx.Done():
+				http.Error(w, "shutting down", http.StatusServiceUnavailable)
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// readBody splits a request body into raw lines: the elements of a JSON
+// array (strings as they are, anything else as its JSON), or the non-empty
+// lines of anything else. A gzip body may inflate to at most maxBody bytes.
+func readBody(body io.Reader, h http.Header, maxBody int64) ([]string, error) {
+	if h.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = io.LimitReader(zr, maxBody+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBody {
+		return nil, &http.MaxBytesError{Limit: maxBody}
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' && !strings.HasPrefix(h.Get("Content-Type"), "text/plain") {
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		lines := make([]string, 0, len(items))
+		for _, item := range items {
+			var s string
+			if json.Unmarshal(item, &s) == nil {
+				lines = append(lines, s)
+				continue
+			}
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, item); err != nil {
+				return nil, err
+			}
+			lines = append(lines, compact.String())
+		}
+		return lines, nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
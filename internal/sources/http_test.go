@@ -0,0 +1,142 @@
+package sources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"collector/internal/event"
+)
+
+func startHTTP(t *testing.T, src *HTTPSource, buf int) (string, chan event.Event) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Listener = ln
+	out := make(chan event.Event, buf)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx, out) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	return "http://" + ln.Addr().String(), out
+}
+
+func post(t *testing.T, url string, body []byte, header map[string]string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestHTTPSource_Bodies(t *testing.T) {
+	base, out := startHTTP(t, &HTTPSource{Service: "default"}, 10)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("zipped line\n"))
+	zw.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		body   []byte
+		header map[string]string
+		want   []string
+		svc    string
+	}{
+		{"array", base + "/ingest", []byte(`[{"msg": "a"}, "plain b"]`), nil, []string{`{"msg":"a"}`, "plain b"}, "default"},
+		{"ndjson", base + "/?service=api", []byte("{\"msg\":\"a\"}\n\n{\"msg\":\"b\"}\n"), nil, []string{`{"msg":"a"}`, `{"msg":"b"}`}, "api"},
+		{"text", base, []byte("[bracketed] line\r\n"), map[string]string{"Content-Type": "text/plain", "X-Service": "web"}, []string{"[bracketed] line"}, "web"},
+		{"gzip", base, gz.Bytes(), map[string]string{"Content-Encoding": "gzip"}, []string{"zipped line"}, "default"},
+	}
+	for _, tt := range tests {
+		if resp := post(t, tt.url, tt.body, tt.header); resp.StatusCode != http.StatusNoContent {
+			t.Errorf("%s: status %d", tt.name, resp.StatusCode)
+			continue
+		}
+		for _, want := range tt.want {
+			evt := receive(t, out)
+			if evt.Message != want || evt.Service != tt.svc || evt.Source != "http" {
+				t.Errorf("%s: got %+v, want %q from %s", tt.name, evt, want, tt.svc)
+			}
+		}
+	}
+
+	if resp := post(t, base, []byte("[not json"), nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid array: status %d", resp.StatusCode)
+	}
+	if resp, _ := http.Get(base); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPSource_IngestPathIsNotTheOperation(t *testing.T) {
+	base, out := startHTTP(t, &HTTPSource{Service: "api"}, 1)
+	if resp := post(t, base+"/ingest", []byte(`{"msg":"no url here"}`), nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	evt := receive(t, out)
+	if evt.Attrs["http_path"] != "/ingest" {
+		t.Errorf("attrs %v", evt.Attrs)
+	}
+	if n := event.Normalize(&evt); n.Operation != "" {
+		t.Errorf("operation %q taken from the ingest path", n.Operation)
+	}
+}
+
+func TestHTTPSource_AuthAndLimits(t *testing.T) {
+	base, out := startHTTP(t, &HTTPSource{AuthToken: "t0ken", MaxLength: 32, Timeout: 50 * time.Millisecond}, 1)
+	auth := map[string]string{"Authorization": "Bearer t0ken"}
+
+	if resp := post(t, base, []byte("x\n"), nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no token: status %d", resp.StatusCode)
+	}
+	if resp := post(t, base, []byte("x\n"), map[string]string{"Authorization": "Bearer wrong"}); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong token: status %d", resp.StatusCode)
+	}
+	if resp := post(t, base, []byte(strings.Repeat("x", 64)), auth); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: status %d", resp.StatusCode)
+	}
+	// The pipeline takes one event, then stays full.
+	resp := post(t, base, []byte("a\nb\n"), auth)
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("full pipeline: status %d", resp.StatusCode)
+	}
+	if evt := receive(t, out); evt.Message != "a" {
+		t.Errorf("got %q", evt.Message)
+	}
+}
+
+func TestHTTPSource_GzipBomb(t *testing.T) {
+	base, _ := startHTTP(t, &HTTPSource{MaxLength: 4096}, 1)
+
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(bytes.Repeat([]byte("x"), 1<<20))
+	zw.Close()
+	if bomb.Len() > 4096 {
+		t.Fatalf("compressed body of %d bytes is over the limit already", bomb.Len())
+	}
+	if resp := post(t, base, bomb.Bytes(), map[string]string{"Content-Encoding": "gzip"}); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413", resp.StatusCode)
+	}
+}
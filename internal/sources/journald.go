@@ -0,0 +1,380 @@
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+// JournaldSource reads systemd journal entries in the Journal Export
+// Format, from "journalctl -o export -f" or, when Path is set, from a file
+// of that format such as one saved with "journalctl -o export". journalctl
+// is restarted when it exits, resuming after the last entry read; a file
+// is read from the entry after the saved cursor, or from its start when
+// the cursor is not in it.
+//
+// An entry becomes an event with the service of its unit (without the
+// .service suffix), the level of its PRIORITY and the time of its
+// __REALTIME_TIMESTAMP.
+type JournaldSource struct {
+	Service string   // for entries without a unit
+	Units   []string // units to read, shell patterns allowed; empty means all
+	Path    string   // export format file read instead of running journalctl
+
+	// CursorFile keeps the cursor of the last entry the pipeline is done
+	// with: every entry up to it is acknowledged. A restarted collector
+	// resumes after it, so entries in flight when it stopped are read
+	// again rather than lost.
+	CursorFile string
+
+	Journalctl string      // default "journalctl"
+	Clock      clock.Clock // stamps entries without a timestamp; nil means the wall clock
+
+	cursor string // of the last entry read, where journalctl restarts
+	skipTo string // entries are skipped up to and including this cursor
+
+	mu          sync.Mutex // guards the fields below, which acks update
+	inFlight    []*journalEntry
+	committed   string // of the last entry acknowledged along with all before it
+	stopped     bool
+	savedCursor string
+	savedAt     time.Time
+}
+
+// maxJournalInFlight bounds the entries awaiting acknowledgement; past it
+// the oldest is given up on, so that one lost ack cannot hold the cursor.
+const maxJournalInFlight = 10000
+
+type journalEntry struct {
+	cursor string
+	done   bool
+}
+
+func (s *JournaldSource) Run(ctx context.Context, out chan<- event.Event) error {
+	if s.CursorFile != "" {
+		data, err := os.ReadFile(s.CursorFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("journald source: %w", err)
+		}
+		s.cursor = strings.TrimSpace(string(data))
+	}
+	s.mu.Lock()
+	s.committed, s.inFlight, s.stopped = s.cursor, nil, false
+	s.mu.Unlock()
+	defer func() {
+		// Entries still in flight are acknowledged later; each then
+		// saves the cursor right away.
+		s.mu.Lock()
+		s.stopped = true
+		s.saveCursor(true)
+		s.mu.Unlock()
+	}()
+
+	if s.Path != "" {
+		f, err := os.Open(s.Path)
+		if err != nil {
+			return fmt.Errorf("journald source: %w", err)
+		}
+		defer f.Close()
+		s.skipTo = s.cursor
+		err = s.read(ctx, f, out)
+		if err == io.EOF && s.skipTo != "" {
+			log.Printf("journald source: cursor %s not found in %s, reading it from the start", s.skipTo, s.Path)
+			s.skipTo = ""
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("journald source: %w", err)
+			}
+			err = s.read(ctx, f, out)
+		}
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := s.follow(ctx, out)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("journald source: journalctl exited (%v), restarting in %s", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// follow runs journalctl until it exits or ctx ends.
+func (s *JournaldSource) follow(ctx context.Context, out chan<- event.Event) error {
+	bin := s.Journalctl
+	if bin == "" {
+		bin = "journalctl"
+	}
+	args := []string{"--output=export", "--follow", "--no-pager"}
+	if s.cursor != "" {
+		args = append(args, "--after-cursor="+s.cursor)
+	} else {
+		args = append(args, "--lines=0")
+	}
+	for _, u := range s.Units {
+		args = append(args, "--unit="+u)
+	}
+
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	readErr := s.read(ctx, stdout, out)
+	if readErr != nil && readErr != io.EOF {
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("%v: %s", waitErr, msg)
+	}
+	if readErr != nil && readErr != io.EOF {
+		return readErr
+	}
+	return waitErr
+}
+
+// read sends the entries of r until it ends (io.EOF) or fails.
+func (s *JournaldSource) read(ctx context.Context, r io.Reader, out chan<- event.Event) error {
+	br := bufio.NewReader(r)
+	for {
+		fields, err := readExportEntry(br)
+		if err != nil {
+			return err
+		}
+		if s.skipTo != "" {
+			if fields["__CURSOR"] == s.skipTo {
+				s.skipTo = ""
+			}
+			continue
+		}
+		c := fields["__CURSOR"]
+		if !s.wanted(fields["_SYSTEMD_UNIT"]) {
+			s.track(c)()
+			if c != "" {
+				s.cursor = c
+			}
+			continue
+		}
+		evt := s.event(fields)
+		evt.Ack = s.track(c)
+		if !send(ctx, out, evt) {
+			return ctx.Err()
+		}
+		if c != "" {
+			s.cursor = c
+		}
+	}
+}
+
+func (s *JournaldSource) wanted(unit string) bool {
+	if len(s.Units) == 0 {
+		return true
+	}
+	for _, pattern := range s.Units {
+		if !strings.ContainsAny(pattern, ".*?[") {
+			pattern += ".service"
+		}
+		if ok, _ := path.Match(pattern, unit); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *JournaldSource) event(fields map[string]string) event.Event {
+	evt := event.Event{
+		Timestamp: clock.Or(s.Clock).Now().UTC(),
+		Source:    "journald",
+		Service:   s.Service,
+		Type:      event.TypeLog,
+		Level:     priorityLevel(fields["PRIORITY"]),
+		Message:   fields["MESSAGE"],
+		Attrs:     make(map[string]any),
+	}
+	if us, err := strconv.ParseInt(fields["__REALTIME_TIMESTAMP"], 10, 64); err == nil {
+		evt.Timestamp = time.UnixMicro(us).UTC()
+	}
+	if unit := fields["_SYSTEMD_UNIT"]; unit != "" {
+		evt.Service = strings.TrimSuffix(unit, ".service")
+		evt.Attrs["unit"] = unit
+	}
+	for field, attr := range map[string]string{
+		"_HOSTNAME":         "hostname",
+		"_PID":              "pid",
+		"SYSLOG_IDENTIFIER": "syslog_identifier",
+		"PRIORITY":          "priority",
+	} {
+		if v, ok := fields[field]; ok {
+			evt.Attrs[attr] = v
+		}
+	}
+	return evt
+}
+
+// priorityLevel maps a syslog priority (0 emerg to 7 debug) to a level.
+func priorityLevel(p string) string {
+	switch p {
+	case "0", "1", "2":
+		return "fatal"
+	case "3":
+		return "error"
+	case "4":
+		return "warn"
+	case "5", "6":
+		return "info"
+	case "7":
+		return "debug"
+	}
+	return ""
+}
+
+// track queues an entry read with cursor and returns its Ack, which moves
+// the committed cursor over every acknowledged entry at the head of the
+// queue.
+func (s *JournaldSource) track(cursor string) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := &journalEntry{cursor: cursor}
+	s.inFlight = append(s.inFlight, e)
+	if len(s.inFlight) > maxJournalInFlight {
+		log.Printf("journald source: entry %s still unacknowledged after %d later ones, moving the cursor past it",
+			s.inFlight[0].cursor, maxJournalInFlight)
+		s.inFlight[0].done = true
+		s.advance()
+	}
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		e.done = true
+		s.advance()
+	}
+}
+
+// advance commits the acknowledged entries at the head of the queue. The
+// caller holds s.mu.
+func (s *JournaldSource) advance() {
+	moved := false
+	for len(s.inFlight) > 0 && s.inFlight[0].done {
+		if c := s.inFlight[0].cursor; c != "" {
+			s.committed = c
+			moved = true
+		}
+		s.inFlight = s.inFlight[1:]
+	}
+	if moved {
+		s.saveCursor(s.stopped)
+	}
+}
+
+// saveCursor writes the committed cursor at most once a second, or now
+// when force is set. The caller holds s.mu.
+func (s *JournaldSource) saveCursor(force bool) {
+	if s.CursorFile == "" || s.committed == "" || s.committed == s.savedCursor {
+		return
+	}
+	now := clock.Or(s.Clock).Now()
+	if !force && now.Sub(s.savedAt) < time.Second {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.CursorFile), 0o755); err != nil {
+		log.Printf("journald source: save cursor: %v", err)
+		return
+	}
+	tmp := s.CursorFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(s.committed+"\n"), 0o644); err != nil {
+		log.Printf("journald source: save cursor: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.CursorFile); err != nil {
+		log.Printf("journald source: save cursor: %v", err)
+		return
+	}
+	s.savedAt, s.savedCursor = now, s.committed
+}
+
+// readExportEntry reads one entry of the Journal Export Format: fields as
+// "NAME=value" lines, or for binary values "NAME", a little-endian 64-bit
+// length and the data, the entry ending with an empty line. It returns
+// io.EOF at the end of the stream.
+func readExportEntry(br *bufio.Reader) (map[string]string, error) {
+	fields := make(map[string]string)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && len(fields) > 0 {
+				return fields, nil
+			}
+			if err == io.EOF && line != "" {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(fields) == 0 {
+				continue
+			}
+			return fields, nil
+		}
+		if name, value, ok := strings.Cut(line, "="); ok {
+			fields[name] = value
+			continue
+		}
+
+		var size [8]byte
+		if _, err := io.ReadFull(br, size[:]); err != nil {
+			return nil, unexpected(err)
+		}
+		n := binary.LittleEndian.Uint64(size[:])
+		if n > defaultMaxBodyLength {
+			return nil, fmt.Errorf("journal field %s of %d bytes", line, n)
+		}
+		data := make([]byte, n+1)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, unexpected(err)
+		}
+		fields[line] = string(data[:n])
+	}
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
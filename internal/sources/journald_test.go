@@ -0,0 +1,175 @@
+package sources
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"collector/internal/event"
+)
+
+// journalExport builds entries in the Journal Export Format, MESSAGE of
+// the second one as a binary field as journald writes multi-line messages.
+func journalExport() string {
+	multiline := "MESSAGE\n" + string(binary.LittleEndian.AppendUint64(nil, 11)) + "panic:\nboom\n"
+	return "__CURSOR=s=1\n__REALTIME_TIMESTAMP=1714557600000000\n_SYSTEMD_UNIT=api.service\n_HOSTNAME=vm1\nPRIORITY=6\nMESSAGE=started\n\n" +
+		"__CURSOR=s=2\n__REALTIME_TIMESTAMP=1714557601000000\n_SYSTEMD_UNIT=cron.service\nPRIORITY=5\nMESSAGE=tick\n\n" +
+		"__CURSOR=s=3\n__REALTIME_TIMESTAMP=1714557602000000\n_SYSTEMD_UNIT=api.service\nPRIORITY=3\n" + multiline + "\n"
+}
+
+// runJournald returns the events src sends, acknowledged as the sinks
+// would once Run returns.
+func runJournald(t *testing.T, src *JournaldSource) []event.Event {
+	t.Helper()
+	events := runJournaldUnacked(t, src)
+	for _, evt := range events {
+		evt.Done()
+	}
+	return events
+}
+
+func runJournaldUnacked(t *testing.T, src *JournaldSource) []event.Event {
+	t.Helper()
+	out := make(chan event.Event, 10)
+	if err := src.Run(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+	var events []event.Event
+	for evt := range out {
+		events = append(events, evt)
+	}
+	return events
+}
+
+func TestJournaldSource_File(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.export")
+	os.WriteFile(path, []byte(journalExport()), 0o644)
+	cursorFile := filepath.Join(dir, "state", "cursor")
+
+	events := runJournald(t, &JournaldSource{Path: path, Units: []string{"api"}, CursorFile: cursorFile})
+	if len(events) != 2 {
+		t.Fatalf("got %d events: %+v", len(events), events)
+	}
+	first, second := events[0], events[1]
+	if first.Service != "api" || first.Message != "started" || first.Level != "info" || first.Source != "journald" ||
+		!first.Timestamp.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) || first.Attrs["hostname"] != "vm1" {
+		t.Errorf("first: %+v", first)
+	}
+	if second.Message != "panic:\nboom" || second.Level != "error" || second.Attrs["unit"] != "api.service" {
+		t.Errorf("second: %+v", second)
+	}
+	if data, _ := os.ReadFile(cursorFile); strings.TrimSpace(string(data)) != "s=3" {
+		t.Errorf("cursor %q", data)
+	}
+}
+
+func TestJournaldSource_FileResumesAfterCursor(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.export")
+	os.WriteFile(path, []byte(journalExport()), 0o644)
+	cursorFile := filepath.Join(dir, "cursor")
+
+	if events := runJournald(t, &JournaldSource{Path: path, CursorFile: cursorFile}); len(events) != 3 {
+		t.Fatalf("first run: %d events", len(events))
+	}
+	if events := runJournald(t, &JournaldSource{Path: path, CursorFile: cursorFile}); len(events) != 0 {
+		t.Errorf("second run resent %d events", len(events))
+	}
+
+	more := "__CURSOR=s=4\n_SYSTEMD_UNIT=api.service\nMESSAGE=again\n\n"
+	os.WriteFile(path, []byte(journalExport()+more), 0o644)
+	events := runJournald(t, &JournaldSource{Path: path, CursorFile: cursorFile})
+	if len(events) != 1 || events[0].Message != "again" {
+		t.Errorf("appended entry: %+v", events)
+	}
+
+	// A cursor from another journal does not hide the whole file.
+	os.WriteFile(cursorFile, []byte("s=99\n"), 0o644)
+	if events := runJournald(t, &JournaldSource{Path: path, CursorFile: cursorFile}); len(events) != 4 {
+		t.Errorf("unknown cursor: %d events", len(events))
+	}
+}
+
+func TestJournaldSource_UnackedEntryIsReadAgain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.export")
+	os.WriteFile(path, []byte(journalExport()), 0o644)
+	cursorFile := filepath.Join(dir, "cursor")
+
+	// Only the first entry is done with when the collector stops; the
+	// third, read after the filtered cron one, is still in flight.
+	events := runJournaldUnacked(t, &JournaldSource{Path: path, Units: []string{"api"}, CursorFile: cursorFile})
+	if len(events) != 2 {
+		t.Fatalf("first run: %d events", len(events))
+	}
+	events[0].Done()
+	if data, _ := os.ReadFile(cursorFile); strings.TrimSpace(string(data)) != "s=2" {
+		t.Errorf("cursor %q, want s=2", data)
+	}
+
+	events = runJournald(t, &JournaldSource{Path: path, Units: []string{"api"}, CursorFile: cursorFile})
+	if len(events) != 1 || events[0].Message != "panic:\nboom" {
+		t.Fatalf("second run: %+v", events)
+	}
+	if data, _ := os.ReadFile(cursorFile); strings.TrimSpace(string(data)) != "s=3" {
+		t.Errorf("cursor %q", data)
+	}
+}
+
+func TestJournaldSource_FilteredEntryWithoutCursor(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.export")
+	// The cron entry has no __CURSOR and must not clear the api one.
+	os.WriteFile(path, []byte("__CURSOR=s=1\n_SYSTEMD_UNIT=api.service\nMESSAGE=a\n\n_SYSTEMD_UNIT=cron.service\nMESSAGE=b\n\n"), 0o644)
+
+	src := &JournaldSource{Path: path, Units: []string{"api"}}
+	runJournald(t, src)
+	if src.cursor != "s=1" {
+		t.Errorf("cursor %q, journalctl would restart at the end", src.cursor)
+	}
+}
+
+func TestJournaldSource_Journalctl(t *testing.T) {
+	dir := t.TempDir()
+	export := filepath.Join(dir, "journal.export")
+	os.WriteFile(export, []byte(journalExport()), 0o644)
+	cursorFile := filepath.Join(dir, "cursor")
+	os.WriteFile(cursorFile, []byte("s=0\n"), 0o644)
+
+	// A stand-in journalctl recording its arguments.
+	argsFile := filepath.Join(dir, "args")
+	script := filepath.Join(dir, "journalctl")
+	os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > "+argsFile+"\ncat "+export+"\nexec sleep 60\n"), 0o755)
+
+	src := &JournaldSource{Units: []string{"api", "cron"}, CursorFile: cursorFile, Journalctl: script}
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx, out) }()
+
+	for _, want := range []string{"started", "tick", "panic:\nboom"} {
+		evt := receive(t, out)
+		if evt.Message != want {
+			t.Errorf("got %q, want %q", evt.Message, want)
+		}
+		evt.Done()
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	if want := "--output=export --follow --no-pager --after-cursor=s=0 --unit=api --unit=cron"; strings.TrimSpace(string(args)) != want {
+		t.Errorf("args %q, want %q", args, want)
+	}
+	if data, _ := os.ReadFile(cursorFile); strings.TrimSpace(string(data)) != "s=3" {
+		t.Errorf("cursor %q", data)
+	}
+}
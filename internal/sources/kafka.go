@@ -0,0 +1,255 @@
+package sources
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+const defaultKafkaMaxPending = 10000
+
+// Decodings of kafka record values.
+const (
+	DecodingRaw  = "raw"  // the value is a log line
+	DecodingJSON = "json" // the value is an event as the kafka sink writes it
+)
+
+// KafkaSource consumes topics as a member of a consumer group. Every event
+// carries an Ack, and a partition's offset is committed only up to the
+// oldest record the pipeline is not done with, so records in flight when
+// the collector stops are read again (at-least-once). A record still
+// unacknowledged once MaxPending later records of its partition are in
+// flight is given up on, logged and committed past, so that one lost ack
+// cannot stall a partition's commits or grow its backlog without bound.
+// Without a Group the topics are read from StartOffset on every start and
+// nothing is committed.
+type KafkaSource struct {
+	Service     string
+	Brokers     []string
+	Topics      []string
+	Group       string
+	Decoding    string        // DecodingRaw (default) or DecodingJSON
+	StartOffset string        // "earliest" or "latest" (default), for partitions without a committed offset
+	CommitEvery time.Duration // default 5s
+	MaxPending  int           // in-flight records per partition; default 10000
+	Clock       clock.Clock   // stamps records without a timestamp; nil means the wall clock
+
+	// Opts are added to the client options, e.g. for TLS or SASL.
+	Opts []kgo.Opt
+}
+
+func (s *KafkaSource) Run(ctx context.Context, out chan<- event.Event) error {
+	switch s.Decoding {
+	case "", DecodingRaw, DecodingJSON:
+	default:
+		return fmt.Errorf("kafka source: unknown decoding %q", s.Decoding)
+	}
+	start := kgo.NewOffset().AtEnd()
+	switch s.StartOffset {
+	case "", "latest":
+	case "earliest":
+		start = kgo.NewOffset().AtStart()
+	default:
+		return fmt.Errorf("kafka source: unknown start offset %q", s.StartOffset)
+	}
+
+	tracker := &offsetTracker{
+		pending: make(map[topicPartition]*partitionOffsets),
+		limit:   cmp.Or(s.MaxPending, defaultKafkaMaxPending),
+	}
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(s.Brokers...),
+		kgo.ConsumeTopics(s.Topics...),
+		kgo.ConsumeResetOffset(start),
+	}
+	if s.Group != "" {
+		every := s.CommitEvery
+		if every <= 0 {
+			every = 5 * time.Second
+		}
+		opts = append(opts,
+			kgo.ConsumerGroup(s.Group),
+			kgo.AutoCommitMarks(),
+			kgo.AutoCommitInterval(every),
+			kgo.OnPartitionsRevoked(func(ctx context.Context, cl *kgo.Client, revoked map[string][]int32) {
+				if err := cl.CommitMarkedOffsets(ctx); err != nil {
+					log.Printf("kafka source: commit on revoke: %v", err)
+				}
+				tracker.drop(revoked)
+			}),
+			kgo.OnPartitionsLost(func(_ context.Context, _ *kgo.Client, lost map[string][]int32) {
+				tracker.drop(lost)
+			}),
+		)
+	}
+	cl, err := kgo.NewClient(append(opts, s.Opts...)...)
+	if err != nil {
+		return fmt.Errorf("kafka source: %w", err)
+	}
+	tracker.mark = cl.MarkCommitRecords
+	defer func() {
+		if s.Group != "" {
+			commitCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := cl.CommitMarkedOffsets(commitCtx); err != nil {
+				log.Printf("kafka source: final commit: %v", err)
+			}
+			cancel()
+		}
+		cl.Close()
+	}()
+
+	log.Printf("kafka source consuming %v from %v", s.Topics, s.Brokers)
+	for {
+		fetches := cl.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return nil
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			if !errors.Is(err, context.Canceled) {
+				log.Printf("kafka source: %s[%d]: %v", topic, partition, err)
+			}
+		})
+
+		var records []*kgo.Record
+		fetches.EachRecord(func(r *kgo.Record) { records = append(records, r) })
+		for _, r := range records {
+			evt := s.event(r)
+			if s.Group != "" {
+				evt.Ack = tracker.add(r)
+			}
+			if !send(ctx, out, evt) {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *KafkaSource) event(r *kgo.Record) event.Event {
+	var evt event.Event
+	if s.Decoding == DecodingJSON {
+		if err := json.Unmarshal(r.Value, &evt); err != nil {
+			evt = event.Event{}
+			log.Printf("kafka source: %s[%d]@%d: not an event, kept as a raw line: %v", r.Topic, r.Partition, r.Offset, err)
+		}
+	}
+	if evt.Message == "" && evt.Metric == "" {
+		evt.Message = string(r.Value)
+	}
+	if evt.Timestamp.IsZero() {
+		evt.Timestamp = r.Timestamp.UTC()
+		if r.Timestamp.IsZero() {
+			evt.Timestamp = clock.Or(s.Clock).Now().UTC()
+		}
+	}
+	if evt.Source == "" {
+		evt.Source = "kafka"
+	}
+	if evt.Service == "" {
+		evt.Service = s.Service
+	}
+	if evt.Type == "" {
+		evt.Type = event.TypeLog
+	}
+	if evt.Attrs == nil {
+		evt.Attrs = make(map[string]any, 4)
+	}
+	evt.Attrs["kafka_topic"] = r.Topic
+	evt.Attrs["kafka_partition"] = r.Partition
+	evt.Attrs["kafka_offset"] = r.Offset
+	if len(r.Key) > 0 {
+		evt.Attrs["kafka_key"] = string(r.Key)
+	}
+	return evt
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// offsetTracker marks a record for commit once it and every record read
+// before it from its partition are acknowledged.
+type offsetTracker struct {
+	mu      sync.Mutex
+	pending map[topicPartition]*partitionOffsets
+	mark    func(...*kgo.Record)
+	limit   int // records in flight per partition; 0 means no limit
+}
+
+type partitionOffsets struct {
+	records []*kgo.Record // in flight, in offset order
+	acked   map[int64]bool
+}
+
+// add tracks r and returns its Ack.
+func (t *offsetTracker) add(r *kgo.Record) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tp := topicPartition{r.Topic, r.Partition}
+	p, ok := t.pending[tp]
+	if !ok {
+		p = &partitionOffsets{acked: make(map[int64]bool)}
+		t.pending[tp] = p
+	}
+	p.records = append(p.records, r)
+	metrics.KafkaPendingRecords.WithLabelValues(r.Topic).Inc()
+	if t.limit > 0 && len(p.records) > t.limit {
+		head := p.records[0]
+		log.Printf("kafka source: %s[%d]@%d still unacknowledged after %d later records, committing past it",
+			head.Topic, head.Partition, head.Offset, t.limit)
+		metrics.KafkaRecordsAbandoned.WithLabelValues(head.Topic).Inc()
+		p.acked[head.Offset] = true
+		t.advance(p)
+	}
+	return func() { t.ack(tp, p, r.Offset) }
+}
+
+func (t *offsetTracker) ack(tp topicPartition, p *partitionOffsets, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending[tp] != p || len(p.records) == 0 || offset < p.records[0].Offset {
+		return // revoked since, or acknowledged already
+	}
+	p.acked[offset] = true
+	t.advance(p)
+}
+
+// advance marks the last of the acknowledged records at the head of p.
+func (t *offsetTracker) advance(p *partitionOffsets) {
+	var last *kgo.Record
+	for len(p.records) > 0 && p.acked[p.records[0].Offset] {
+		last = p.records[0]
+		delete(p.acked, last.Offset)
+		p.records = p.records[1:]
+		metrics.KafkaPendingRecords.WithLabelValues(last.Topic).Dec()
+	}
+	if last != nil && t.mark != nil {
+		t.mark(last)
+	}
+}
+
+// drop forgets partitions no longer assigned; their late acks are ignored.
+func (t *offsetTracker) drop(partitions map[string][]int32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for topic, ps := range partitions {
+		for _, p := range ps {
+			tp := topicPartition{topic, p}
+			if po, ok := t.pending[tp]; ok {
+				metrics.KafkaPendingRecords.WithLabelValues(topic).Sub(float64(len(po.records)))
+			}
+			delete(t.pending, tp)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package sources
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"collector/internal/event"
+)
+
+func startKafka(t *testing.T, values ...string) []string {
+	t.Helper()
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "logs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+
+	cl, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.DefaultProduceTopic("logs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	for _, v := range values {
+		if err := cl.ProduceSync(context.Background(), &kgo.Record{Key: []byte("k"), Value: []byte(v)}).FirstErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cluster.ListenAddrs()
+}
+
+// runKafka runs src until the returned function is called.
+func runKafka(t *testing.T, src *KafkaSource) (<-chan event.Event, func()) {
+	t.Helper()
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx, out) }()
+	return out, func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}
+}
+
+func TestKafkaSource_CommitsAckedOffsets(t *testing.T) {
+	brokers := startKafka(t, "one", "two", "three")
+	src := &KafkaSource{Service: "bus", Brokers: brokers, Topics: []string{"logs"}, Group: "collector", StartOffset: "earliest"}
+
+	out, stop := runKafka(t, src)
+	var got []event.Event
+	for range 3 {
+		got = append(got, receive(t, out))
+	}
+	for i, want := range []string{"one", "two", "three"} {
+		evt := got[i]
+		if evt.Message != want || evt.Source != "kafka" || evt.Service != "bus" {
+			t.Errorf("event %d: got %+v, want message %q", i, evt, want)
+		}
+		if evt.Attrs["kafka_topic"] != "logs" || evt.Attrs["kafka_offset"] != int64(i) || evt.Attrs["kafka_key"] != "k" {
+			t.Errorf("event %d: attrs %v", i, evt.Attrs)
+		}
+	}
+	// "two" is never acknowledged, so only "one" may be committed.
+	got[2].Done()
+	got[0].Done()
+	stop()
+
+	src = &KafkaSource{Service: "bus", Brokers: brokers, Topics: []string{"logs"}, Group: "collector", StartOffset: "earliest"}
+	out, stop = runKafka(t, src)
+	defer stop()
+	if evt := receive(t, out); evt.Message != "two" {
+		t.Errorf("resumed at %q, want two", evt.Message)
+	}
+}
+
+func TestKafkaSource_JSON(t *testing.T) {
+	brokers := startKafka(t,
+		`{"ts":"2024-05-01T10:00:00Z","service":"api","level":"warn","message":"slow","type":"log"}`,
+		"not json",
+	)
+	src := &KafkaSource{Service: "bus", Brokers: brokers, Topics: []string{"logs"}, Decoding: DecodingJSON, StartOffset: "earliest"}
+	out, stop := runKafka(t, src)
+	defer stop()
+
+	evt := receive(t, out)
+	if evt.Service != "api" || evt.Level != "warn" || evt.Message != "slow" || !evt.Timestamp.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %+v", evt)
+	}
+	if evt.Ack != nil {
+		t.Error("event without a group has an Ack")
+	}
+	if evt := receive(t, out); evt.Message != "not json" || evt.Service != "bus" {
+		t.Errorf("got %+v", evt)
+	}
+}
+
+func TestOffsetTracker_AbandonsStuckRecord(t *testing.T) {
+	var marked []int64
+	tr := &offsetTracker{
+		pending: make(map[topicPartition]*partitionOffsets),
+		mark: func(rs ...*kgo.Record) {
+			for _, r := range rs {
+				marked = append(marked, r.Offset)
+			}
+		},
+		limit: 2,
+	}
+	rec := func(offset int64) *kgo.Record { return &kgo.Record{Topic: "logs", Offset: offset} }
+
+	tr.add(rec(0)) // never acknowledged
+	ack1 := tr.add(rec(1))
+	ack1()
+	if len(marked) != 0 {
+		t.Fatalf("marked %v past an unacknowledged record", marked)
+	}
+	ack2 := tr.add(rec(2)) // the third in flight gives up on offset 0
+	if want := []int64{1}; !slices.Equal(marked, want) {
+		t.Errorf("marked %v, want %v", marked, want)
+	}
+	ack2()
+	if want := []int64{1, 2}; !slices.Equal(marked, want) {
+		t.Errorf("marked %v, want %v", marked, want)
+	}
+	if n := len(tr.pending[topicPartition{"logs", 0}].records); n != 0 {
+		t.Errorf("%d records still pending", n)
+	}
+}
@@ -0,0 +1,89 @@
+package sources
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"collector/internal/event"
+)
+
+// Framings of the tcp source.
+const (
+	FramingNewline        = "newline"
+	FramingLengthPrefixed = "length_prefixed"
+)
+
+const (
+	defaultMaxLineLength = 1 << 20
+	defaultMaxBodyLength = 10 << 20
+)
+
+// listen returns ln if set, or a listener on addr, wrapped for TLS when
+// cfg is set.
+func listen(ln net.Listener, addr string, cfg *tls.Config) (net.Listener, error) {
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+	if cfg != nil {
+		ln = tls.NewListener(ln, cfg)
+	}
+	return ln, nil
+}
+
+// frameReader returns the next line of a stream, or io.EOF at its end.
+type frameReader func() (string, error)
+
+func newlineFrames(r io.Reader, max int) frameReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, min(4096, max)), max)
+	return func() (string, error) {
+		if sc.Scan() {
+			return strings.TrimSuffix(sc.Text(), "\r"), nil
+		}
+		if err := sc.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+}
+
+// lengthPrefixedFrames reads frames preceded by their length as a 4-byte
+// big-endian integer.
+func lengthPrefixedFrames(r io.Reader, max int) frameReader {
+	br := bufio.NewReader(r)
+	var header [4]byte
+	return func() (string, error) {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			return "", err
+		}
+		n := binary.BigEndian.Uint32(header[:])
+		if int64(n) > int64(max) {
+			return "", fmt.Errorf("frame of %d bytes exceeds max_length %d", n, max)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+}
+
+// send delivers evt, blocking while the pipeline is full, unless ctx ends
+// first.
+func send(ctx context.Context, out chan<- event.Event, evt event.Event) bool {
+	select {
+	case out <- evt:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"bufio"
 	"context"
 	"os"
-	"time"
 
+	"collector/internal/clock"
 	"collector/internal/event"
 )
 
 type StdinSource struct {
 	Service string
+	Clock   clock.Clock // stamps events; nil means the wall clock
 }
 
 func (s *StdinSource) Run(ctx context.Context, out chan<- event.Event) error {
@@ -30,7 +31,7 @@ func (s *StdinSource) Run(ctx context.Context, out chan<- event.Event) error {
 		line := reader.Text()
 
 		evt := event.Event{
-			Timestamp: time.Now().UTC(),
+			Timestamp: clock.Or(s.Clock).Now().UTC(),
 			Source:    "stdin",
 			Service:   s.Service,
 			Type:      event.TypeLog,
@@ -4,6 +4,9 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
+
+	"collector/internal/clock"
 	"collector/internal/event"
 )
 
@@ -27,4 +30,25 @@ func TestStdinSource(t *testing.T) {
 	if evt.Message != "hello from stdin" {
 		t.Errorf("got %s", evt.Message)
 	}
-}
\ No newline at end of file
+}
+func TestStdinSource_Clock(t *testing.T) {
+	r, w, _ := os.Pipe()
+	oldStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
+
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	src := &StdinSource{Clock: clock.NewFake(at)}
+	out := make(chan event.Event, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go src.Run(ctx, out)
+
+	w.WriteString("hello\n")
+
+	if evt := <-out; !evt.Timestamp.Equal(at) {
+		t.Errorf("timestamp %s, want the clock's %s", evt.Timestamp, at)
+	}
+}
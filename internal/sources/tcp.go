@@ -0,0 +1,119 @@
+package sources
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+// TCPSource accepts log lines over TCP connections. A full pipeline stops
+// reads, so backpressure reaches senders through TCP flow control.
+type TCPSource struct {
+	Service        string
+	Address        string
+	Framing        string       // FramingNewline (default) or FramingLengthPrefixed
+	MaxLength      int          // longest line or frame in bytes; default 1 MiB
+	MaxConnections int          // connections beyond it are closed; 0 means no limit
+	TLS            *tls.Config  // optional
+	Listener       net.Listener // used instead of listening on Address when set; Run closes it
+	Clock          clock.Clock  // stamps events; nil means the wall clock
+}
+
+func (s *TCPSource) Run(ctx context.Context, out chan<- event.Event) error {
+	switch s.Framing {
+	case "", FramingNewline, FramingLengthPrefixed:
+	default:
+		return fmt.Errorf("tcp source: unknown framing %q", s.Framing)
+	}
+	ln, err := listen(s.Listener, s.Address, s.TLS)
+	if err != nil {
+		return fmt.Errorf("tcp source: %w", err)
+	}
+	defer ln.Close()
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+
+	log.Printf("tcp source listening on %s", ln.Addr())
+
+	var slots chan struct{}
+	if s.MaxConnections > 0 {
+		slots = make(chan struct{}, s.MaxConnections)
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return fmt.Errorf("tcp source: accept: %w", err)
+		}
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			default:
+				log.Printf("tcp source: %d connections open, closing %s", s.MaxConnections, conn.RemoteAddr())
+				conn.Close()
+				continue
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+			s.serve(ctx, conn, out)
+		}()
+	}
+}
+
+func (s *TCPSource) serve(ctx context.Context, conn net.Conn, out chan<- event.Event) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	max := s.MaxLength
+	if max <= 0 {
+		max = defaultMaxLineLength
+	}
+	next := newlineFrames(conn, max)
+	if s.Framing == FramingLengthPrefixed {
+		next = lengthPrefixedFrames(conn, max)
+	}
+
+	remote := conn.RemoteAddr().String()
+	for {
+		line, err := next()
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				log.Printf("tcp source: %s: %v", remote, err)
+			}
+			return
+		}
+		evt := event.Event{
+			Timestamp: clock.Or(s.Clock).Now().UTC(),
+			Source:    "tcp",
+			Service:   s.Service,
+			Type:      event.TypeLog,
+			Message:   line,
+			Attrs:     map[string]any{"remote_addr": remote},
+		}
+		if !send(ctx, out, evt) {
+			return
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package sources
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"collector/internal/event"
+)
+
+func startTCP(t *testing.T, src *TCPSource) (string, chan event.Event) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Listener = ln
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx, out) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	return ln.Addr().String(), out
+}
+
+func receive(t *testing.T, out <-chan event.Event) event.Event {
+	t.Helper()
+	select {
+	case evt := <-out:
+		return evt
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event")
+		return event.Event{}
+	}
+}
+
+func TestTCPSource_Newline(t *testing.T) {
+	addr, out := startTCP(t, &TCPSource{Service: "api"})
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("first\r\nsecond\n"))
+
+	for _, want := range []string{"first", "second"} {
+		evt := receive(t, out)
+		if evt.Message != want || evt.Source != "tcp" || evt.Service != "api" || evt.Attrs["remote_addr"] != conn.LocalAddr().String() {
+			t.Errorf("got %+v, want message %q", evt, want)
+		}
+	}
+}
+
+func TestTCPSource_LengthPrefixed(t *testing.T) {
+	addr, out := startTCP(t, &TCPSource{Framing: FramingLengthPrefixed, MaxLength: 16})
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	frame := func(s string) []byte {
+		return append(binary.BigEndian.AppendUint32(nil, uint32(len(s))), s...)
+	}
+	conn.Write(append(frame("multi\nline"), frame("next")...))
+
+	for _, want := range []string{"multi\nline", "next"} {
+		if evt := receive(t, out); evt.Message != want {
+			t.Errorf("got %q, want %q", evt.Message, want)
+		}
+	}
+
+	// An oversized frame closes the connection.
+	conn.Write(frame("far too long for the limit"))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("connection still open")
+	}
+}
+
+func TestTCPSource_MaxConnections(t *testing.T) {
+	addr, out := startTCP(t, &TCPSource{MaxConnections: 1})
+	first, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	first.Write([]byte("hello\n"))
+	receive(t, out)
+
+	second, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := second.Read(make([]byte, 1)); err == nil {
+		t.Error("second connection accepted")
+	}
+}
+
+// failingListener fails every Accept, as a listener broken by the OS would.
+type failingListener struct {
+	net.Listener
+	closed chan struct{}
+}
+
+func (l *failingListener) Accept() (net.Conn, error) { return nil, errors.New("too many open files") }
+
+func (l *failingListener) Close() error {
+	select {
+	case <-l.closed:
+	default:
+		close(l.closed)
+	}
+	return l.Listener.Close()
+}
+
+func TestTCPSource_ClosesListenerOnError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fl := &failingListener{Listener: ln, closed: make(chan struct{})}
+	if err := (&TCPSource{Listener: fl}).Run(context.Background(), nil); err == nil {
+		t.Fatal("want the accept error")
+	}
+	select {
+	case <-fl.closed:
+	default:
+		t.Error("listener left open")
+	}
+}
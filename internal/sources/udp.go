@@ -0,0 +1,71 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+// UDPSource reads log lines from datagrams, one per datagram or several
+// separated by newlines. Reads stop while the pipeline is full, so the
+// kernel drops what no longer fits the socket buffer.
+type UDPSource struct {
+	Service   string
+	Address   string
+	MaxLength int            // largest datagram in bytes; default 64 KiB
+	Conn      net.PacketConn // used instead of listening on Address when set; Run closes it
+	Clock     clock.Clock    // stamps events; nil means the wall clock
+}
+
+func (s *UDPSource) Run(ctx context.Context, out chan<- event.Event) error {
+	conn := s.Conn
+	if conn == nil {
+		var err error
+		if conn, err = net.ListenPacket("udp", s.Address); err != nil {
+			return fmt.Errorf("udp source: %w", err)
+		}
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	log.Printf("udp source listening on %s", conn.LocalAddr())
+
+	max := s.MaxLength
+	if max <= 0 {
+		max = 64 << 10
+	}
+	buf := make([]byte, max)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("udp source: %w", err)
+		}
+		remote := addr.String()
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if line == "" {
+				continue
+			}
+			evt := event.Event{
+				Timestamp: clock.Or(s.Clock).Now().UTC(),
+				Source:    "udp",
+				Service:   s.Service,
+				Type:      event.TypeLog,
+				Message:   line,
+				Attrs:     map[string]any{"remote_addr": remote},
+			}
+			if !send(ctx, out, evt) {
+				return nil
+			}
+		}
+	}
+}
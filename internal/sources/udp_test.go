@@ -0,0 +1,71 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"collector/internal/event"
+)
+
+func TestUDPSource(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &UDPSource{Service: "dns", Conn: conn}
+	out := make(chan event.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx, out) }()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Write([]byte("one datagram"))
+	client.Write([]byte("two\nlines\n"))
+
+	for _, want := range []string{"one datagram", "two", "lines"} {
+		evt := receive(t, out)
+		if evt.Message != want || evt.Source != "udp" || evt.Service != "dns" {
+			t.Errorf("got %+v, want message %q", evt, want)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+}
+
+// failingConn fails every read, as a socket broken by the OS would.
+type failingConn struct {
+	net.PacketConn
+	closed bool
+}
+
+func (c *failingConn) ReadFrom([]byte) (int, net.Addr, error) {
+	return 0, nil, errors.New("connection refused")
+}
+
+func (c *failingConn) Close() error {
+	c.closed = true
+	return c.PacketConn.Close()
+}
+
+func TestUDPSource_ClosesConnOnError(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := &failingConn{PacketConn: conn}
+	if err := (&UDPSource{Conn: fc}).Run(context.Background(), nil); err == nil {
+		t.Fatal("want the read error")
+	}
+	if !fc.closed {
+		t.Error("conn left open")
+	}
+}
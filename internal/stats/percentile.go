@@ -0,0 +1,28 @@
+// Package stats holds small numeric helpers shared by the graph and the
+// windowed transforms.
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// Percentile returns the q-th quantile (0..1) of vals using the
+// nearest-rank-below method. vals is not modified.
+func Percentile(vals []time.Duration, q float64) time.Duration {
+	if len(vals) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(vals))
+	copy(sorted, vals)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	if q < 0 {
+		q = 0
+	} else if q > 1 {
+		q = 1
+	}
+	idx := int(float64(len(sorted)-1) * q)
+	return sorted[idx]
+}
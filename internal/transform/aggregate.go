@@ -0,0 +1,319 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+	"collector/internal/metrics"
+	"collector/internal/stats"
+)
+
+// Group-by keys understood by AggregateTransform.
+const (
+	GroupBySrc         = "src"
+	GroupByDst         = "dst"
+	GroupByOperation   = "operation"
+	GroupByStatusClass = "status_class"
+)
+
+const maxLatencySamples = 1024
+
+// AggregateTransform rolls log events up into one summary event per group
+// and window. Windows are driven by event time: a window is emitted once the
+// watermark (latest event time minus AllowedLateness) passes its end, and
+// events arriving for an already-emitted window are dropped as late.
+//
+// Tumbling windows are used when Slide is zero or equal to Window; otherwise
+// each event is counted in every sliding window that covers it. Metric
+// events pass through unchanged.
+//
+// Events without a timestamp are stamped from Clock, which also measures
+// how long the stream has been idle.
+type AggregateTransform struct {
+	GroupBy         []string
+	Window          time.Duration
+	Slide           time.Duration
+	AllowedLateness time.Duration
+	Clock           clock.Clock // nil means the wall clock
+
+	buckets  map[windowKey]*windowAgg
+	maxSeen  time.Time
+	lastSeen time.Time // Clock time of the latest event
+	closed   time.Time // every window ending at or before this was emitted
+	rng      *rand.Rand
+}
+
+type windowKey struct {
+	start time.Time
+	group string
+}
+
+type windowAgg struct {
+	start, end  time.Time
+	fields      map[string]string
+	count       int64
+	errors      int64
+	latencySum  time.Duration
+	latencyMin  time.Duration
+	latencyMax  time.Duration
+	latencies   []time.Duration
+	latencySeen int64
+}
+
+// NewAggregateTransform validates the grouping keys and window sizes.
+func NewAggregateTransform(groupBy []string, window, slide, lateness time.Duration) (*AggregateTransform, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("aggregate: window must be positive")
+	}
+	if slide <= 0 {
+		slide = window
+	}
+	if slide > window {
+		return nil, fmt.Errorf("aggregate: slide (%s) must not exceed window (%s)", slide, window)
+	}
+	if len(groupBy) == 0 {
+		groupBy = []string{GroupBySrc, GroupByDst, GroupByOperation}
+	}
+	for _, k := range groupBy {
+		switch k {
+		case GroupBySrc, GroupByDst, GroupByOperation, GroupByStatusClass:
+		default:
+			return nil, fmt.Errorf("aggregate: unknown group_by key %q", k)
+		}
+	}
+	return &AggregateTransform{
+		GroupBy:         groupBy,
+		Window:          window,
+		Slide:           slide,
+		AllowedLateness: lateness,
+	}, nil
+}
+
+func (t *AggregateTransform) Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	t.buckets = make(map[windowKey]*windowAgg)
+	t.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	ticker := time.NewTicker(t.Slide)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			// Without new events the watermark would never move, so idle
+			// streams advance it by the Clock time elapsed since the last
+			// event.
+			if t.lastSeen.IsZero() {
+				continue
+			}
+			idle := clock.Or(t.Clock).Now().Sub(t.lastSeen)
+			if !t.emit(ctx, out, t.maxSeen.Add(idle)) {
+				return nil
+			}
+
+		case evt, ok := <-in:
+			if !ok {
+				t.emit(ctx, out, time.Time{})
+				return nil
+			}
+			if evt.Type == event.TypeMetric {
+				select {
+				case out <- evt:
+				case <-ctx.Done():
+					return nil
+				}
+				continue
+			}
+			t.add(&evt)
+			evt.Done() // its window holds what is kept of it
+			if !t.emit(ctx, out, t.maxSeen) {
+				return nil
+			}
+		}
+	}
+}
+
+// add folds evt into every open window that covers its timestamp. It is
+// late only if every such window has been emitted.
+func (t *AggregateTransform) add(evt *event.Event) {
+	n := event.Normalize(evt)
+	now := clock.Or(t.Clock).Now()
+	ts := n.Timestamp
+	if ts.IsZero() {
+		ts = now
+	}
+	t.lastSeen = now
+	if ts.After(t.maxSeen) {
+		t.maxSeen = ts
+	}
+
+	fields := t.groupFields(n)
+	group := groupKey(fields)
+	isErr := n.StatusCode >= 500 || strings.EqualFold(n.Level, "error")
+
+	added := false
+	first := ts.Truncate(t.Slide)
+	for start := first; start.Add(t.Window).After(ts); start = start.Add(-t.Slide) {
+		end := start.Add(t.Window)
+		if !end.After(t.closed) {
+			continue
+		}
+		key := windowKey{start: start, group: group}
+		agg, ok := t.buckets[key]
+		if !ok {
+			agg = &windowAgg{start: start, end: end, fields: fields}
+			t.buckets[key] = agg
+		}
+		agg.observe(n.Latency, isErr, t.rng)
+		added = true
+	}
+	if !added {
+		metrics.AggregateLateEvents.Inc()
+	}
+}
+
+// emit flushes every window whose end is at or before the watermark derived
+// from now. A zero now flushes everything (end of input).
+func (t *AggregateTransform) emit(ctx context.Context, out chan<- event.Event, now time.Time) bool {
+	var ready []*windowAgg
+	var watermark time.Time
+	if !now.IsZero() {
+		watermark = now.Add(-t.AllowedLateness)
+	}
+	for key, agg := range t.buckets {
+		if now.IsZero() || !agg.end.After(watermark) {
+			ready = append(ready, agg)
+			delete(t.buckets, key)
+		}
+	}
+	if !now.IsZero() && watermark.After(t.closed) {
+		t.closed = watermark
+	}
+	if len(ready) == 0 {
+		return true
+	}
+
+	sort.Slice(ready, func(i, j int) bool {
+		if !ready[i].end.Equal(ready[j].end) {
+			return ready[i].end.Before(ready[j].end)
+		}
+		return groupKey(ready[i].fields) < groupKey(ready[j].fields)
+	})
+	for _, agg := range ready {
+		select {
+		case out <- agg.summary():
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (t *AggregateTransform) groupFields(n *event.NormalizedEvent) map[string]string {
+	fields := make(map[string]string, len(t.GroupBy))
+	for _, k := range t.GroupBy {
+		switch k {
+		case GroupBySrc:
+			fields["src_service"] = n.SrcService
+		case GroupByDst:
+			fields["dst_service"] = n.DstService
+		case GroupByOperation:
+			fields["operation"] = n.Operation
+		case GroupByStatusClass:
+			fields["status_class"] = statusClass(n.StatusCode)
+		}
+	}
+	return fields
+}
+
+func groupKey(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + fields[k] + "\x00")
+	}
+	return b.String()
+}
+
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return ""
+	}
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+func (a *windowAgg) observe(latency time.Duration, isErr bool, rng *rand.Rand) {
+	a.count++
+	if isErr {
+		a.errors++
+	}
+	if latency <= 0 {
+		return
+	}
+	a.latencySeen++
+	a.latencySum += latency
+	if a.latencyMin == 0 || latency < a.latencyMin {
+		a.latencyMin = latency
+	}
+	if latency > a.latencyMax {
+		a.latencyMax = latency
+	}
+	// Reservoir sampling keeps p95 bounded in memory for hot groups.
+	if len(a.latencies) < maxLatencySamples {
+		a.latencies = append(a.latencies, latency)
+	} else if i := rng.Int63n(a.latencySeen); i < maxLatencySamples {
+		a.latencies[i] = latency
+	}
+}
+
+func (a *windowAgg) summary() event.Event {
+	attrs := map[string]any{
+		"format":       "aggregate",
+		"window_start": a.start.UTC().Format(time.RFC3339Nano),
+		"window_end":   a.end.UTC().Format(time.RFC3339Nano),
+		"count":        float64(a.count),
+		"error_count":  float64(a.errors),
+	}
+	for k, v := range a.fields {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	if a.latencySeen > 0 {
+		attrs["latency_min_ms"] = durationMs(a.latencyMin)
+		attrs["latency_avg_ms"] = durationMs(a.latencySum / time.Duration(a.latencySeen))
+		attrs["latency_p95_ms"] = durationMs(stats.Percentile(a.latencies, 0.95))
+		attrs["latency_max_ms"] = durationMs(a.latencyMax)
+	}
+
+	level := "info"
+	if a.errors > 0 {
+		level = "error"
+	}
+	return event.Event{
+		Timestamp: a.end,
+		Source:    "aggregate",
+		Service:   a.fields["src_service"],
+		Type:      event.TypeLog,
+		Level:     level,
+		Message: fmt.Sprintf("%d events, %d errors in %s window",
+			a.count, a.errors, a.end.Sub(a.start)),
+		Attrs: attrs,
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
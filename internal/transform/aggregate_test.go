@@ -0,0 +1,214 @@
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+var aggBase = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func callEvent(offset time.Duration, dst string, status int, latencyMs float64) event.Event {
+	return event.Event{
+		Timestamp: aggBase.Add(offset),
+		Service:   "api",
+		Type:      event.TypeLog,
+		Attrs: map[string]any{
+			"dst_service": dst,
+			"operation":   "GET /x",
+			"status":      float64(status),
+			"latency_ms":  latencyMs,
+		},
+	}
+}
+
+func runAggregate(t *testing.T, tr *AggregateTransform, events ...event.Event) []event.Event {
+	t.Helper()
+	in := make(chan event.Event, len(events))
+	out := make(chan event.Event, 64)
+	for _, e := range events {
+		in <- e
+	}
+	close(in)
+	if err := tr.Run(context.Background(), in, out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	close(out)
+	var res []event.Event
+	for e := range out {
+		res = append(res, e)
+	}
+	return res
+}
+
+func TestAggregate_TumblingSummary(t *testing.T) {
+	tr, err := NewAggregateTransform(nil, time.Minute, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := runAggregate(t, tr,
+		callEvent(1*time.Second, "db", 200, 10),
+		callEvent(2*time.Second, "db", 500, 30),
+		callEvent(3*time.Second, "cache", 200, 1),
+		callEvent(4*time.Second, "db", 200, 20),
+		callEvent(70*time.Second, "db", 200, 5), // next window, closes the first
+	)
+
+	if len(res) != 3 {
+		t.Fatalf("got %d summaries, want 3: %+v", len(res), res)
+	}
+	var db event.Event
+	for _, e := range res[:2] {
+		if e.Attrs["dst_service"] == "db" {
+			db = e
+		}
+	}
+	if db.Attrs["count"] != float64(3) || db.Attrs["error_count"] != float64(1) {
+		t.Errorf("db counts: %v", db.Attrs)
+	}
+	if db.Attrs["latency_min_ms"] != float64(10) || db.Attrs["latency_max_ms"] != float64(30) ||
+		db.Attrs["latency_avg_ms"] != float64(20) {
+		t.Errorf("db latency: %v", db.Attrs)
+	}
+	if !db.Timestamp.Equal(aggBase.Add(time.Minute)) {
+		t.Errorf("summary timestamp = %v, want window end", db.Timestamp)
+	}
+	if db.Service != "api" || db.Level != "error" {
+		t.Errorf("Service/Level = %q/%q", db.Service, db.Level)
+	}
+	if res[2].Attrs["window_start"] != aggBase.Add(time.Minute).Format(time.RFC3339Nano) {
+		t.Errorf("last summary should be the flushed second window, got %v", res[2].Attrs["window_start"])
+	}
+}
+
+func TestAggregate_AllowedLateness(t *testing.T) {
+	tr, _ := NewAggregateTransform([]string{GroupBySrc}, time.Minute, 0, 30*time.Second)
+
+	res := runAggregate(t, tr,
+		callEvent(10*time.Second, "db", 200, 1),
+		callEvent(65*time.Second, "db", 200, 1),
+		callEvent(20*time.Second, "db", 200, 1), // late but within lateness
+		callEvent(100*time.Second, "db", 200, 1),
+		callEvent(30*time.Second, "db", 200, 1), // window already emitted → dropped
+	)
+
+	if len(res) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(res))
+	}
+	if res[0].Attrs["count"] != float64(2) {
+		t.Errorf("first window count = %v, want 2", res[0].Attrs["count"])
+	}
+	if res[1].Attrs["count"] != float64(2) {
+		t.Errorf("second window count = %v, want 2", res[1].Attrs["count"])
+	}
+}
+
+func TestAggregate_SlidingLateOnlyWhenNoWindowOpen(t *testing.T) {
+	tr, _ := NewAggregateTransform([]string{GroupBySrc}, 2*time.Minute, time.Minute, 0)
+	before := testutil.ToFloat64(metrics.AggregateLateEvents)
+
+	res := runAggregate(t, tr,
+		callEvent(30*time.Second, "db", 200, 1),
+		callEvent(90*time.Second, "db", 200, 1),  // closes [-1m, 1m)
+		callEvent(45*time.Second, "db", 200, 1),  // still counted in [0, 2m)
+		callEvent(210*time.Second, "db", 200, 1), // closes [0, 2m) and [1m, 3m)
+		callEvent(50*time.Second, "db", 200, 1),  // no window left: late
+	)
+
+	if got := testutil.ToFloat64(metrics.AggregateLateEvents) - before; got != 1 {
+		t.Errorf("late events = %v, want 1", got)
+	}
+	var count any
+	for _, e := range res {
+		if e.Attrs["window_start"] == aggBase.Format(time.RFC3339Nano) {
+			count = e.Attrs["count"]
+		}
+	}
+	if count != float64(3) {
+		t.Errorf("[0, 2m) count = %v, want 3", count)
+	}
+}
+
+func TestAggregate_IdleAdvanceFollowsClock(t *testing.T) {
+	clk := clock.NewFake(aggBase)
+	tr, _ := NewAggregateTransform([]string{GroupBySrc}, 20*time.Millisecond, 0, time.Hour)
+	tr.Clock = clk
+
+	in := make(chan event.Event, 1)
+	out := make(chan event.Event, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tr.Run(ctx, in, out)
+
+	in <- event.Event{Service: "api", Type: event.TypeLog} // stamped from clk
+	select {
+	case e := <-out:
+		t.Fatalf("emitted before the clock moved: %v", e.Attrs)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	clk.Advance(2 * time.Hour)
+	select {
+	case e := <-out:
+		if e.Attrs["window_start"] != aggBase.Format(time.RFC3339Nano) {
+			t.Errorf("window_start = %v, want the clock time", e.Attrs["window_start"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("idle window not emitted after the clock passed the lateness")
+	}
+}
+
+func TestAggregate_SlidingWindows(t *testing.T) {
+	tr, _ := NewAggregateTransform([]string{GroupBySrc}, time.Minute, 30*time.Second, 0)
+
+	res := runAggregate(t, tr, callEvent(45*time.Second, "db", 200, 1))
+
+	if len(res) != 2 {
+		t.Fatalf("event should land in 2 sliding windows, got %d", len(res))
+	}
+	starts := map[any]bool{}
+	for _, e := range res {
+		starts[e.Attrs["window_start"]] = true
+	}
+	if !starts[aggBase.Format(time.RFC3339Nano)] || !starts[aggBase.Add(30*time.Second).Format(time.RFC3339Nano)] {
+		t.Errorf("unexpected window starts %v", starts)
+	}
+}
+
+func TestAggregate_StatusClassAndPassthrough(t *testing.T) {
+	tr, _ := NewAggregateTransform([]string{GroupByStatusClass}, time.Minute, 0, 0)
+
+	metric := event.Event{Type: event.TypeMetric, Metric: "up", Value: 1}
+	res := runAggregate(t, tr,
+		callEvent(time.Second, "db", 200, 1),
+		callEvent(time.Second, "db", 503, 1),
+		metric,
+	)
+
+	if len(res) != 3 || res[0].Type != event.TypeMetric {
+		t.Fatalf("metric should pass straight through, got %+v", res)
+	}
+	classes := map[any]bool{res[1].Attrs["status_class"]: true, res[2].Attrs["status_class"]: true}
+	if !classes["2xx"] || !classes["5xx"] {
+		t.Errorf("status classes = %v", classes)
+	}
+}
+
+func TestNewAggregateTransform_Validation(t *testing.T) {
+	if _, err := NewAggregateTransform(nil, 0, 0, 0); err == nil {
+		t.Error("zero window should fail")
+	}
+	if _, err := NewAggregateTransform(nil, time.Minute, 2*time.Minute, 0); err == nil {
+		t.Error("slide > window should fail")
+	}
+	if _, err := NewAggregateTransform([]string{"host"}, time.Minute, 0, 0); err == nil {
+		t.Error("unknown group key should fail")
+	}
+}
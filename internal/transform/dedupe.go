@@ -0,0 +1,130 @@
+package transform
+
+import (
+	"context"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+const defaultDedupeWindow = 10 * time.Second
+
+// DedupeTransform collapses repeated log lines. Events are keyed on
+// service + level + message template (see messageTemplate); the first event
+// of a key passes through and further matches inside Window are suppressed.
+// When the window closes, a summary event carrying Attrs["repeat_count"] is
+// emitted for keys that saw repeats. Metric events pass through unchanged.
+//
+// Duplicates are matched on event timestamps; windows of idle keys are
+// closed by Clock time.
+type DedupeTransform struct {
+	Window time.Duration
+	Clock  clock.Clock // nil means the wall clock
+
+	entries map[string]*dedupeEntry
+}
+
+type dedupeEntry struct {
+	first    event.Event
+	last     time.Time
+	openedAt time.Time // Clock time, used to close windows on idle streams
+	repeats  int64
+}
+
+func (t *DedupeTransform) Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	if t.Window <= 0 {
+		t.Window = defaultDedupeWindow
+	}
+	t.entries = make(map[string]*dedupeEntry)
+	clk := clock.Or(t.Clock)
+
+	ticker := time.NewTicker(t.Window / 2)
+	defer ticker.Stop()
+
+	send := func(e event.Event) bool {
+		select {
+		case out <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			now := clk.Now()
+			for key, entry := range t.entries {
+				if now.Sub(entry.openedAt) < t.Window {
+					continue
+				}
+				delete(t.entries, key)
+				if entry.repeats > 0 && !send(entry.summary()) {
+					return nil
+				}
+			}
+
+		case evt, ok := <-in:
+			if !ok {
+				for _, entry := range t.entries {
+					if entry.repeats > 0 && !send(entry.summary()) {
+						return nil
+					}
+				}
+				return nil
+			}
+			if evt.Type == event.TypeMetric {
+				if !send(evt) {
+					return nil
+				}
+				continue
+			}
+
+			key := fingerprint(evt.Service, evt.Level, messageTemplate(evt.Message))
+			if entry, ok := t.entries[key]; ok {
+				if evt.Timestamp.Sub(entry.first.Timestamp) < t.Window {
+					entry.repeats++
+					if evt.Timestamp.After(entry.last) {
+						entry.last = evt.Timestamp
+					}
+					metrics.TransformDropped.WithLabelValues("dedupe", "duplicate").Inc()
+					evt.Done()
+					continue
+				}
+				if entry.repeats > 0 && !send(entry.summary()) {
+					return nil
+				}
+			}
+
+			t.entries[key] = &dedupeEntry{
+				first:    evt,
+				last:     evt.Timestamp,
+				openedAt: clk.Now(),
+			}
+			if !send(evt) {
+				return nil
+			}
+		}
+	}
+}
+
+// summary reports how many duplicates of the first event were suppressed.
+func (e *dedupeEntry) summary() event.Event {
+	s := e.first
+	s.Ack = nil // the first event was sent on its own
+	s.Attrs = make(map[string]any, len(e.first.Attrs)+3)
+	for k, v := range e.first.Attrs {
+		s.Attrs[k] = v
+	}
+	s.Attrs["repeat_count"] = float64(e.repeats)
+	s.Attrs["first_seen"] = e.first.Timestamp.UTC().Format(time.RFC3339Nano)
+	s.Attrs["last_seen"] = e.last.UTC().Format(time.RFC3339Nano)
+	s.Attrs["template"] = messageTemplate(e.first.Message)
+	s.Timestamp = e.last
+	return s
+}
@@ -0,0 +1,101 @@
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{`connection to 10.0.0.5:5432 failed after 3 retries`, `connection to <ip> failed after <num> retries`},
+		{`user 8f14e45f-ceea-4671-a8c6-d0a3b1e0e7a2 not found`, `user <uuid> not found`},
+		{`timeout after 250ms calling "payments"`, `timeout after <num> calling "<str>"`},
+		{`request a1b2c3d4 rejected`, `request <hex> rejected`},
+	}
+	for _, tc := range cases {
+		if got := messageTemplate(tc.in); got != tc.want {
+			t.Errorf("messageTemplate(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDedupe_CollapsesRepeats(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mk := func(offset time.Duration, msg string) event.Event {
+		return event.Event{
+			Timestamp: base.Add(offset),
+			Service:   "orders",
+			Level:     "error",
+			Type:      event.TypeLog,
+			Message:   msg,
+		}
+	}
+
+	in := make(chan event.Event, 8)
+	out := make(chan event.Event, 8)
+	in <- mk(0, "db timeout after 30ms")
+	in <- mk(time.Second, "db timeout after 31ms")
+	in <- mk(2*time.Second, "db timeout after 45ms")
+	in <- mk(3*time.Second, "cache miss for key 17")
+	in <- mk(20*time.Second, "db timeout after 29ms") // new window
+	close(in)
+
+	tr := &DedupeTransform{Window: 10 * time.Second}
+	if err := tr.Run(context.Background(), in, out); err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+
+	var got []event.Event
+	for e := range out {
+		got = append(got, e)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d events, want 4: %+v", len(got), got)
+	}
+	summary := got[2]
+	if summary.Attrs["repeat_count"] != float64(2) {
+		t.Fatalf("expected summary with repeat_count=2 before the new window, got %+v", summary)
+	}
+	if !summary.Timestamp.Equal(base.Add(2 * time.Second)) {
+		t.Errorf("summary timestamp = %v, want last duplicate", summary.Timestamp)
+	}
+	if got[3].Message != "db timeout after 29ms" {
+		t.Errorf("new window should forward its first event, got %q", got[3].Message)
+	}
+}
+
+func TestDedupe_IdleWindowClosesByClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tr := &DedupeTransform{Window: 20 * time.Millisecond, Clock: clk}
+
+	in := make(chan event.Event, 2)
+	out := make(chan event.Event, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tr.Run(ctx, in, out)
+
+	dup := event.Event{Service: "api", Level: "error", Type: event.TypeLog, Message: "db timeout after 30ms"}
+	in <- dup
+	in <- dup
+	<-out // the first one passes through
+	select {
+	case e := <-out:
+		t.Fatalf("summary before the clock moved: %v", e.Attrs)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	clk.Advance(time.Second)
+	select {
+	case e := <-out:
+		if e.Attrs["repeat_count"] != float64(1) {
+			t.Errorf("repeat_count = %v, want 1", e.Attrs["repeat_count"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("window not closed after the clock passed it")
+	}
+}
@@ -0,0 +1,80 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"collector/internal/event"
+)
+
+// fieldValue looks up a field on an event. Top-level names (message, level,
+// service, source, type, metric, value) address Event fields; anything else
+// is a dotted path into Attrs (see event.Lookup), optionally written with an
+// explicit "attrs." prefix.
+func fieldValue(evt *event.Event, name string) (any, bool) {
+	switch name {
+	case "message", "msg":
+		return evt.Message, evt.Message != ""
+	case "level":
+		return evt.Level, evt.Level != ""
+	case "service":
+		return evt.Service, evt.Service != ""
+	case "source":
+		return evt.Source, evt.Source != ""
+	case "type":
+		return evt.Type, evt.Type != ""
+	case "metric":
+		return evt.Metric, evt.Metric != ""
+	case "value":
+		return evt.Value, evt.Type == event.TypeMetric
+	}
+	v, ok := evt.Get(strings.TrimPrefix(name, "attrs."))
+	return v, ok && v != nil
+}
+
+// fieldString renders a field value as a string for labels and comparisons.
+func fieldString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// fieldFloat converts a field value to a number. Duration strings such as
+// "12ms" or "1.5s" are converted to milliseconds.
+func fieldFloat(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case time.Duration:
+		return float64(val) / float64(time.Millisecond), true
+	case bool:
+		if val {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		s := strings.TrimSpace(val)
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+		if d, err := time.ParseDuration(s); err == nil {
+			return float64(d) / float64(time.Millisecond), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,357 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"collector/internal/event"
+)
+
+// Filter is a compiled boolean expression evaluated against events.
+//
+// Supported syntax:
+//
+//	level == "error" && status >= 500
+//	service =~ "^api-" || !(message contains "healthcheck")
+//	trace_id                       // true when the field is present
+//
+// Operators: == != > >= < <= =~ !~ contains, combined with && || ! and
+// parentheses ("and", "or", "not" are accepted as aliases). Comparisons are
+// numeric when both sides are numbers, otherwise string-based.
+type Filter struct {
+	src  string
+	root node
+}
+
+// CompileFilter parses expr. An empty expression yields a nil Filter,
+// which matches every event.
+func CompileFilter(expr string) (*Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", expr, err)
+	}
+	p := &filterParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", expr, err)
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("filter %q: unexpected %q", expr, p.toks[p.pos].text)
+	}
+	return &Filter{src: expr, root: root}, nil
+}
+
+// Match reports whether evt satisfies the filter. A nil Filter matches everything.
+func (f *Filter) Match(evt *event.Event) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.eval(evt)
+}
+
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.src
+}
+
+type node interface {
+	eval(evt *event.Event) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ inner node }
+type existsNode struct{ field string }
+
+type cmpNode struct {
+	field string
+	op    string
+	lit   string
+	num   float64
+	isNum bool
+	re    *regexp.Regexp
+}
+
+func (n andNode) eval(evt *event.Event) bool { return n.left.eval(evt) && n.right.eval(evt) }
+func (n orNode) eval(evt *event.Event) bool  { return n.left.eval(evt) || n.right.eval(evt) }
+func (n notNode) eval(evt *event.Event) bool { return !n.inner.eval(evt) }
+
+func (n existsNode) eval(evt *event.Event) bool {
+	v, ok := fieldValue(evt, n.field)
+	if !ok {
+		return false
+	}
+	switch val := v.(type) {
+	case bool:
+		return val
+	case string:
+		return val != ""
+	}
+	return true
+}
+
+func (n cmpNode) eval(evt *event.Event) bool {
+	v, ok := fieldValue(evt, n.field)
+	if !ok {
+		return n.op == "!=" || n.op == "!~"
+	}
+	s := fieldString(v)
+
+	switch n.op {
+	case "=~":
+		return n.re.MatchString(s)
+	case "!~":
+		return !n.re.MatchString(s)
+	case "contains":
+		return strings.Contains(s, n.lit)
+	}
+
+	if n.isNum {
+		if f, ok := fieldFloat(v); ok {
+			switch n.op {
+			case "==":
+				return f == n.num
+			case "!=":
+				return f != n.num
+			case ">":
+				return f > n.num
+			case ">=":
+				return f >= n.num
+			case "<":
+				return f < n.num
+			case "<=":
+				return f <= n.num
+			}
+		}
+	}
+
+	switch n.op {
+	case "==":
+		return s == n.lit
+	case "!=":
+		return s != n.lit
+	case ">":
+		return s > n.lit
+	case ">=":
+		return s >= n.lit
+	case "<":
+		return s < n.lit
+	case "<=":
+		return s <= n.lit
+	}
+	return false
+}
+
+// ── tokenizer ────────────────────────────────────────────────────────────────
+
+type tokKind int
+
+const (
+	tokIdent tokKind = iota
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "("})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")"})
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var b strings.Builder
+			for j < len(s) && s[j] != c {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, token{tokString, b.String()})
+			i = j + 1
+		case strings.ContainsRune("=!<>&|~", rune(c)):
+			j := i + 1
+			for j < len(s) && strings.ContainsRune("=<>&|~", rune(s[j])) {
+				j++
+			}
+			op := s[i:j]
+			switch op {
+			case "==", "!=", ">", ">=", "<", "<=", "=~", "!~", "&&", "||", "!":
+			default:
+				return nil, fmt.Errorf("unknown operator %q", op)
+			}
+			toks = append(toks, token{tokOp, op})
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			toks = append(toks, token{tokNumber, s[i:j]})
+			i = j
+		case isIdentRune(rune(c)):
+			j := i + 1
+			for j < len(s) && (isIdentRune(rune(s[j])) || (s[j] >= '0' && s[j] <= '9') || s[j] == '-') {
+				j++
+			}
+			word := s[i:j]
+			switch strings.ToLower(word) {
+			case "and":
+				toks = append(toks, token{tokOp, "&&"})
+			case "or":
+				toks = append(toks, token{tokOp, "||"})
+			case "not":
+				toks = append(toks, token{tokOp, "!"})
+			case "contains":
+				toks = append(toks, token{tokOp, "contains"})
+			default:
+				toks = append(toks, token{tokIdent, word})
+			}
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+		}
+	}
+	return toks, nil
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '.' || r == '@'
+}
+
+// ── parser ───────────────────────────────────────────────────────────────────
+
+type filterParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *filterParser) peek() *token {
+	if p.pos >= len(p.toks) {
+		return nil
+	}
+	return &p.toks[p.pos]
+}
+
+func (p *filterParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokOp && t.text == "||"; t = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokOp && t.text == "&&"; t = p.peek() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	if t.kind == tokOp && t.text == "!" {
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	if t.kind == tokLParen {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.peek(); r == nil || r.kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (node, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return nil, fmt.Errorf("expected field name, got %q", t.text)
+	}
+	field := t.text
+	p.pos++
+
+	op := p.peek()
+	if op == nil || op.kind != tokOp || op.text == "&&" || op.text == "||" || op.text == "!" {
+		return existsNode{field: field}, nil
+	}
+	p.pos++
+
+	lit := p.peek()
+	if lit == nil || (lit.kind != tokString && lit.kind != tokNumber && lit.kind != tokIdent) {
+		return nil, fmt.Errorf("expected value after %q", op.text)
+	}
+	p.pos++
+
+	n := cmpNode{field: field, op: op.text, lit: lit.text}
+	if lit.kind == tokNumber {
+		if f, err := strconv.ParseFloat(lit.text, 64); err == nil {
+			n.num, n.isNum = f, true
+		}
+	}
+	if n.op == "=~" || n.op == "!~" {
+		re, err := regexp.Compile(lit.text)
+		if err != nil {
+			return nil, err
+		}
+		n.re = re
+	}
+	return n, nil
+}
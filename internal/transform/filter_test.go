@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"testing"
+
+	"collector/internal/event"
+)
+
+func TestFilter_Match(t *testing.T) {
+	evt := &event.Event{
+		Service: "api-gateway",
+		Level:   "error",
+		Type:    event.TypeLog,
+		Message: "upstream timeout after healthcheck",
+		Attrs: map[string]any{
+			"status":   float64(503),
+			"latency":  "120ms",
+			"trace_id": "abc",
+			"cached":   false,
+			"http":     map[string]any{"status": float64(503), "method": "GET"},
+		},
+	}
+
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{`level == "error"`, true},
+		{`level != "error"`, false},
+		{`status >= 500`, true},
+		{`status < 500`, false},
+		{`latency > 100`, true},
+		{`service =~ "^api-"`, true},
+		{`service !~ "^api-"`, false},
+		{`message contains "timeout"`, true},
+		{`trace_id`, true},
+		{`span_id`, false},
+		{`cached`, false},
+		{`level == "error" && status >= 500`, true},
+		{`level == "info" || status == 503`, true},
+		{`!(message contains "healthcheck")`, false},
+		{`level == error and not span_id`, true},
+		{`missing != "x"`, true},
+		{`http.status >= 500`, true},
+		{`attrs.http.method == "GET"`, true},
+		{`http.path`, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			f, err := CompileFilter(tc.expr)
+			if err != nil {
+				t.Fatalf("CompileFilter: %v", err)
+			}
+			if got := f.Match(evt); got != tc.want {
+				t.Errorf("Match = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Empty(t *testing.T) {
+	f, err := CompileFilter("  ")
+	if err != nil || f != nil {
+		t.Fatalf("empty filter: got %v, %v", f, err)
+	}
+	if !f.Match(&event.Event{}) {
+		t.Error("nil filter should match everything")
+	}
+}
+
+func TestFilter_Errors(t *testing.T) {
+	for _, expr := range []string{
+		`level ==`,
+		`(level == "x"`,
+		`level === "x"`,
+		`"unterminated`,
+		`service =~ "("`,
+	} {
+		if _, err := CompileFilter(expr); err == nil {
+			t.Errorf("CompileFilter(%q): expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package transform
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Variable parts of a message, replaced in order so that more specific
+// patterns (UUIDs, IPs) win over the generic number rule.
+var templateRules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`"[^"]*"|'[^']*'`), `"<str>"`},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b|\b[0-9a-fA-F]*\d[0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*\b`), "<hex>"},
+	{regexp.MustCompile(`\b\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h)?\b`), "<num>"},
+}
+
+// messageTemplate strips variable tokens from msg so that log lines emitted
+// by the same statement share a template.
+func messageTemplate(msg string) string {
+	t := strings.TrimSpace(msg)
+	for _, r := range templateRules {
+		t = r.re.ReplaceAllString(t, r.repl)
+	}
+	return t
+}
+
+// fingerprint hashes the given parts into a compact map key.
+func fingerprint(parts ...string) string {
+	h := fnv.New64a()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,263 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+const (
+	MetricCounter   = "counter"
+	MetricHistogram = "histogram"
+	MetricGauge     = "gauge"
+
+	// ModeEmit sends derived metrics downstream as event.TypeMetric events.
+	ModeEmit = "emit"
+	// ModeRegister records derived metrics as Prometheus series in the
+	// collector's own registry instead of emitting events.
+	ModeRegister = "register"
+
+	defaultMaxCardinality = 1000
+	overflowLabelValue    = "__overflow__"
+)
+
+var defaultBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// MetricRule derives one metric from log events.
+type MetricRule struct {
+	Type   string
+	Name   string
+	Filter *Filter
+	// Field is the numeric field observed by histograms and gauges.
+	Field string
+	// Labels maps label name -> event field.
+	Labels         map[string]string
+	Buckets        []float64
+	MaxCardinality int
+
+	labelNames []string
+	mu         sync.Mutex
+	seen       map[string]struct{}
+	collector  prometheus.Collector
+}
+
+// LogToMetricTransform passes every event through unchanged and derives
+// counters, histograms and gauges from the log events that match each rule.
+type LogToMetricTransform struct {
+	Mode  string
+	Rules []*MetricRule
+}
+
+// NewLogToMetricTransform validates rules and, in register mode, creates
+// and registers their Prometheus collectors.
+func NewLogToMetricTransform(mode string, rules []*MetricRule) (*LogToMetricTransform, error) {
+	if mode == "" {
+		mode = ModeEmit
+	}
+	if mode != ModeEmit && mode != ModeRegister {
+		return nil, fmt.Errorf("log_to_metric: unknown mode %q", mode)
+	}
+	for _, r := range rules {
+		if err := r.init(mode); err != nil {
+			return nil, err
+		}
+	}
+	return &LogToMetricTransform{Mode: mode, Rules: rules}, nil
+}
+
+func (r *MetricRule) init(mode string) error {
+	if r.Name == "" {
+		return fmt.Errorf("log_to_metric: metric name is required")
+	}
+	switch r.Type {
+	case MetricCounter:
+	case MetricHistogram, MetricGauge:
+		if r.Field == "" {
+			return fmt.Errorf("log_to_metric: %s %q requires a field", r.Type, r.Name)
+		}
+	default:
+		return fmt.Errorf("log_to_metric: metric %q has unknown type %q", r.Name, r.Type)
+	}
+	if len(r.Buckets) == 0 {
+		r.Buckets = defaultBuckets
+	}
+	sort.Float64s(r.Buckets)
+	if r.MaxCardinality <= 0 {
+		r.MaxCardinality = defaultMaxCardinality
+	}
+	r.seen = make(map[string]struct{})
+
+	r.labelNames = make([]string, 0, len(r.Labels))
+	for name := range r.Labels {
+		r.labelNames = append(r.labelNames, name)
+	}
+	sort.Strings(r.labelNames)
+
+	if mode != ModeRegister {
+		return nil
+	}
+	help := "Derived from log events by the log_to_metric transform"
+	switch r.Type {
+	case MetricCounter:
+		r.collector = prometheus.NewCounterVec(prometheus.CounterOpts{Name: r.Name, Help: help}, r.labelNames)
+	case MetricHistogram:
+		r.collector = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: r.Name, Help: help, Buckets: r.Buckets}, r.labelNames)
+	case MetricGauge:
+		r.collector = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: r.Name, Help: help}, r.labelNames)
+	}
+	if err := metrics.Register(r.collector); err != nil {
+		return fmt.Errorf("log_to_metric: register %q: %w", r.Name, err)
+	}
+	return nil
+}
+
+func (t *LogToMetricTransform) Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	for evt := range in {
+		select {
+		case out <- evt:
+		case <-ctx.Done():
+			return nil
+		}
+		if evt.Type == event.TypeMetric {
+			continue
+		}
+
+		for _, r := range t.Rules {
+			for _, m := range t.apply(r, &evt) {
+				select {
+				case out <- m:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// apply evaluates one rule against evt. In emit mode it returns the derived
+// metric events; in register mode it updates the collector and returns nil.
+func (t *LogToMetricTransform) apply(r *MetricRule, evt *event.Event) []event.Event {
+	if !r.Filter.Match(evt) {
+		return nil
+	}
+
+	var value float64
+	if r.Type != MetricCounter {
+		v, ok := fieldValue(evt, r.Field)
+		if !ok {
+			return nil
+		}
+		if value, ok = fieldFloat(v); !ok {
+			return nil
+		}
+	}
+
+	labels := r.labelValues(evt)
+
+	if t.Mode == ModeRegister {
+		switch c := r.collector.(type) {
+		case *prometheus.CounterVec:
+			c.WithLabelValues(labels...).Inc()
+		case *prometheus.HistogramVec:
+			c.WithLabelValues(labels...).Observe(value)
+		case *prometheus.GaugeVec:
+			c.WithLabelValues(labels...).Set(value)
+		}
+		return nil
+	}
+
+	attrs := make(map[string]any, len(labels)+1)
+	for i, name := range r.labelNames {
+		attrs[name] = labels[i]
+	}
+
+	switch r.Type {
+	case MetricCounter:
+		return []event.Event{derived(evt, r.Name, 1, MetricCounter, attrs)}
+	case MetricGauge:
+		return []event.Event{derived(evt, r.Name, value, MetricGauge, attrs)}
+	}
+
+	// Histograms are emitted as Prometheus-style cumulative series
+	// (_bucket{le}, _sum, _count) with counter semantics, so sinks that
+	// accumulate counters reconstruct the full histogram.
+	out := make([]event.Event, 0, len(r.Buckets)+3)
+	for _, le := range r.Buckets {
+		if value <= le {
+			out = append(out, derived(evt, r.Name+"_bucket", 1, MetricCounter,
+				withAttr(attrs, "le", strconv.FormatFloat(le, 'f', -1, 64))))
+		}
+	}
+	out = append(out,
+		derived(evt, r.Name+"_bucket", 1, MetricCounter, withAttr(attrs, "le", "+Inf")),
+		derived(evt, r.Name+"_sum", value, MetricCounter, attrs),
+		derived(evt, r.Name+"_count", 1, MetricCounter, attrs),
+	)
+	return out
+}
+
+// labelValues extracts label values in labelNames order. Once a rule has
+// seen MaxCardinality distinct label sets, new sets collapse into a single
+// overflow series so a runaway field cannot explode the series count.
+func (r *MetricRule) labelValues(evt *event.Event) []string {
+	values := make([]string, len(r.labelNames))
+	for i, name := range r.labelNames {
+		if v, ok := fieldValue(evt, r.Labels[name]); ok {
+			values[i] = fieldString(v)
+		}
+	}
+	if len(values) == 0 {
+		return values
+	}
+
+	key := strings.Join(values, "\x00")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.seen[key]; ok {
+		return values
+	}
+	if len(r.seen) >= r.MaxCardinality {
+		metrics.LogToMetricOverflow.WithLabelValues(r.Name).Inc()
+		for i := range values {
+			values[i] = overflowLabelValue
+		}
+		return values
+	}
+	r.seen[key] = struct{}{}
+	return values
+}
+
+func derived(src *event.Event, name string, value float64, kind string, labels map[string]any) event.Event {
+	attrs := make(map[string]any, len(labels)+1)
+	for k, v := range labels {
+		attrs[k] = v
+	}
+	attrs["metric_type"] = kind
+	return event.Event{
+		Timestamp: src.Timestamp,
+		Source:    src.Source,
+		Service:   src.Service,
+		Type:      event.TypeMetric,
+		Metric:    name,
+		Value:     value,
+		Attrs:     attrs,
+	}
+}
+
+func withAttr(m map[string]any, k string, v any) map[string]any {
+	c := make(map[string]any, len(m)+1)
+	for key, val := range m {
+		c[key] = val
+	}
+	c[k] = v
+	return c
+}
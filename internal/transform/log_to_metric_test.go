@@ -0,0 +1,164 @@
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"collector/internal/event"
+)
+
+func runLogToMetric(t *testing.T, tr *LogToMetricTransform, events ...event.Event) []event.Event {
+	t.Helper()
+	in := make(chan event.Event, len(events))
+	out := make(chan event.Event, 256)
+	for _, e := range events {
+		in <- e
+	}
+	close(in)
+
+	if err := tr.Run(context.Background(), in, out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	close(out)
+
+	var res []event.Event
+	for e := range out {
+		res = append(res, e)
+	}
+	return res
+}
+
+func logEvent(level string, attrs map[string]any) event.Event {
+	return event.Event{
+		Timestamp: time.Now(),
+		Service:   "checkout",
+		Type:      event.TypeLog,
+		Level:     level,
+		Attrs:     attrs,
+	}
+}
+
+func metricsNamed(evts []event.Event, name string) []event.Event {
+	var out []event.Event
+	for _, e := range evts {
+		if e.Type == event.TypeMetric && e.Metric == name {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestLogToMetric_Counter(t *testing.T) {
+	filter, _ := CompileFilter(`status >= 500`)
+	tr, err := NewLogToMetricTransform(ModeEmit, []*MetricRule{{
+		Type:   MetricCounter,
+		Name:   "http_errors_total",
+		Filter: filter,
+		Labels: map[string]string{"route": "path"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := runLogToMetric(t, tr,
+		logEvent("error", map[string]any{"status": float64(502), "path": "/pay"}),
+		logEvent("info", map[string]any{"status": float64(200), "path": "/pay"}),
+	)
+
+	if len(res) != 3 {
+		t.Fatalf("got %d events, want 2 logs + 1 metric", len(res))
+	}
+	m := metricsNamed(res, "http_errors_total")
+	if len(m) != 1 {
+		t.Fatalf("got %d counter events, want 1", len(m))
+	}
+	if m[0].Value != 1 || m[0].Attrs["route"] != "/pay" || m[0].Attrs["metric_type"] != MetricCounter {
+		t.Errorf("unexpected counter event %+v", m[0])
+	}
+	if m[0].Service != "checkout" {
+		t.Errorf("Service = %q, want checkout", m[0].Service)
+	}
+}
+
+func TestLogToMetric_HistogramBuckets(t *testing.T) {
+	tr, err := NewLogToMetricTransform(ModeEmit, []*MetricRule{{
+		Type:    MetricHistogram,
+		Name:    "latency_ms",
+		Field:   "latency",
+		Buckets: []float64{10, 100, 1000},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := runLogToMetric(t, tr, logEvent("info", map[string]any{"latency": "42ms"}))
+
+	buckets := metricsNamed(res, "latency_ms_bucket")
+	var les []string
+	for _, b := range buckets {
+		les = append(les, b.Attrs["le"].(string))
+	}
+	if len(les) != 3 || les[0] != "100" || les[1] != "1000" || les[2] != "+Inf" {
+		t.Errorf("bucket le labels = %v, want [100 1000 +Inf]", les)
+	}
+	if sum := metricsNamed(res, "latency_ms_sum"); len(sum) != 1 || sum[0].Value != 42 {
+		t.Errorf("sum = %+v", sum)
+	}
+	if cnt := metricsNamed(res, "latency_ms_count"); len(cnt) != 1 || cnt[0].Value != 1 {
+		t.Errorf("count = %+v", cnt)
+	}
+}
+
+func TestLogToMetric_GaugeSkipsNonNumeric(t *testing.T) {
+	tr, _ := NewLogToMetricTransform(ModeEmit, []*MetricRule{{
+		Type:  MetricGauge,
+		Name:  "queue_depth",
+		Field: "depth",
+	}})
+
+	res := runLogToMetric(t, tr,
+		logEvent("info", map[string]any{"depth": float64(7)}),
+		logEvent("info", map[string]any{"depth": "n/a"}),
+	)
+	g := metricsNamed(res, "queue_depth")
+	if len(g) != 1 || g[0].Value != 7 {
+		t.Errorf("gauge events = %+v", g)
+	}
+}
+
+func TestLogToMetric_CardinalityLimit(t *testing.T) {
+	tr, _ := NewLogToMetricTransform(ModeEmit, []*MetricRule{{
+		Type:           MetricCounter,
+		Name:           "requests_total",
+		Labels:         map[string]string{"user": "user_id"},
+		MaxCardinality: 2,
+	}})
+
+	res := runLogToMetric(t, tr,
+		logEvent("info", map[string]any{"user_id": "u1"}),
+		logEvent("info", map[string]any{"user_id": "u2"}),
+		logEvent("info", map[string]any{"user_id": "u3"}),
+		logEvent("info", map[string]any{"user_id": "u1"}),
+	)
+
+	var users []string
+	for _, m := range metricsNamed(res, "requests_total") {
+		users = append(users, m.Attrs["user"].(string))
+	}
+	want := []string{"u1", "u2", overflowLabelValue, "u1"}
+	for i := range want {
+		if users[i] != want[i] {
+			t.Fatalf("labels = %v, want %v", users, want)
+		}
+	}
+}
+
+func TestLogToMetric_InvalidRule(t *testing.T) {
+	if _, err := NewLogToMetricTransform(ModeEmit, []*MetricRule{{Type: MetricHistogram, Name: "x"}}); err == nil {
+		t.Error("histogram without field should fail")
+	}
+	if _, err := NewLogToMetricTransform("bogus", nil); err == nil {
+		t.Error("unknown mode should fail")
+	}
+}
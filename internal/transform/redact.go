@@ -0,0 +1,360 @@
+package transform
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+// Redaction actions.
+const (
+	RedactMask   = "mask"   // replace with [REDACTED:<rule>]
+	RedactDelete = "delete" // remove the match (or the whole field)
+	RedactHash   = "hash"   // replace with a keyed HMAC so values stay joinable
+)
+
+// Built-in detector names accepted in RedactRule.Detector.
+const (
+	DetectEmail      = "email"
+	DetectCreditCard = "credit_card"
+	DetectIPv4       = "ipv4"
+	DetectIPv6       = "ipv6"
+	DetectJWT        = "jwt"
+	DetectBearer     = "bearer"
+	DetectAWSKey     = "aws_key"
+)
+
+// A detector finds candidate matches with re and, if validate is set,
+// rejects false positives (failed Luhn check, unparsable address, ...).
+// When re has a capture group only the first group is redacted, so for
+// example "Bearer " survives and just the token is replaced.
+type detector struct {
+	re       *regexp.Regexp
+	validate func(string) bool
+}
+
+var builtinDetectors = map[string][]detector{
+	DetectEmail: {{re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)}},
+	DetectCreditCard: {{
+		re:       regexp.MustCompile(`\b(?:\d{4}[ -]){3}\d{1,7}\b|\b\d{4}[ -]\d{6}[ -]\d{5}\b|\b\d{13,19}\b`),
+		validate: luhnValid,
+	}},
+	DetectIPv4: {{
+		re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+	}},
+	DetectIPv6: {{
+		re:       regexp.MustCompile(`(?:^|[^0-9A-Za-z_:.])((?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4})`),
+		validate: isIPv6,
+	}},
+	DetectJWT:    {{re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)}},
+	DetectBearer: {{re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`)}},
+	DetectAWSKey: {
+		{re: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`)},
+		{re: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`)},
+	},
+}
+
+// DefaultRedactDetectors lists the detectors applied when no rules are configured.
+var DefaultRedactDetectors = []string{
+	DetectEmail, DetectCreditCard, DetectJWT, DetectBearer, DetectAWSKey, DetectIPv4, DetectIPv6,
+}
+
+// RedactRule finds sensitive values with either a built-in Detector or a
+// custom Pattern and applies Action to each match.
+type RedactRule struct {
+	Name     string
+	Detector string
+	Pattern  string
+	Action   string
+
+	detectors []detector
+}
+
+// RedactTransform scrubs sensitive values from Message and from every
+// string in Attrs, including nested maps and slices.
+//
+// Fields are addressed as "message" or by their dotted attribute path
+// ("user.email", optionally prefixed with "attrs."); a bare key such as
+// "password" also matches that key at any depth.
+//
+//	AllowFields – never scanned, passed through untouched
+//	DenyFields  – always redacted as a whole with DenyAction, whatever their value
+//
+// Metric events pass through unchanged.
+type RedactTransform struct {
+	Rules       []*RedactRule
+	AllowFields []string
+	DenyFields  []string
+	DenyAction  string
+
+	key   []byte
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// NewRedactTransform validates the rules, falling back to the built-in
+// detectors with defaultAction when none are given. hashKey is required
+// when any rule hashes.
+func NewRedactTransform(rules []*RedactRule, allow, deny []string, defaultAction, hashKey string) (*RedactTransform, error) {
+	if defaultAction == "" {
+		defaultAction = RedactMask
+	}
+	if len(rules) == 0 {
+		for _, d := range DefaultRedactDetectors {
+			rules = append(rules, &RedactRule{Detector: d})
+		}
+	}
+
+	needKey := len(deny) > 0 && defaultAction == RedactHash
+	for _, r := range rules {
+		if r.Action == "" {
+			r.Action = defaultAction
+		}
+		if err := r.init(); err != nil {
+			return nil, err
+		}
+		if r.Action == RedactHash {
+			needKey = true
+		}
+	}
+	if err := checkRedactAction(defaultAction); err != nil {
+		return nil, err
+	}
+	if needKey && hashKey == "" {
+		return nil, fmt.Errorf("redact: action %q requires hash_key", RedactHash)
+	}
+
+	return &RedactTransform{
+		Rules:       rules,
+		AllowFields: allow,
+		DenyFields:  deny,
+		DenyAction:  defaultAction,
+		key:         []byte(hashKey),
+		allow:       fieldSet(allow),
+		deny:        fieldSet(deny),
+	}, nil
+}
+
+func (r *RedactRule) init() error {
+	if err := checkRedactAction(r.Action); err != nil {
+		return err
+	}
+	switch {
+	case r.Pattern != "" && r.Detector != "":
+		return fmt.Errorf("redact: rule %q sets both detector and pattern", r.Name)
+	case r.Pattern != "":
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("redact: rule %q: %w", r.Name, err)
+		}
+		r.detectors = []detector{{re: re}}
+		if r.Name == "" {
+			r.Name = "custom"
+		}
+	case r.Detector != "":
+		ds, ok := builtinDetectors[r.Detector]
+		if !ok {
+			return fmt.Errorf("redact: unknown detector %q", r.Detector)
+		}
+		r.detectors = ds
+		if r.Name == "" {
+			r.Name = r.Detector
+		}
+	default:
+		return fmt.Errorf("redact: rule %q needs a detector or a pattern", r.Name)
+	}
+	return nil
+}
+
+func checkRedactAction(action string) error {
+	switch action {
+	case RedactMask, RedactDelete, RedactHash:
+		return nil
+	}
+	return fmt.Errorf("redact: unknown action %q", action)
+}
+
+func (t *RedactTransform) Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	for evt := range in {
+		if evt.Type != event.TypeMetric {
+			t.redactEvent(&evt)
+		}
+		select {
+		case out <- evt:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+func (t *RedactTransform) redactEvent(evt *event.Event) {
+	switch {
+	case t.allow["message"]:
+	case t.deny["message"]:
+		if v, keep := t.redactWhole(evt.Message); keep {
+			evt.Message = v.(string)
+		} else {
+			evt.Message = ""
+		}
+	default:
+		evt.Message = t.scan(evt.Message)
+	}
+	if evt.Attrs != nil {
+		t.redactMap(evt.Attrs, "")
+	}
+}
+
+func (t *RedactTransform) redactMap(m map[string]any, prefix string) {
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if k == "format" && prefix == "" {
+			continue
+		}
+		if t.matches(t.allow, path, k) {
+			continue
+		}
+		if t.matches(t.deny, path, k) {
+			if nv, keep := t.redactWhole(v); keep {
+				m[k] = nv
+			} else {
+				delete(m, k)
+			}
+			continue
+		}
+		m[k] = t.redactValue(v, path)
+	}
+}
+
+func (t *RedactTransform) redactValue(v any, path string) any {
+	switch val := v.(type) {
+	case string:
+		return t.scan(val)
+	case map[string]any:
+		t.redactMap(val, path)
+	case []any:
+		for i := range val {
+			val[i] = t.redactValue(val[i], path)
+		}
+	}
+	return v
+}
+
+// redactWhole applies DenyAction to an entire field value. keep is false
+// when the field should be removed.
+func (t *RedactTransform) redactWhole(v any) (any, bool) {
+	metrics.Redactions.WithLabelValues("deny_field").Inc()
+	switch t.DenyAction {
+	case RedactDelete:
+		return nil, false
+	case RedactHash:
+		return t.hash("field", fieldString(v)), true
+	default:
+		return "[REDACTED]", true
+	}
+}
+
+// scan runs every rule over s.
+func (t *RedactTransform) scan(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, r := range t.Rules {
+		for _, d := range r.detectors {
+			s = t.apply(r, d, s)
+		}
+	}
+	return s
+}
+
+func (t *RedactTransform) apply(r *RedactRule, d detector, s string) string {
+	matches := d.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		match := s[start:end]
+		if d.validate != nil && !d.validate(match) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		switch r.Action {
+		case RedactMask:
+			b.WriteString("[REDACTED:" + r.Name + "]")
+		case RedactHash:
+			b.WriteString(t.hash(r.Name, match))
+		}
+		last = end
+		metrics.Redactions.WithLabelValues(r.Name).Inc()
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// hash returns a truncated HMAC-SHA256 of v: equal inputs give equal
+// outputs, so redacted values can still be joined across events.
+func (t *RedactTransform) hash(name, v string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(v))
+	return "[" + name + ":" + hex.EncodeToString(mac.Sum(nil)[:8]) + "]"
+}
+
+// matches reports whether set names path, its "attrs."-prefixed form, or key.
+func (t *RedactTransform) matches(set map[string]bool, path, key string) bool {
+	return set[path] || set["attrs."+path] || set[key]
+}
+
+func fieldSet(fields []string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		set[f] = true
+	}
+	return set
+}
+
+// luhnValid checks a card number candidate, ignoring spaces and dashes.
+func luhnValid(s string) bool {
+	var digits []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			digits = append(digits, s[i]-'0')
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i])
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func isIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is6()
+}
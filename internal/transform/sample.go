@@ -0,0 +1,286 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"collector/internal/event"
+	"collector/internal/metrics"
+	"collector/internal/parse"
+)
+
+// Sampling modes for SampleTransform.
+const (
+	SampleHash  = "hash"
+	SampleLevel = "level"
+	SampleTail  = "tail"
+)
+
+const (
+	defaultTailWindow = 10 * time.Second
+	defaultMaxTraces  = 10000
+)
+
+// SampleTransform keeps a fraction of log events. Metric events always pass.
+//
+//	hash  – keep Rate of events, decided by a hash of Field (trace_id by
+//	        default) so every event of a trace shares the same fate
+//	level – like hash, but the rate comes from LevelRates[level], falling
+//	        back to Rate (or keeping everything) for unlisted levels
+//	tail  – buffer each trace for Window; keep the whole trace if any event
+//	        has StatusCode >= 500 or latency above LatencyThreshold,
+//	        otherwise keep it with probability Rate
+//
+// Kept events sampled at a rate below 1 carry Attrs["sample_rate"] = 1/rate.
+type SampleTransform struct {
+	Mode             string
+	Field            string
+	Rate             float64
+	LevelRates       map[string]float64
+	Window           time.Duration
+	LatencyThreshold time.Duration
+	MaxTraces        int
+
+	traces map[string]*traceBuffer
+	order  []string // trace ids in arrival order, for MaxTraces eviction
+	rng    *rand.Rand
+}
+
+type traceBuffer struct {
+	events    []event.Event
+	openedAt  time.Time
+	important bool
+}
+
+// NewSampleTransform validates the mode and rates.
+func NewSampleTransform(mode, field string, rate float64, levelRates map[string]float64) (*SampleTransform, error) {
+	if mode == "" {
+		mode = SampleHash
+	}
+	switch mode {
+	case SampleHash, SampleLevel, SampleTail:
+	default:
+		return nil, fmt.Errorf("sample: unknown mode %q", mode)
+	}
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("sample: rate must be between 0 and 1, got %v", rate)
+	}
+	for lvl, r := range levelRates {
+		if r < 0 || r > 1 {
+			return nil, fmt.Errorf("sample: rate for level %q must be between 0 and 1, got %v", lvl, r)
+		}
+	}
+	switch {
+	case mode == SampleHash && rate == 0:
+		return nil, fmt.Errorf("sample: hash mode requires a rate above 0")
+	case mode == SampleLevel && len(levelRates) == 0:
+		return nil, fmt.Errorf("sample: level mode requires level_rates")
+	case mode == SampleLevel && rate == 0:
+		rate = 1 // unlisted levels are kept unless a rate is given
+	}
+	if field == "" {
+		field = "trace_id"
+	}
+	return &SampleTransform{
+		Mode:       mode,
+		Field:      field,
+		Rate:       rate,
+		LevelRates: levelRates,
+	}, nil
+}
+
+func (t *SampleTransform) Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	t.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	if t.Mode == SampleTail {
+		return t.runTail(ctx, in, out)
+	}
+
+	for evt := range in {
+		if evt.Type != event.TypeMetric {
+			rate := t.Rate
+			if t.Mode == SampleLevel {
+				rate = t.levelRate(evt.Level)
+			}
+			if !t.keep(t.key(&evt), rate) {
+				metrics.TransformDropped.WithLabelValues("sample", "sampled_out").Inc()
+				evt.Done()
+				continue
+			}
+			tagRate(&evt, rate)
+		}
+		select {
+		case out <- evt:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+func (t *SampleTransform) runTail(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	if t.Window <= 0 {
+		t.Window = defaultTailWindow
+	}
+	if t.MaxTraces <= 0 {
+		t.MaxTraces = defaultMaxTraces
+	}
+	t.traces = make(map[string]*traceBuffer)
+
+	ticker := time.NewTicker(t.Window / 4)
+	defer ticker.Stop()
+
+	send := func(e event.Event) bool {
+		select {
+		case out <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case now := <-ticker.C:
+			for len(t.order) > 0 {
+				buf := t.traces[t.order[0]]
+				if now.Sub(buf.openedAt) < t.Window {
+					break
+				}
+				if !t.decide(t.order[0], send) {
+					return nil
+				}
+			}
+
+		case evt, ok := <-in:
+			if !ok {
+				for len(t.order) > 0 {
+					if !t.decide(t.order[0], send) {
+						return nil
+					}
+				}
+				return nil
+			}
+
+			traceID := t.key(&evt)
+			if evt.Type == event.TypeMetric || traceID == "" {
+				if !send(evt) {
+					return nil
+				}
+				continue
+			}
+
+			buf, ok := t.traces[traceID]
+			if !ok {
+				if len(t.order) >= t.MaxTraces && !t.decide(t.order[0], send) {
+					return nil
+				}
+				buf = &traceBuffer{openedAt: time.Now()}
+				t.traces[traceID] = buf
+				t.order = append(t.order, traceID)
+			}
+			buf.events = append(buf.events, evt)
+			if t.isImportant(&evt) {
+				buf.important = true
+			}
+		}
+	}
+}
+
+// decide releases or drops the buffered trace. It must be the oldest one.
+func (t *SampleTransform) decide(traceID string, send func(event.Event) bool) bool {
+	buf := t.traces[traceID]
+	delete(t.traces, traceID)
+	t.order = t.order[1:]
+
+	rate := 1.0
+	if !buf.important {
+		rate = t.Rate
+		if !t.keep(traceID, rate) {
+			metrics.TransformDropped.WithLabelValues("sample", "sampled_out").Add(float64(len(buf.events)))
+			for _, e := range buf.events {
+				e.Done()
+			}
+			return true
+		}
+	}
+	for _, e := range buf.events {
+		tagRate(&e, rate)
+		if !send(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *SampleTransform) isImportant(evt *event.Event) bool {
+	if parse.ExtractStatusCode(evt.Attrs) >= 500 {
+		return true
+	}
+	if t.LatencyThreshold > 0 && parse.ExtractLatency(evt.Attrs) > t.LatencyThreshold {
+		return true
+	}
+	return false
+}
+
+// key returns the sampling key: the trace id for the default field (using
+// the same aliases as the JSON parser), or the configured field otherwise.
+func (t *SampleTransform) key(evt *event.Event) string {
+	if t.Field == "trace_id" {
+		return parse.ExtractTraceID(evt.Attrs)
+	}
+	if v, ok := fieldValue(evt, t.Field); ok {
+		return fieldString(v)
+	}
+	return ""
+}
+
+func (t *SampleTransform) levelRate(level string) float64 {
+	if r, ok := t.LevelRates[strings.ToLower(level)]; ok {
+		return r
+	}
+	return t.Rate
+}
+
+// keep decides deterministically from key when present and randomly otherwise.
+func (t *SampleTransform) keep(key string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	if key == "" {
+		return t.rng.Float64() < rate
+	}
+	return hashFraction(key) < rate
+}
+
+// hashFraction maps key uniformly onto [0, 1).
+func hashFraction(key string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	// FNV leaves the high bits poorly mixed for short keys that differ only
+	// at the end (trace-1, trace-2, ...), so finish with a splitmix64 round.
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / float64(uint64(1)<<53)
+}
+
+func tagRate(evt *event.Event, rate float64) {
+	if rate <= 0 || rate >= 1 {
+		return
+	}
+	evt.Attrs = withAttr(evt.Attrs, "sample_rate", math.Round(1/rate*1000)/1000)
+}
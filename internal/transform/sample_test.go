@@ -0,0 +1,134 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"collector/internal/event"
+)
+
+func runSample(t *testing.T, tr *SampleTransform, events []event.Event) []event.Event {
+	t.Helper()
+	in := make(chan event.Event, len(events))
+	out := make(chan event.Event, len(events))
+	for _, e := range events {
+		in <- e
+	}
+	close(in)
+	if err := tr.Run(context.Background(), in, out); err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+	var res []event.Event
+	for e := range out {
+		res = append(res, e)
+	}
+	return res
+}
+
+func tracedEvent(trace, level string, attrs map[string]any) event.Event {
+	a := map[string]any{"trace_id": trace}
+	for k, v := range attrs {
+		a[k] = v
+	}
+	return event.Event{Timestamp: time.Now(), Service: "api", Type: event.TypeLog, Level: level, Attrs: a}
+}
+
+func TestSample_HashKeepsWholeTraces(t *testing.T) {
+	tr, err := NewSampleTransform(SampleHash, "", 0.3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []event.Event
+	for i := 0; i < 1000; i++ {
+		for j := 0; j < 3; j++ {
+			events = append(events, tracedEvent(fmt.Sprintf("trace-%d", i), "info", nil))
+		}
+	}
+	res := runSample(t, tr, events)
+
+	perTrace := map[string]int{}
+	for _, e := range res {
+		perTrace[e.Attrs["trace_id"].(string)]++
+	}
+	for id, n := range perTrace {
+		if n != 3 {
+			t.Fatalf("trace %s partially kept (%d of 3)", id, n)
+		}
+	}
+	if frac := float64(len(perTrace)) / 1000; math.Abs(frac-0.3) > 0.06 {
+		t.Errorf("kept fraction = %.3f, want ~0.3", frac)
+	}
+	if res[0].Attrs["sample_rate"] != 3.333 {
+		t.Errorf("sample_rate tag = %v", res[0].Attrs["sample_rate"])
+	}
+}
+
+func TestSample_LevelRates(t *testing.T) {
+	tr, _ := NewSampleTransform(SampleLevel, "", 0, map[string]float64{"error": 1, "info": 0})
+
+	res := runSample(t, tr, []event.Event{
+		tracedEvent("a", "error", nil),
+		tracedEvent("b", "info", nil),
+		tracedEvent("c", "warn", nil),
+		tracedEvent("d", "ERROR", nil),
+	})
+	if len(res) != 3 {
+		t.Fatalf("kept %d events, want 3 (errors + unlisted warn)", len(res))
+	}
+	for _, e := range res {
+		if e.Level == "info" {
+			t.Error("info events should be dropped at rate 0")
+		}
+	}
+}
+
+func TestSample_TailKeepsErroredTraces(t *testing.T) {
+	tr, _ := NewSampleTransform(SampleTail, "", 0, nil)
+	tr.LatencyThreshold = 500 * time.Millisecond
+
+	res := runSample(t, tr, []event.Event{
+		tracedEvent("ok", "info", map[string]any{"status": float64(200)}),
+		tracedEvent("bad", "info", map[string]any{"status": float64(200)}),
+		tracedEvent("ok", "info", map[string]any{"status": float64(200)}),
+		tracedEvent("bad", "error", map[string]any{"status": float64(503)}),
+		tracedEvent("slow", "info", map[string]any{"latency": "1.2s"}),
+		{Service: "api", Type: event.TypeLog, Message: "no trace"},
+	})
+
+	counts := map[any]int{}
+	for _, e := range res {
+		counts[e.Attrs["trace_id"]]++
+	}
+	if counts["bad"] != 2 || counts["slow"] != 1 {
+		t.Errorf("important traces should be kept whole: %v", counts)
+	}
+	if counts["ok"] != 0 {
+		t.Errorf("healthy trace should be dropped at rate 0: %v", counts)
+	}
+	if counts[nil] != 1 {
+		t.Errorf("untraced event should pass through: %v", counts)
+	}
+}
+
+func TestSample_Validation(t *testing.T) {
+	for _, tc := range []struct {
+		mode string
+		rate float64
+		lr   map[string]float64
+	}{
+		{"bogus", 0.5, nil},
+		{SampleHash, 1.5, nil},
+		{SampleHash, 0, nil},
+		{SampleLevel, 0.5, nil},
+		{SampleLevel, 0.5, map[string]float64{"info": 2}},
+	} {
+		if _, err := NewSampleTransform(tc.mode, "", tc.rate, tc.lr); err == nil {
+			t.Errorf("NewSampleTransform(%q, %v, %v): expected error", tc.mode, tc.rate, tc.lr)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+	"collector/internal/metrics"
+)
+
+// Overflow actions for ThrottleTransform.
+const (
+	OverflowDrop   = "drop"
+	OverflowSample = "sample"
+	OverflowRoute  = "route"
+)
+
+const throttleIdleTTL = time.Minute
+
+// ThrottleTransform rate-limits events with a token bucket per key. The key
+// is built from KeyFields (service and level by default). Events that find
+// the bucket empty are handled by the Overflow action:
+//
+//	drop   – discard the event
+//	sample – forward one of every SampleRate overflow events, tagged with
+//	         Attrs["sample_rate"], and discard the rest
+//	route  – send the event to Route instead of the main output
+type ThrottleTransform struct {
+	KeyFields  []string
+	Rate       float64 // tokens per second
+	Burst      int
+	Overflow   string
+	SampleRate int
+	Route      chan<- event.Event
+	Clock      clock.Clock // refills buckets and expires idle ones; nil means the wall clock
+
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	overflow int64
+}
+
+// NewThrottleTransform validates the limiter settings.
+func NewThrottleTransform(keyFields []string, rate float64, burst int, overflow string, sampleRate int) (*ThrottleTransform, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("throttle: rate must be positive")
+	}
+	if burst <= 0 {
+		burst = int(rate)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	if len(keyFields) == 0 {
+		keyFields = []string{"service", "level"}
+	}
+	switch overflow {
+	case "":
+		overflow = OverflowDrop
+	case OverflowDrop, OverflowRoute:
+	case OverflowSample:
+		if sampleRate <= 1 {
+			return nil, fmt.Errorf("throttle: sample overflow requires sample_rate > 1")
+		}
+	default:
+		return nil, fmt.Errorf("throttle: unknown overflow action %q", overflow)
+	}
+	return &ThrottleTransform{
+		KeyFields:  keyFields,
+		Rate:       rate,
+		Burst:      burst,
+		Overflow:   overflow,
+		SampleRate: sampleRate,
+	}, nil
+}
+
+func (t *ThrottleTransform) Run(ctx context.Context, in <-chan event.Event, out chan<- event.Event) error {
+	if t.Overflow == OverflowRoute && t.Route == nil {
+		return fmt.Errorf("throttle: route overflow has no destination")
+	}
+	t.buckets = make(map[string]*tokenBucket)
+	clk := clock.Or(t.Clock)
+
+	sweep := time.NewTicker(throttleIdleTTL)
+	defer sweep.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-sweep.C:
+			now := clk.Now()
+			for key, b := range t.buckets {
+				if now.Sub(b.last) > throttleIdleTTL {
+					delete(t.buckets, key)
+				}
+			}
+
+		case evt, ok := <-in:
+			if !ok {
+				return nil
+			}
+
+			dst := out
+			if !t.allow(&evt, clk.Now()) {
+				switch t.Overflow {
+				case OverflowRoute:
+					metrics.TransformDropped.WithLabelValues("throttle", "routed").Inc()
+					dst = t.Route
+				case OverflowSample:
+					b := t.buckets[t.key(&evt)]
+					if b.overflow%int64(t.SampleRate) != 1 {
+						metrics.TransformDropped.WithLabelValues("throttle", "sampled_out").Inc()
+						evt.Done()
+						continue
+					}
+					evt.Attrs = withAttr(evt.Attrs, "sample_rate", float64(t.SampleRate))
+				default:
+					metrics.TransformDropped.WithLabelValues("throttle", "rate_limited").Inc()
+					evt.Done()
+					continue
+				}
+			}
+
+			select {
+			case dst <- evt:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// allow refills the bucket for evt's key and tries to take one token.
+func (t *ThrottleTransform) allow(evt *event.Event, now time.Time) bool {
+	key := t.key(evt)
+	b, ok := t.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(t.Burst), last: now}
+		t.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * t.Rate
+	if b.tokens > float64(t.Burst) {
+		b.tokens = float64(t.Burst)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	b.overflow++
+	return false
+}
+
+func (t *ThrottleTransform) key(evt *event.Event) string {
+	parts := make([]string, len(t.KeyFields))
+	for i, f := range t.KeyFields {
+		if v, ok := fieldValue(evt, f); ok {
+			parts[i] = fieldString(v)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,108 @@
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"collector/internal/clock"
+	"collector/internal/event"
+)
+
+func runThrottle(t *testing.T, tr *ThrottleTransform, n int, svc string) (main, routed []event.Event) {
+	t.Helper()
+	in := make(chan event.Event, n)
+	out := make(chan event.Event, n)
+	route := make(chan event.Event, n)
+	for i := 0; i < n; i++ {
+		in <- event.Event{Service: svc, Level: "error", Type: event.TypeLog}
+	}
+	close(in)
+	tr.Route = route
+	if err := tr.Run(context.Background(), in, out); err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+	close(route)
+	for e := range out {
+		main = append(main, e)
+	}
+	for e := range route {
+		routed = append(routed, e)
+	}
+	return main, routed
+}
+
+func TestThrottle_Drop(t *testing.T) {
+	tr, err := NewThrottleTransform(nil, 0.001, 5, OverflowDrop, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main, routed := runThrottle(t, tr, 20, "api")
+	if len(main) != 5 || len(routed) != 0 {
+		t.Errorf("main=%d routed=%d, want 5/0", len(main), len(routed))
+	}
+}
+
+func TestThrottle_Sample(t *testing.T) {
+	tr, _ := NewThrottleTransform(nil, 0.001, 2, OverflowSample, 5)
+	main, _ := runThrottle(t, tr, 12, "api")
+	// 2 within burst, then overflow #1 and #6 are sampled.
+	if len(main) != 4 {
+		t.Fatalf("main=%d, want 4", len(main))
+	}
+	if main[2].Attrs["sample_rate"] != float64(5) {
+		t.Errorf("sampled event should carry sample_rate, got %v", main[2].Attrs)
+	}
+}
+
+func TestThrottle_Route(t *testing.T) {
+	tr, _ := NewThrottleTransform([]string{"service"}, 0.001, 3, OverflowRoute, 0)
+	main, routed := runThrottle(t, tr, 10, "api")
+	if len(main) != 3 || len(routed) != 7 {
+		t.Errorf("main=%d routed=%d, want 3/7", len(main), len(routed))
+	}
+}
+
+func TestThrottle_Validation(t *testing.T) {
+	if _, err := NewThrottleTransform(nil, 0, 1, "", 0); err == nil {
+		t.Error("zero rate should fail")
+	}
+	if _, err := NewThrottleTransform(nil, 1, 1, OverflowSample, 1); err == nil {
+		t.Error("sample without rate should fail")
+	}
+	if _, err := NewThrottleTransform(nil, 1, 1, "explode", 0); err == nil {
+		t.Error("unknown action should fail")
+	}
+}
+
+func TestThrottle_RefillFollowsClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tr, _ := NewThrottleTransform(nil, 1, 1, OverflowDrop, 0)
+	tr.Clock = clk
+
+	in := make(chan event.Event)
+	out := make(chan event.Event, 3)
+	done := make(chan error, 1)
+	go func() { done <- tr.Run(context.Background(), in, out) }()
+
+	in <- event.Event{Service: "api", Message: "first"}
+	dropped := make(chan struct{})
+	in <- event.Event{Service: "api", Message: "over the limit", Ack: func() { close(dropped) }}
+	<-dropped
+	clk.Advance(time.Second)
+	in <- event.Event{Service: "api", Message: "refilled"}
+	close(in)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+
+	var got []string
+	for e := range out {
+		got = append(got, e.Message)
+	}
+	if len(got) != 2 || got[1] != "refilled" {
+		t.Errorf("got %q, want first and refilled", got)
+	}
+}
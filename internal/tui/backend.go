@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"time"
+
+	"collector/internal/anomaly"
+	"collector/internal/graph"
+)
+
+// Backend is what the TUI watches: the call graph and the anomaly stream
+// of a collector, in this process or reached over the network.
+type Backend interface {
+	// Snapshot returns the current call graph.
+	Snapshot() graph.CallGraphSnapshot
+	// GraphEvents streams topology changes; nil if there are none.
+	GraphEvents() <-chan graph.GraphEvent
+	// AnomalyEvents streams fired anomalies; nil if there is no detector.
+	AnomalyEvents() <-chan anomaly.AnomalyEvent
+	// Mute extends the detector cooldown for an edge until the given
+	// time; a zero time lifts the mute.
+	Mute(edgeKey string, until time.Time) error
+}
+
+// Local returns a Backend over a graph and detector in this process.
+// Either may be nil.
+func Local(g *graph.CallGraph, det *anomaly.ZScoreDetector) Backend {
+	return &localBackend{graph: g, detector: det}
+}
+
+type localBackend struct {
+	graph    *graph.CallGraph
+	detector *anomaly.ZScoreDetector
+}
+
+func (b *localBackend) Snapshot() graph.CallGraphSnapshot {
+	if b.graph == nil {
+		return graph.CallGraphSnapshot{At: time.Now()}
+	}
+	return b.graph.Snapshot()
+}
+
+func (b *localBackend) GraphEvents() <-chan graph.GraphEvent {
+	if b.graph == nil {
+		return nil
+	}
+	return b.graph.Events()
+}
+
+func (b *localBackend) AnomalyEvents() <-chan anomaly.AnomalyEvent {
+	if b.detector == nil {
+		return nil
+	}
+	return b.detector.Events()
+}
+
+func (b *localBackend) Mute(edgeKey string, until time.Time) error {
+	if b.detector != nil {
+		b.detector.Mute(edgeKey, until)
+	}
+	return nil
+}
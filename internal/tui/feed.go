@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"collector/internal/event"
+)
+
+// maxEventBatch bounds how many queued events one EventMsg carries.
+const maxEventBatch = 256
+
+// EventFeed carries pipeline events to the TUI. Only events on the edge
+// focused in the event log are queued; when the TUI falls behind, the
+// oldest queued events are dropped so the pipeline never blocks on it.
+type EventFeed struct {
+	ch      chan *event.NormalizedEvent
+	mu      sync.Mutex // serialises drop-oldest against concurrent publishers
+	focus   atomic.Pointer[string]
+	dropped atomic.Int64
+}
+
+func NewEventFeed(size int) *EventFeed {
+	if size <= 0 {
+		size = maxEvents
+	}
+	return &EventFeed{ch: make(chan *event.NormalizedEvent, size)}
+}
+
+// SetFocus selects the edge ("src|dst|op") whose events are queued; ""
+// stops the feed.
+func (f *EventFeed) SetFocus(key string) {
+	f.focus.Store(&key)
+}
+
+// Focus returns the edge currently fed.
+func (f *EventFeed) Focus() string {
+	if p := f.focus.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// Publish queues ev if it is on the focused edge. It never blocks.
+func (f *EventFeed) Publish(ev *event.NormalizedEvent) {
+	focus := f.Focus()
+	if ev == nil || focus == "" || eventEdgeKey(ev) != focus {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for {
+		select {
+		case f.ch <- ev:
+			return
+		default:
+		}
+		select {
+		case <-f.ch:
+			f.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Dropped reports how many events were discarded because the TUI lagged.
+func (f *EventFeed) Dropped() int64 {
+	return f.dropped.Load()
+}
+
+// Events exposes the queue to Bubble Tea.
+func (f *EventFeed) Events() <-chan *event.NormalizedEvent {
+	return f.ch
+}
+
+func eventEdgeKey(ev *event.NormalizedEvent) string {
+	return fmt.Sprintf("%s|%s|%s", ev.SrcService, ev.DstService, ev.Operation)
+}
+
+// listenEventFeed waits for one event, then drains whatever else is
+// already queued into the same message.
+func listenEventFeed(f *EventFeed) tea.Cmd {
+	if f == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		batch := []*event.NormalizedEvent{<-f.ch}
+		for len(batch) < maxEventBatch {
+			select {
+			case ev := <-f.ch:
+				batch = append(batch, ev)
+			default:
+				return EventMsg{Events: batch}
+			}
+		}
+		return EventMsg{Events: batch}
+	}
+}
@@ -0,0 +1,281 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"collector/internal/graph"
+)
+
+// historyLen is how many samples each series keeps: one minute at the
+// default tick interval.
+const historyLen = 120
+
+// Sparkline column widths, per series, on Screens 1 and 2.
+const (
+	minSparkWidth = 6
+	maxSparkWidth = 20
+)
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+// series is the recent history of one service or edge, one value per tick.
+type series struct {
+	calls  []float64 // calls per second
+	errors []float64 // share of calls that failed, 0–1
+	p99    []float64 // milliseconds
+}
+
+func (s *series) add(calls, errRate, p99 float64) {
+	s.calls = appendSample(s.calls, calls)
+	s.errors = appendSample(s.errors, errRate)
+	s.p99 = appendSample(s.p99, p99)
+}
+
+func appendSample(vals []float64, v float64) []float64 {
+	vals = append(vals, v)
+	if len(vals) > historyLen {
+		vals = vals[len(vals)-historyLen:]
+	}
+	return vals
+}
+
+// History samples per-edge and per-service rates from successive graph
+// snapshots. Snapshots carry cumulative counters, so each sample is the
+// delta since the previous one. Services aggregate their outgoing edges,
+// like the columns on Screen 1.
+type History struct {
+	edges    map[string]*series
+	services map[string]*series
+
+	counts map[string][2]int64 // calls, errors per edge at lastAt
+	lastAt time.Time
+}
+
+func NewHistory() *History {
+	return &History{
+		edges:    make(map[string]*series),
+		services: make(map[string]*series),
+		counts:   make(map[string][2]int64),
+	}
+}
+
+// Record adds one sample per edge and service in snap. The first call
+// only sets the baseline. Series of edges and services no longer in the
+// snapshot are dropped.
+func (h *History) Record(snap graph.CallGraphSnapshot, at time.Time) {
+	elapsed := at.Sub(h.lastAt).Seconds()
+	baseline := h.lastAt.IsZero() || elapsed <= 0
+	prev := h.counts
+	h.counts = make(map[string][2]int64, len(snap.Edges))
+	h.lastAt = at
+
+	type totals struct {
+		calls, errors int64
+		p99           time.Duration
+	}
+	bySvc := make(map[string]*totals, len(snap.Nodes))
+	for _, name := range snap.Nodes {
+		bySvc[name] = &totals{}
+	}
+
+	live := make(map[string]bool, len(snap.Edges))
+	for _, e := range snap.Edges {
+		key := fmt.Sprintf("%s|%s|%s", e.Src, e.Dst, e.Operation)
+		live[key] = true
+		h.counts[key] = [2]int64{e.CallCount, e.ErrorCount}
+		if baseline {
+			continue
+		}
+		p := prev[key]
+		if e.CallCount < p[0] || e.ErrorCount < p[1] {
+			p = [2]int64{} // the edge expired and came back
+		}
+		calls, errs := e.CallCount-p[0], e.ErrorCount-p[1]
+
+		s, ok := h.edges[key]
+		if !ok {
+			s = &series{}
+			h.edges[key] = s
+		}
+		s.add(float64(calls)/elapsed, ratio(errs, calls), millis(e.LatencyP99))
+
+		t, ok := bySvc[e.Src]
+		if !ok {
+			t = &totals{}
+			bySvc[e.Src] = t
+		}
+		t.calls += calls
+		t.errors += errs
+		if e.LatencyP99 > t.p99 {
+			t.p99 = e.LatencyP99
+		}
+	}
+	for key := range h.edges {
+		if !live[key] {
+			delete(h.edges, key)
+		}
+	}
+	for name := range h.services {
+		if _, ok := bySvc[name]; !ok {
+			delete(h.services, name)
+		}
+	}
+	if baseline {
+		return
+	}
+	for name, t := range bySvc {
+		s, ok := h.services[name]
+		if !ok {
+			s = &series{}
+			h.services[name] = s
+		}
+		s.add(float64(t.calls)/elapsed, ratio(t.errors, t.calls), millis(t.p99))
+	}
+}
+
+// Edge returns the history of the edge "src|dst|op", or nil.
+func (h *History) Edge(key string) *series {
+	if h == nil {
+		return nil
+	}
+	return h.edges[key]
+}
+
+// Service returns the history of a service's outgoing calls, or nil.
+func (h *History) Service(name string) *series {
+	if h == nil {
+		return nil
+	}
+	return h.services[name]
+}
+
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// sparkWidth returns the width of each of the three sparkline columns
+// that fit after base columns on a line, or 0 when they do not fit.
+func sparkWidth(width, base int) int {
+	w := min(maxSparkWidth, (width-base)/3-1)
+	if w < minSparkWidth {
+		return 0
+	}
+	return w
+}
+
+// sparkline renders the last width values scaled to their maximum,
+// right-aligned so the newest sample is always in the last column.
+func sparkline(vals []float64, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if len(vals) > width {
+		vals = vals[len(vals)-width:]
+	}
+	peak := 0.0
+	for _, v := range vals {
+		if v > peak {
+			peak = v
+		}
+	}
+	var b strings.Builder
+	b.WriteString(strings.Repeat(" ", width-len(vals)))
+	for _, v := range vals {
+		i := 0
+		if peak > 0 {
+			i = int(v / peak * float64(len(sparkRunes)-1))
+		}
+		b.WriteRune(sparkRunes[min(max(i, 0), len(sparkRunes)-1)])
+	}
+	return b.String()
+}
+
+// sparkCells renders the calls/s, error rate and p99 sparklines of s
+// as fixed-width columns; a nil series renders blank.
+func sparkCells(s *series, width int) string {
+	if width == 0 {
+		return ""
+	}
+	if s == nil {
+		s = &series{}
+	}
+	errStyle := StyleOK
+	if n := len(s.errors); n > 0 {
+		switch e := s.errors[n-1]; {
+		case e >= 0.05:
+			errStyle = StyleError
+		case e >= 0.01:
+			errStyle = StyleWarn
+		}
+	}
+	return " " + StyleCycleBadge.Render(sparkline(s.calls, width)) +
+		" " + errStyle.Render(sparkline(s.errors, width)) +
+		" " + StyleWarn.Render(sparkline(s.p99, width))
+}
+
+// sparkHeader labels the three sparkline columns.
+func sparkHeader(width int) string {
+	if width == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" %-*s %-*s %-*s", width, "CALLS/S", width, "ERR", width, "P99")
+}
+
+// chart draws vals as a bar chart height rows tall and width columns
+// wide, with the peak and latest values in the caption.
+func chart(title string, vals []float64, width, height int, format func(float64) string, style lipgloss.Style) string {
+	if len(vals) > width {
+		vals = vals[len(vals)-width:]
+	}
+	peak, last := 0.0, 0.0
+	for _, v := range vals {
+		if v > peak {
+			peak = v
+		}
+	}
+	if len(vals) > 0 {
+		last = vals[len(vals)-1]
+	}
+
+	var b strings.Builder
+	b.WriteString(StyleBold.Render(title) +
+		StyleDim.Render(fmt.Sprintf("  now %s  max %s", format(last), format(peak))) + "\n")
+	pad := strings.Repeat(" ", width-len(vals))
+	for row := height - 1; row >= 0; row-- {
+		var bars strings.Builder
+		for _, v := range vals {
+			eighths := 0
+			if peak > 0 {
+				eighths = int(v / peak * float64(height*8))
+			}
+			switch level := eighths - row*8; {
+			case level >= 8:
+				bars.WriteRune('█')
+			case level > 0:
+				bars.WriteRune(sparkRunes[level-1])
+			default:
+				bars.WriteRune(' ')
+			}
+		}
+		b.WriteString(pad + style.Render(bars.String()))
+		if row > 0 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func formatRate(v float64) string    { return fmt.Sprintf("%.1f/s", v) }
+func formatPercent(v float64) string { return fmt.Sprintf("%.2f%%", v*100) }
+func formatMillis(v float64) string  { return fmt.Sprintf("%.0fms", v) }
@@ -18,14 +18,20 @@ import (
 const tickInterval = 500 * time.Millisecond
 
 type Model struct {
-	graph    *graph.CallGraph
-	detector *anomaly.ZScoreDetector
+	backend  Backend
+	feed     *EventFeed
+	acks     *anomaly.AckStore
 	cancel   context.CancelFunc
 
 	screen  Screen
 	screen1 Screen1
 	screen2 Screen2
 	screen3 Screen3
+	screen4 Screen4
+	screen5 Screen5
+
+	// dependencyFrom is the screen esc returns to from Screen 2.
+	dependencyFrom Screen
 
 	startTime      time.Time
 	totalEvents    int64
@@ -41,35 +47,64 @@ type Model struct {
 	spinner  spinner.Model
 
 	lastSnapshot graph.CallGraphSnapshot
+	history      *History
 }
 
-func New(g *graph.CallGraph, det *anomaly.ZScoreDetector, cancel context.CancelFunc) Model {
+// New builds the TUI over b; see Local for an in-process collector.
+func New(b Backend, cancel context.CancelFunc) Model {
 	sp := spinner.New()
 	sp.Spinner = spinner.Dot
 
+	history := NewHistory()
+	screen1, screen2 := NewScreen1(), NewScreen2()
+	screen1.SetHistory(history)
+	screen2.SetHistory(history)
+	acks, _ := anomaly.LoadAcks("") // in memory until WithAcks
+	screen5 := NewScreen5()
+	screen5.SetAcks(acks)
+
 	return Model{
-		graph:         g,
-		detector:      det,
+		backend:       b,
+		acks:          acks,
 		cancel:        cancel,
 		screen:        ScreenServiceList,
-		screen1:       NewScreen1(),
-		screen2:       NewScreen2(),
+		screen1:       screen1,
+		screen2:       screen2,
 		screen3:       NewScreen3(),
+		screen4:       NewScreen4(),
+		screen5:       screen5,
 		startTime:     time.Now(),
 		anomalyCounts: make(map[string]int),
 		newAlerts:     make(map[string]bool),
 		cycleEdges:    make(map[string]bool),
 		anomalyEdges:  make(map[string]bool),
 		spinner:       sp,
+		history:       history,
 	}
 }
 
+// WithEventFeed streams the events of the edge open in the event log
+// from the pipeline.
+func (m Model) WithEventFeed(f *EventFeed) Model {
+	m.feed = f
+	return m
+}
+
+// WithAcks persists acknowledged edges in store. Mutes already in it
+// should have been applied to the detector by the caller.
+func (m Model) WithAcks(store *anomaly.AckStore) Model {
+	m.acks = store
+	m.screen5.SetAcks(store)
+	return m
+}
+
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		tick(),
 		m.spinner.Tick,
-		listenGraphEvents(m.graph),
-		listenAnomalyEvents(m.detector),
+		listenGraphEvents(m.backend.GraphEvents()),
+		listenAnomalyEvents(m.backend.AnomalyEvents()),
+		listenEventFeed(m.feed),
 	)
 }
 
@@ -84,14 +119,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.screen1.SetSize(msg.Width, msg.Height)
 		m.screen2.SetSize(msg.Width, msg.Height)
 		m.screen3.SetSize(msg.Width, msg.Height)
+		m.screen4.SetSize(msg.Width, msg.Height)
+		m.screen5.SetSize(msg.Width, msg.Height)
 
 	case tea.KeyMsg:
+		if m.capturingInput() && msg.String() != "ctrl+c" {
+			switch m.screen {
+			case ScreenServiceList:
+				cmds = append(cmds, m.screen1.HandleKey(msg))
+			case ScreenEventLog:
+				m.screen3.HandleKey(msg)
+			case ScreenAnomalies:
+				cmds = append(cmds, m.screen5.HandleKey(msg))
+			}
+			return m, tea.Batch(cmds...)
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
 			m.cancel()
 			return m, tea.Quit
 		case "r":
-			snap := m.graph.Snapshot()
+			snap := m.backend.Snapshot()
 			m.applySnapshot(snap)
 		case "?":
 			m.showHelp = !m.showHelp
@@ -107,22 +155,45 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case ScreenServiceList:
 			switch msg.String() {
 			case "enter":
-				svc := m.screen1.SelectedService()
-				if svc != "" {
-					m.screen2.SetService(svc)
-					m.screen2.SetCycleEdges(m.cycleEdges)
-					m.screen2.SetAnomalyEdges(m.anomalyEdges)
-					m.screen2.Update(m.lastSnapshot)
-					m.screen = ScreenDependency
-				}
+				m.openDependency(m.screen1.SelectedService())
+			case "m":
+				m.screen4.SetCycleEdges(m.cycleEdges)
+				m.screen4.SetAnomalyEdges(m.anomalyEdges)
+				m.screen4.Update(m.lastSnapshot)
+				m.screen4.Select(m.screen1.SelectedService())
+				m.screen = ScreenTopology
+			case "a":
+				m.screen = ScreenAnomalies
 			default:
 				cmds = append(cmds, m.screen1.HandleKey(msg))
 			}
 
-		case ScreenDependency:
+		case ScreenTopology:
 			switch msg.String() {
 			case "esc":
 				m.screen = ScreenServiceList
+			case "enter":
+				m.openDependency(m.screen4.SelectedService())
+			default:
+				m.screen4.HandleKey(msg)
+			}
+
+		case ScreenAnomalies:
+			switch msg.String() {
+			case "esc":
+				m.screen = ScreenServiceList
+			case "enter":
+				if e := m.screen5.Selected(); e != nil {
+					m.openDependency(e.Src)
+				}
+			default:
+				cmds = append(cmds, m.screen5.HandleKey(msg))
+			}
+
+		case ScreenDependency:
+			switch msg.String() {
+			case "esc":
+				m.screen = m.dependencyFrom
 			case "enter":
 				edge := m.screen2.SelectedEdge()
 				if edge != nil {
@@ -132,6 +203,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						edge.Operation,
 						edge.Key,
 					)
+					m.setFeedFocus(edge.Key)
 					m.screen = ScreenEventLog
 				}
 			default:
@@ -141,6 +213,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case ScreenEventLog:
 			switch msg.String() {
 			case "esc":
+				m.setFeedFocus("")
 				m.screen = ScreenDependency
 			default:
 				m.screen3.HandleKey(msg)
@@ -148,9 +221,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case TickMsg:
-		snap := m.graph.Snapshot()
+		snap := m.backend.Snapshot()
+		m.history.Record(snap, time.Time(msg))
 		m.applySnapshot(snap)
 		m.screen1.ToggleBlink()
+		if m.feed != nil {
+			m.screen3.SetDropped(m.feed.Dropped())
+		}
 		cmds = append(cmds, tick())
 
 	case AnomalyMsg:
@@ -162,7 +239,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.newAlerts[parts[0]] = true
 		}
 		m.anomalyEdges[ev.EdgeKey] = true
-		cmds = append(cmds, listenAnomalyEvents(m.detector))
+		m.screen5.Add(ev)
+		cmds = append(cmds, listenAnomalyEvents(m.backend.AnomalyEvents()))
+
+	case MuteMsg:
+		m.applyMute(msg)
 
 	case GraphEventMsg:
 		gev := msg.Event
@@ -171,11 +252,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			key := fmt.Sprintf("%s|%s|%s", gev.Edge.Src, gev.Edge.Dst, gev.Edge.Operation)
 			m.cycleEdges[key] = true
 		}
-		cmds = append(cmds, listenGraphEvents(m.graph))
+		cmds = append(cmds, listenGraphEvents(m.backend.GraphEvents()))
 
 	case event.NormalizedEvent:
 		m.screen3.AddEvent(&msg)
 
+	case EventMsg:
+		for _, ev := range msg.Events {
+			m.screen3.AddEvent(ev)
+		}
+		cmds = append(cmds, listenEventFeed(m.feed))
+
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -185,11 +272,70 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// capturingInput reports whether the active screen is reading text or
+// has a pane open, in which case it receives keys before the global ones.
+func (m *Model) capturingInput() bool {
+	switch m.screen {
+	case ScreenServiceList:
+		return m.screen1.filterMode
+	case ScreenEventLog:
+		return m.screen3.Capturing()
+	case ScreenAnomalies:
+		return m.screen5.Capturing()
+	}
+	return false
+}
+
+// applyMute extends the detector's cooldown for the edge and, once the
+// backend has taken it, records the ack. A backend that cannot mute (a
+// remote collector) leaves the edge as it was.
+func (m *Model) applyMute(msg MuteMsg) {
+	if err := m.backend.Mute(msg.EdgeKey, msg.Until); err != nil {
+		m.screen5.SetNotice("mute not applied: " + err.Error())
+		return
+	}
+	now := time.Now()
+	var err error
+	if msg.Until.IsZero() {
+		err = m.acks.Unack(msg.EdgeKey, now)
+		m.screen5.SetNotice("unmuted " + msg.EdgeKey)
+	} else {
+		err = m.acks.Ack(msg.EdgeKey, msg.Until, now)
+		m.screen5.SetNotice(fmt.Sprintf("muted %s until %s", msg.EdgeKey, msg.Until.Format("15:04")))
+	}
+	if err != nil {
+		m.screen5.SetNotice("ack not saved: " + err.Error())
+	}
+}
+
+// openDependency shows Screen 2 for svc; esc there returns to the
+// current screen.
+func (m *Model) openDependency(svc string) {
+	if svc == "" {
+		return
+	}
+	m.screen2.SetService(svc)
+	m.screen2.SetCycleEdges(m.cycleEdges)
+	m.screen2.SetAnomalyEdges(m.anomalyEdges)
+	m.screen2.Update(m.lastSnapshot)
+	m.dependencyFrom = m.screen
+	m.screen = ScreenDependency
+}
+
+func (m *Model) setFeedFocus(key string) {
+	if m.feed != nil {
+		m.feed.SetFocus(key)
+	}
+}
+
 func (m *Model) applySnapshot(snap graph.CallGraphSnapshot) {
 	m.lastSnapshot = snap
 	m.screen1.Update(snap, m.anomalyCounts, m.newAlerts)
-	if m.screen == ScreenDependency {
+	switch m.screen {
+	case ScreenDependency:
 		m.screen2.Update(snap)
+	case ScreenTopology:
+		m.screen4.Update(snap)
 	}
 	m.newAlerts = make(map[string]bool)
 }
@@ -211,6 +357,10 @@ func (m Model) View() string {
 		content = m.screen2.View(m.serviceRisk(m.screen2.service))
 	case ScreenEventLog:
 		content = m.screen3.View()
+	case ScreenTopology:
+		content = m.screen4.View()
+	case ScreenAnomalies:
+		content = m.screen5.View()
 	}
 
 	statusBar := m.statusBar()
@@ -262,11 +412,15 @@ func (m Model) keyBar() string {
 	var keys string
 	switch m.screen {
 	case ScreenServiceList:
-		keys = "↑↓ navigate  enter select  / filter  s sort  r refresh  ? help  q quit"
+		keys = "↑↓ navigate  enter select  m map  a anomalies  / filter  s sort  r refresh  ? help  q quit"
 	case ScreenDependency:
-		keys = "↑↓ navigate  enter events  esc back  ? help  q quit"
+		keys = "↑↓ navigate  enter events  c chart  esc back  ? help  q quit"
 	case ScreenEventLog:
-		keys = "↑↓ navigate  enter details  a autoscroll  esc back  q quit"
+		keys = "↑↓ navigate  enter details  p pause  / search  l level  a autoscroll  esc back  q quit"
+	case ScreenTopology:
+		keys = "←↑↓→ move  enter dependencies  esc back  ? help  q quit"
+	case ScreenAnomalies:
+		keys = "↑↓ navigate  enter service  a ack/mute  u unmute  d duration  / service  f metric  esc back  q quit"
 	}
 	return StyleDim.Width(m.width).Render(keys)
 }
@@ -285,21 +439,41 @@ func (m Model) helpView() string {
 		"  ↓ / j         down\n" +
 		"  enter         open service detail\n" +
 		"  /             filter by name\n" +
-		"  s             cycle sort column\n\n" +
+		"  s             cycle sort column\n" +
+		"  m             open topology map\n" +
+		"  a             open anomaly timeline\n\n" +
 		StyleBold.Render("Screen 2 — Dependency View\n") +
 		"  ↑ / k         up\n" +
 		"  ↓ / j         down\n" +
 		"  enter         open event log\n" +
-		"  esc           back to service list\n\n" +
+		"  c             toggle history chart\n" +
+		"  esc           back to previous screen\n\n" +
 		StyleBold.Render("Screen 3 — Event Log\n") +
 		"  ↑ / k         up\n" +
 		"  ↓ / j         down\n" +
-		"  enter         show raw JSON\n" +
+		"  enter         show parsed and raw event\n" +
+		"  p / space     pause / resume the live tail\n" +
+		"  /             search within the buffer\n" +
+		"  l             cycle minimum level\n" +
 		"  a             toggle autoscroll\n" +
 		"  esc           back to dependency view\n\n" +
+		StyleBold.Render("Screen 4 — Topology Map\n") +
+		"  ← ↑ ↓ → / hjkl  move between services\n" +
+		"  enter         open service detail\n" +
+		"  esc           back to service list\n\n" +
+		StyleBold.Render("Screen 5 — Anomalies\n") +
+		"  ↑ / k, ↓ / j  move\n" +
+		"  enter         open the caller's dependencies\n" +
+		"  a             acknowledge: mute the edge\n" +
+		"  u             unmute the edge\n" +
+		"  d             cycle mute duration\n" +
+		"  /             filter by service\n" +
+		"  f             cycle metric filter\n" +
+		"  esc           back to service list\n\n" +
 		StyleBold.Render("Legend\n") +
 		"  ⚠             anomaly detected\n" +
 		"  ↺             part of a dependency cycle\n" +
+		"  ▁▃▇           last minute of calls/s, errors, p99\n" +
 		"  ●             new unseen alert (blinks)\n\n" +
 		StyleDim.Render("press any key to close")
 
@@ -341,19 +515,28 @@ func tick() tea.Cmd {
 	})
 }
 
-func listenGraphEvents(g *graph.CallGraph) tea.Cmd {
+func listenGraphEvents(ch <-chan graph.GraphEvent) tea.Cmd {
+	if ch == nil {
+		return nil
+	}
 	return func() tea.Msg {
-		ev := <-g.Events()
+		ev, ok := <-ch
+		if !ok {
+			return nil
+		}
 		return GraphEventMsg{Event: ev}
 	}
 }
 
-func listenAnomalyEvents(det *anomaly.ZScoreDetector) tea.Cmd {
-	if det == nil {
+func listenAnomalyEvents(ch <-chan anomaly.AnomalyEvent) tea.Cmd {
+	if ch == nil {
 		return nil
 	}
 	return func() tea.Msg {
-		ev := <-det.Events()
+		ev, ok := <-ch
+		if !ok {
+			return nil
+		}
 		return AnomalyMsg{Event: ev}
 	}
 }
@@ -26,6 +26,9 @@ type ServiceRow struct {
 	HasNewAlert bool
 }
 
+// screen1RowWidth is the width of a Screen 1 row before the sparklines.
+const screen1RowWidth = 88
+
 // Screen1 is the main service list view.
 type Screen1 struct {
 	rows        []ServiceRow
@@ -36,6 +39,7 @@ type Screen1 struct {
 	filterMode  bool
 	filterInput textinput.Model
 	blinkOn     bool
+	history     *History
 	width       int
 	height      int
 }
@@ -54,6 +58,11 @@ func (s *Screen1) SetSize(w, h int) {
 	s.height = h
 }
 
+// SetHistory supplies the samples drawn as sparklines next to each row.
+func (s *Screen1) SetHistory(h *History) {
+	s.history = h
+}
+
 // Update rebuilds rows from a graph snapshot and anomaly counts.
 func (s *Screen1) Update(snap graph.CallGraphSnapshot, anomalyCounts map[string]int, newAlerts map[string]bool) {
 	inMap := make(map[string][]graph.Edge)
@@ -206,7 +215,8 @@ func (s *Screen1) View(width int) string {
 
 	// Column headers
 	colHeaders := fmt.Sprintf("  %-22s %8s %8s %10s %12s %10s %10s",
-		"SERVICE", "IN", "OUT", "ANOMALIES", "AVG LAT", "ERR RATE", "RISK")
+		"SERVICE", "IN", "OUT", "ANOMALIES", "AVG LAT", "ERR RATE", "RISK") +
+		sparkHeader(sparkWidth(width, screen1RowWidth))
 	b.WriteString(StyleHeader.Width(width).Render(colHeaders) + "\n")
 
 	// Rows
@@ -249,7 +259,7 @@ func (s *Screen1) renderRow(r ServiceRow, selected bool, width int) string {
 		formatDuration(r.AvgLatency),
 		errStr,
 		riskStr,
-	)
+	) + sparkCells(s.history.Service(r.Name), sparkWidth(width, screen1RowWidth))
 
 	if selected {
 		return StyleSelected.Width(width).Render(line)
@@ -282,10 +292,11 @@ func sortColName(c SortColumn) string {
 }
 
 func truncateName(s string, n int) string {
-	if len(s) <= n {
+	r := []rune(s)
+	if len(r) <= n {
 		return s
 	}
-	return s[:n-1] + "…"
+	return string(r[:n-1]) + "…"
 }
 
 func formatDuration(d time.Duration) string {
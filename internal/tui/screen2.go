@@ -25,6 +25,12 @@ type EdgeRow struct {
 	IsCycle    bool
 }
 
+// screen2RowWidth is the width of a Screen 2 row before the sparklines.
+const screen2RowWidth = 97
+
+// chartHeight is the height in rows of the charts in the detail panel.
+const chartHeight = 5
+
 // Screen2 shows the dependency detail for one service.
 type Screen2 struct {
 	service      string
@@ -34,6 +40,8 @@ type Screen2 struct {
 	cursor       int
 	cycleEdges   map[string]bool
 	anomalyEdges map[string]bool
+	history      *History
+	showChart    bool
 	width        int
 	height       int
 }
@@ -42,6 +50,7 @@ func NewScreen2() Screen2 {
 	return Screen2{
 		cycleEdges:   make(map[string]bool),
 		anomalyEdges: make(map[string]bool),
+		showChart:    true,
 	}
 }
 
@@ -63,6 +72,11 @@ func (s *Screen2) SetAnomalyEdges(keys map[string]bool) {
 	s.anomalyEdges = keys
 }
 
+// SetHistory supplies the samples drawn as sparklines and charts.
+func (s *Screen2) SetHistory(h *History) {
+	s.history = h
+}
+
 func (s *Screen2) Update(snap graph.CallGraphSnapshot) {
 	s.upstream = nil
 	s.downstream = nil
@@ -107,6 +121,8 @@ func (s *Screen2) HandleKey(msg tea.KeyMsg) {
 		if s.cursor < len(s.allRows)-1 {
 			s.cursor++
 		}
+	case "c":
+		s.showChart = !s.showChart
 	}
 }
 
@@ -161,12 +177,40 @@ func (s *Screen2) View(riskScore float64) string {
 		}
 	}
 
+	if s.showChart {
+		if edge := s.SelectedEdge(); edge != nil {
+			b.WriteString("\n" + s.renderChartPanel(edge))
+		}
+	}
+
 	return b.String()
 }
 
+// renderChartPanel charts the history of the selected edge.
+func (s *Screen2) renderChartPanel(edge *EdgeRow) string {
+	hist := s.history.Edge(edge.Key)
+	if hist == nil {
+		hist = &series{}
+	}
+	width := min(historyLen, max(10, (s.width-10)/3))
+	op := edge.Operation
+	if op == "" {
+		op = "—"
+	}
+	title := StyleBold.Render(fmt.Sprintf("▸ %s %s", edge.Peer, op)) +
+		StyleDim.Render(fmt.Sprintf("  (last %d samples)", len(hist.calls)))
+	charts := lipgloss.JoinHorizontal(lipgloss.Top,
+		chart("calls/s", hist.calls, width, chartHeight, formatRate, StyleCycleBadge), "   ",
+		chart("errors", hist.errors, width, chartHeight, formatPercent, StyleError), "   ",
+		chart("p99", hist.p99, width, chartHeight, formatMillis, StyleWarn),
+	)
+	return title + "\n" + charts
+}
+
 func (s *Screen2) renderEdgeHeader() string {
 	return StyleDim.Render(fmt.Sprintf("  %-20s %-30s %10s %10s %10s %10s",
-		"PEER", "OPERATION", "AVG LAT", "P99 LAT", "ERR%", "CALLS/MIN"))
+		"PEER", "OPERATION", "AVG LAT", "P99 LAT", "ERR%", "CALLS/MIN") +
+		sparkHeader(sparkWidth(s.width, screen2RowWidth)))
 }
 
 func (s *Screen2) renderEdgeRow(row EdgeRow, selected bool) string {
@@ -189,13 +233,14 @@ func (s *Screen2) renderEdgeRow(row EdgeRow, selected bool) string {
 	errStr := fmt.Sprintf("%.2f%%", row.ErrorRate*100)
 	callsMin := callsPerMin(row.CallCount)
 
-	line := fmt.Sprintf("  %-20s %-30s %10s %10s %10s %10s %s",
+	line := fmt.Sprintf("  %-20s %-30s %10s %10s %10s %10s%s %s",
 		truncateName(row.Peer, 20),
 		op,
 		formatDuration(row.AvgLatency),
 		formatDuration(row.P99Latency),
 		errStr,
 		callsMin,
+		sparkCells(s.history.Edge(row.Key), sparkWidth(s.width, screen2RowWidth)),
 		badges,
 	)
 
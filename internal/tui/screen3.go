@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"collector/internal/event"
@@ -13,13 +14,26 @@ import (
 
 const maxEvents = 100
 
+// levelFilters are the minimum levels cycled through with "l".
+var levelFilters = []string{"", "debug", "info", "warn", "error"}
+
+var levelRank = map[string]int{
+	"trace": 0, "debug": 0,
+	"info": 1, "notice": 1,
+	"warn": 2, "warning": 2,
+	"error": 3, "err": 3,
+	"fatal": 4, "critical": 4, "crit": 4, "panic": 4,
+}
+
 // EventEntry wraps a NormalizedEvent for display.
 type EventEntry struct {
 	Event      *event.NormalizedEvent
 	ReceivedAt time.Time
+
+	text string // lower-cased fields and raw JSON, for search
 }
 
-// Screen3 shows the event log for a selected edge.
+// Screen3 shows the live event log for a selected edge.
 type Screen3 struct {
 	edgeKey    string
 	srcService string
@@ -27,17 +41,29 @@ type Screen3 struct {
 	operation  string
 
 	events     []EventEntry
-	cursor     int
+	shown      []int // indices into events passing the level and search filters
+	cursor     int   // index into shown
 	autoScroll bool
 	showPopup  bool
 	popupJSON  string
+	popupTop   int
+
+	paused   bool
+	pending  []EventEntry // received while paused
+	minLevel int          // index into levelFilters
+	search   textinput.Model
+	typing   bool
+	dropped  int64
 
 	width  int
 	height int
 }
 
 func NewScreen3() Screen3 {
-	return Screen3{autoScroll: true}
+	ti := textinput.New()
+	ti.Placeholder = "search events..."
+	ti.CharLimit = 64
+	return Screen3{autoScroll: true, search: ti}
 }
 
 func (s *Screen3) SetSize(w, h int) {
@@ -51,32 +77,102 @@ func (s *Screen3) SetEdge(src, dst, op, key string) {
 	s.operation = op
 	s.edgeKey = key
 	s.events = nil
+	s.shown = nil
+	s.pending = nil
+	s.paused = false
 	s.cursor = 0
 	s.showPopup = false
 }
 
-// AddEvent appends a new event if it matches the current edge.
+// SetDropped records how many events the feed discarded, for display.
+func (s *Screen3) SetDropped(n int64) {
+	s.dropped = n
+}
+
+// Capturing reports whether the screen wants every key, including the
+// ones the model would otherwise handle (esc, q).
+func (s *Screen3) Capturing() bool {
+	return s.typing || s.showPopup
+}
+
+// AddEvent appends a new event if it matches the current edge. While the
+// tail is paused events are held back and shown on resume.
 func (s *Screen3) AddEvent(ev *event.NormalizedEvent) {
-	if ev == nil {
+	if ev == nil || eventEdgeKey(ev) != s.edgeKey {
 		return
 	}
-	key := fmt.Sprintf("%s|%s|%s", ev.SrcService, ev.DstService, ev.Operation)
-	if key != s.edgeKey {
+	entry := EventEntry{Event: ev, ReceivedAt: time.Now(), text: searchText(ev)}
+	if s.paused {
+		s.pending = appendBounded(s.pending, entry)
 		return
 	}
+	s.events = appendBounded(s.events, entry)
+	s.refilter()
+}
+
+func appendBounded(entries []EventEntry, e EventEntry) []EventEntry {
+	entries = append(entries, e)
+	if len(entries) > maxEvents {
+		entries = entries[len(entries)-maxEvents:]
+	}
+	return entries
+}
 
-	s.events = append(s.events, EventEntry{Event: ev, ReceivedAt: time.Now()})
-	if len(s.events) > maxEvents {
-		s.events = s.events[len(s.events)-maxEvents:]
+// refilter rebuilds the visible rows after the buffer or a filter changed.
+func (s *Screen3) refilter() {
+	query := strings.ToLower(strings.TrimSpace(s.search.Value()))
+	minRank := -1
+	if lvl := levelFilters[s.minLevel]; lvl != "" {
+		minRank = levelRank[lvl]
 	}
+
+	s.shown = s.shown[:0]
+	for i, e := range s.events {
+		if levelRank[eventLevel(e.Event)] < minRank {
+			continue
+		}
+		if query != "" && !strings.Contains(e.text, query) {
+			continue
+		}
+		s.shown = append(s.shown, i)
+	}
+
 	if s.autoScroll {
-		s.cursor = len(s.events) - 1
+		s.cursor = max(0, len(s.shown)-1)
+	} else if s.cursor >= len(s.shown) {
+		s.cursor = max(0, len(s.shown)-1)
 	}
 }
 
 func (s *Screen3) HandleKey(msg tea.KeyMsg) {
+	if s.typing {
+		switch msg.String() {
+		case "enter":
+			s.typing = false
+			s.search.Blur()
+		case "esc":
+			s.typing = false
+			s.search.Blur()
+			s.search.SetValue("")
+		default:
+			s.search, _ = s.search.Update(msg)
+		}
+		s.refilter()
+		return
+	}
 	if s.showPopup {
-		s.showPopup = false
+		switch msg.String() {
+		case "up", "k":
+			if s.popupTop > 0 {
+				s.popupTop--
+			}
+		case "down", "j":
+			if s.popupTop < strings.Count(s.popupJSON, "\n") {
+				s.popupTop++
+			}
+		default:
+			s.showPopup = false
+		}
 		return
 	}
 	switch msg.String() {
@@ -86,22 +182,45 @@ func (s *Screen3) HandleKey(msg tea.KeyMsg) {
 			s.autoScroll = false
 		}
 	case "down", "j":
-		if s.cursor < len(s.events)-1 {
+		if s.cursor < len(s.shown)-1 {
 			s.cursor++
 		}
 	case "a":
 		s.autoScroll = !s.autoScroll
-		if s.autoScroll && len(s.events) > 0 {
-			s.cursor = len(s.events) - 1
+		if s.autoScroll && len(s.shown) > 0 {
+			s.cursor = len(s.shown) - 1
+		}
+	case "p", " ":
+		s.paused = !s.paused
+		if !s.paused {
+			for _, e := range s.pending {
+				s.events = appendBounded(s.events, e)
+			}
+			s.pending = nil
+			s.refilter()
 		}
+	case "l":
+		s.minLevel = (s.minLevel + 1) % len(levelFilters)
+		s.refilter()
+	case "/":
+		s.typing = true
+		s.search.Focus()
 	case "enter":
-		if len(s.events) > 0 && s.cursor < len(s.events) {
+		if e := s.selected(); e != nil {
 			s.showPopup = true
-			s.popupJSON = formatRawJSON(s.events[s.cursor].Event)
+			s.popupTop = 0
+			s.popupJSON = formatRawJSON(e.Event)
 		}
 	}
 }
 
+func (s *Screen3) selected() *EventEntry {
+	if s.cursor < 0 || s.cursor >= len(s.shown) {
+		return nil
+	}
+	return &s.events[s.shown[s.cursor]]
+}
+
 func (s *Screen3) View() string {
 	var b strings.Builder
 
@@ -113,43 +232,48 @@ func (s *Screen3) View() string {
 	title := StyleTitle.Render(fmt.Sprintf("⬡ Edge: %s → %s  |  op: %s",
 		s.srcService, s.dstService, op))
 	b.WriteString(title + "\n")
-
-	autoStr := StyleDim.Render("autoscroll: ")
-	if s.autoScroll {
-		autoStr += StyleOK.Render("ON [a]")
+	b.WriteString(s.controlsLine() + "\n")
+	if s.typing || s.search.Value() != "" {
+		b.WriteString("  " + s.search.View() + "\n")
 	} else {
-		autoStr += StyleWarn.Render("OFF [a]")
+		b.WriteString("\n")
 	}
-	b.WriteString(autoStr + "\n\n")
 
 	// Column headers
-	b.WriteString(StyleDim.Render(fmt.Sprintf("  %-24s %10s %8s %-18s %s",
-		"TIMESTAMP", "LATENCY", "STATUS", "TRACE ID", "SOURCE")) + "\n")
+	b.WriteString(StyleDim.Render(fmt.Sprintf("  %-24s %-6s %10s %8s %-18s %s",
+		"TIMESTAMP", "LEVEL", "LATENCY", "STATUS", "TRACE ID", "SOURCE")) + "\n")
 
 	// Events list
 	visible := s.visibleRows()
 	start := max(0, s.cursor-visible+1)
 	if s.autoScroll {
-		start = max(0, len(s.events)-visible)
+		start = max(0, len(s.shown)-visible)
 	}
-	end := min(len(s.events), start+visible)
+	end := min(len(s.shown), start+visible)
 
-	if len(s.events) == 0 {
+	switch {
+	case len(s.events) == 0:
 		b.WriteString(StyleDim.Render("  — waiting for events —") + "\n")
+	case len(s.shown) == 0:
+		b.WriteString(StyleDim.Render("  — no events match the filters —") + "\n")
 	}
 
 	for i := start; i < end; i++ {
-		entry := s.events[i]
+		entry := s.events[s.shown[i]]
 		line := s.renderEventRow(entry, i == s.cursor)
 		b.WriteString(line + "\n")
 	}
 
-	// Popup overlay
+	// Detail pane
 	if s.showPopup {
+		lines := strings.Split(s.popupJSON, "\n")
+		height := max(5, s.height/2)
+		top := min(s.popupTop, max(0, len(lines)-height))
+		lines = lines[top:min(len(lines), top+height)]
 		popup := StylePopup.Render(
-			StyleBold.Render("Raw Event JSON") + "\n\n" +
-				s.popupJSON + "\n\n" +
-				StyleDim.Render("press any key to close"),
+			StyleBold.Render("Event Detail") + "\n\n" +
+				strings.Join(lines, "\n") + "\n\n" +
+				StyleDim.Render("↑↓ scroll  any other key closes"),
 		)
 		b.WriteString("\n" + popup)
 	}
@@ -157,6 +281,35 @@ func (s *Screen3) View() string {
 	return b.String()
 }
 
+func (s *Screen3) controlsLine() string {
+	sep := StyleDim.Render("  |  ")
+	parts := []string{StyleDim.Render("autoscroll: ")}
+	if s.autoScroll {
+		parts[0] += StyleOK.Render("ON [a]")
+	} else {
+		parts[0] += StyleWarn.Render("OFF [a]")
+	}
+	if s.paused {
+		parts = append(parts, StyleWarn.Render(fmt.Sprintf("⏸ paused, %d new [p]", len(s.pending))))
+	} else {
+		parts = append(parts, StyleOK.Render("▶ live [p]"))
+	}
+	lvl := levelFilters[s.minLevel]
+	if lvl == "" {
+		lvl = "all"
+	} else {
+		lvl += "+"
+	}
+	parts = append(parts,
+		StyleDim.Render("level: ")+lvl+StyleDim.Render(" [l]"),
+		StyleDim.Render(fmt.Sprintf("showing %d/%d", len(s.shown), len(s.events))),
+	)
+	if s.dropped > 0 {
+		parts = append(parts, StyleWarn.Render(fmt.Sprintf("dropped: %d", s.dropped)))
+	}
+	return strings.Join(parts, sep)
+}
+
 func (s *Screen3) renderEventRow(entry EventEntry, selected bool) string {
 	ev := entry.Event
 	ts := ev.Timestamp.Format("2006-01-02 15:04:05.000")
@@ -175,8 +328,8 @@ func (s *Screen3) renderEventRow(entry EventEntry, selected bool) string {
 		traceID = traceID[:16]
 	}
 
-	line := fmt.Sprintf("  %-24s %10s %8s %-18s %s",
-		ts, latency, statusStr, traceID, ev.SourceName)
+	line := fmt.Sprintf("  %-24s %-6s %10s %8s %-18s %s",
+		ts, eventLevel(ev), latency, statusStr, traceID, ev.SourceName)
 
 	style := StatusStyle(ev.StatusCode)
 	line = style.Render(line)
@@ -189,39 +342,69 @@ func (s *Screen3) renderEventRow(entry EventEntry, selected bool) string {
 
 func (s *Screen3) visibleRows() int {
 	rows := s.height - 10
+	if s.showPopup {
+		rows = s.height/2 - 10
+	}
 	if rows < 5 {
 		rows = 5
 	}
 	return rows
 }
 
+// eventLevel returns the event's level, deriving one from the status code
+// when the source did not log any.
+func eventLevel(ev *event.NormalizedEvent) string {
+	if ev.Level != "" {
+		return strings.ToLower(ev.Level)
+	}
+	switch {
+	case ev.StatusCode >= 500:
+		return "error"
+	case ev.StatusCode >= 400:
+		return "warn"
+	}
+	return "info"
+}
+
+func searchText(ev *event.NormalizedEvent) string {
+	raw, _ := json.Marshal(ev.Raw)
+	return strings.ToLower(strings.Join([]string{
+		ev.SrcService, ev.DstService, ev.Operation, ev.TraceID, ev.SpanID,
+		ev.Level, ev.Format, ev.SourceName, fmt.Sprint(ev.StatusCode), string(raw),
+	}, " "))
+}
+
+// formatRawJSON renders the parsed fields followed by the raw event.
 func formatRawJSON(ev *event.NormalizedEvent) string {
 	if ev == nil {
 		return "{}"
 	}
-	// Build a display map
-	m := map[string]any{
-		"timestamp":   ev.Timestamp.Format(time.RFC3339Nano),
-		"src_service": ev.SrcService,
-		"dst_service": ev.DstService,
-		"operation":   ev.Operation,
-		"status_code": ev.StatusCode,
-		"latency_ms":  ev.Latency.Milliseconds(),
-		"trace_id":    ev.TraceID,
-		"span_id":     ev.SpanID,
-		"level":       ev.Level,
-		"source_name": ev.SourceName,
-		"raw":         ev.Raw,
-	}
-	b, err := json.MarshalIndent(m, "", "  ")
-	if err != nil {
-		return "{}"
+	var b strings.Builder
+	b.WriteString(StyleBold.Render("Parsed") + "\n")
+	for _, f := range []struct{ name, value string }{
+		{"timestamp", ev.Timestamp.Format(time.RFC3339Nano)},
+		{"src_service", ev.SrcService},
+		{"dst_service", ev.DstService},
+		{"operation", ev.Operation},
+		{"status_code", fmt.Sprint(ev.StatusCode)},
+		{"latency", ev.Latency.String()},
+		{"trace_id", ev.TraceID},
+		{"span_id", ev.SpanID},
+		{"level", ev.Level},
+		{"format", ev.Format},
+		{"source_name", ev.SourceName},
+	} {
+		if f.value == "" {
+			f.value = "—"
+		}
+		fmt.Fprintf(&b, "  %-12s %s\n", f.name, f.value)
 	}
-	result := string(b)
-	// Truncate for popup display
-	lines := strings.Split(result, "\n")
-	if len(lines) > 30 {
-		lines = append(lines[:30], "  ... (truncated)")
+
+	b.WriteString("\n" + StyleBold.Render("Raw") + "\n")
+	raw, err := json.MarshalIndent(ev.Raw, "", "  ")
+	if err != nil {
+		raw = []byte("{}")
 	}
-	return strings.Join(lines, "\n")
+	b.Write(raw)
+	return b.String()
 }
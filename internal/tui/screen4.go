@@ -0,0 +1,735 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"collector/internal/graph"
+)
+
+// Map geometry: every node slot is a three-row box plus one blank row.
+const (
+	mapSlotHeight = 4
+	mapMaxLabel   = 20
+)
+
+// mapStyle is the style of one canvas cell. Edge styles are ordered by
+// precedence: where edges overlap, the more severe one wins.
+type mapStyle uint8
+
+const (
+	mapPlain mapStyle = iota
+	mapEdgeOK
+	mapEdgeWarn
+	mapEdgeError
+	mapEdgeCycle
+	mapEdgeAnomaly
+	mapBox
+	mapBoxSelected
+)
+
+func (s mapStyle) style() lipgloss.Style {
+	switch s {
+	case mapEdgeOK:
+		return StyleOK
+	case mapEdgeWarn:
+		return StyleWarn
+	case mapEdgeError, mapEdgeAnomaly:
+		return StyleError.Bold(s == mapEdgeAnomaly)
+	case mapEdgeCycle:
+		return StyleCycleBadge
+	case mapBox:
+		return StyleBold
+	case mapBoxSelected:
+		return StyleSelected
+	}
+	return lipgloss.NewStyle()
+}
+
+// Line directions leaving a cell; combined they pick the junction rune.
+const (
+	linkUp uint8 = 1 << iota
+	linkDown
+	linkLeft
+	linkRight
+)
+
+var linkRunes = map[uint8]rune{
+	linkLeft: '─', linkRight: '─', linkLeft | linkRight: '─',
+	linkUp: '│', linkDown: '│', linkUp | linkDown: '│',
+	linkDown | linkRight:                     '┌',
+	linkDown | linkLeft:                      '┐',
+	linkUp | linkRight:                       '└',
+	linkUp | linkLeft:                        '┘',
+	linkUp | linkDown | linkRight:            '├',
+	linkUp | linkDown | linkLeft:             '┤',
+	linkLeft | linkRight | linkDown:          '┬',
+	linkLeft | linkRight | linkUp:            '┴',
+	linkUp | linkDown | linkLeft | linkRight: '┼',
+}
+
+// mapCanvas is a grid of runes and styles the map is drawn on before it
+// is cropped to the terminal and rendered.
+type mapCanvas struct {
+	w, h  int
+	runes [][]rune // explicit runes; zero cells are drawn from links
+	links [][]uint8
+	style [][]mapStyle
+}
+
+func newMapCanvas(w, h int) *mapCanvas {
+	c := &mapCanvas{w: w, h: h}
+	c.runes = make([][]rune, h)
+	c.links = make([][]uint8, h)
+	c.style = make([][]mapStyle, h)
+	for y := 0; y < h; y++ {
+		c.runes[y] = make([]rune, w)
+		c.links[y] = make([]uint8, w)
+		c.style[y] = make([]mapStyle, w)
+	}
+	return c
+}
+
+func (c *mapCanvas) paint(x, y int, st mapStyle) bool {
+	if x < 0 || y < 0 || x >= c.w || y >= c.h {
+		return false
+	}
+	if st > c.style[y][x] {
+		c.style[y][x] = st
+	}
+	return true
+}
+
+func (c *mapCanvas) text(x, y int, s string, st mapStyle) {
+	for _, r := range s {
+		if c.paint(x, y, st) {
+			c.runes[y][x] = r
+		}
+		x++
+	}
+}
+
+func (c *mapCanvas) hline(y, x1, x2 int, st mapStyle) {
+	x1, x2 = min(x1, x2), max(x1, x2)
+	for x := x1; x <= x2; x++ {
+		if !c.paint(x, y, st) {
+			continue
+		}
+		if x > x1 {
+			c.links[y][x] |= linkLeft
+		}
+		if x < x2 {
+			c.links[y][x] |= linkRight
+		}
+		if x1 == x2 {
+			c.links[y][x] |= linkLeft | linkRight
+		}
+	}
+}
+
+func (c *mapCanvas) vline(x, y1, y2 int, st mapStyle) {
+	y1, y2 = min(y1, y2), max(y1, y2)
+	if y1 == y2 {
+		return
+	}
+	for y := y1; y <= y2; y++ {
+		if !c.paint(x, y, st) {
+			continue
+		}
+		if y > y1 {
+			c.links[y][x] |= linkUp
+		}
+		if y < y2 {
+			c.links[y][x] |= linkDown
+		}
+	}
+}
+
+func (c *mapCanvas) box(x, y, w int, label string, st mapStyle) {
+	c.text(x, y, "╭"+strings.Repeat("─", w-2)+"╮", st)
+	pad := w - 3 - lipgloss.Width(label)
+	c.text(x, y+1, "│ "+label+strings.Repeat(" ", max(0, pad))+"│", st)
+	c.text(x, y+2, "╰"+strings.Repeat("─", w-2)+"╯", st)
+}
+
+// render crops the canvas to the given window and styles runs of cells.
+func (c *mapCanvas) render(x0, y0, w, h int) string {
+	lines := make([]string, 0, h)
+	for y := y0; y < min(c.h, y0+h); y++ {
+		var line, run strings.Builder
+		cur := mapPlain
+		flush := func() {
+			if run.Len() == 0 {
+				return
+			}
+			if cur == mapPlain {
+				line.WriteString(run.String())
+			} else {
+				line.WriteString(cur.style().Render(run.String()))
+			}
+			run.Reset()
+		}
+		for x := x0; x < min(c.w, x0+w); x++ {
+			r := c.runes[y][x]
+			if r == 0 {
+				if r = linkRunes[c.links[y][x]]; r == 0 {
+					r = ' '
+				}
+			}
+			if st := c.style[y][x]; st != cur {
+				flush()
+				cur = st
+			}
+			run.WriteRune(r)
+		}
+		flush()
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mapNode is a service box, or a dummy point that carries an edge through
+// a layer it spans.
+type mapNode struct {
+	name   string
+	dummy  bool
+	cyclic bool
+	layer  int
+	slot   int
+	bary   float64
+	x, y   int
+}
+
+func (n *mapNode) label() string {
+	l := truncateName(n.name, mapMaxLabel)
+	if n.cyclic {
+		l += " ↺"
+	}
+	return l
+}
+
+// mapLink aggregates every operation between one pair of services.
+type mapLink struct {
+	src, dst string
+	calls    int64
+	errors   int64
+	anomaly  bool
+	cycle    bool
+	reversed bool // drawn right to left to break a cycle
+}
+
+func (l *mapLink) style() mapStyle {
+	rate := 0.0
+	if l.calls > 0 {
+		rate = float64(l.errors) / float64(l.calls)
+	}
+	switch {
+	case l.anomaly:
+		return mapEdgeAnomaly
+	case l.cycle:
+		return mapEdgeCycle
+	case rate >= 0.05:
+		return mapEdgeError
+	case rate >= 0.01:
+		return mapEdgeWarn
+	}
+	return mapEdgeOK
+}
+
+// mapSegment is one hop of a link between adjacent layers.
+type mapSegment struct {
+	from, to *mapNode
+	link     *mapLink
+	head     bool // arrow into to
+	tail     bool // arrow into from, for reversed links
+}
+
+// topoLayout places a snapshot on a layered left-to-right grid: cycles
+// are broken by reversing DFS back edges, nodes take the longest path
+// from a root as their layer, and links spanning several layers are
+// routed through dummy nodes.
+type topoLayout struct {
+	layers   [][]*mapNode
+	nodes    map[string]*mapNode
+	links    []*mapLink
+	segments []mapSegment
+	colX     []int
+	colW     []int
+	lanes    []map[*mapNode]int
+	width    int
+	height   int
+}
+
+func buildTopology(snap graph.CallGraphSnapshot, cycleEdges, anomalyEdges map[string]bool) *topoLayout {
+	t := &topoLayout{nodes: make(map[string]*mapNode)}
+	node := func(name string) *mapNode {
+		n, ok := t.nodes[name]
+		if !ok {
+			n = &mapNode{name: name}
+			t.nodes[name] = n
+		}
+		return n
+	}
+	for _, name := range snap.Nodes {
+		node(name)
+	}
+
+	pairs := make(map[[2]string]*mapLink)
+	for _, e := range snap.Edges {
+		src, dst := node(e.Src), node(e.Dst)
+		key := fmt.Sprintf("%s|%s|%s", e.Src, e.Dst, e.Operation)
+		if src == dst {
+			src.cyclic = true
+			continue
+		}
+		l, ok := pairs[[2]string{e.Src, e.Dst}]
+		if !ok {
+			l = &mapLink{src: e.Src, dst: e.Dst}
+			pairs[[2]string{e.Src, e.Dst}] = l
+			t.links = append(t.links, l)
+		}
+		l.calls += e.CallCount
+		l.errors += e.ErrorCount
+		l.anomaly = l.anomaly || anomalyEdges[key]
+		l.cycle = l.cycle || cycleEdges[key]
+	}
+	sort.Slice(t.links, func(i, j int) bool {
+		if t.links[i].src != t.links[j].src {
+			return t.links[i].src < t.links[j].src
+		}
+		return t.links[i].dst < t.links[j].dst
+	})
+
+	names := make([]string, 0, len(t.nodes))
+	for name := range t.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make(map[string][]*mapLink)
+	for _, l := range t.links {
+		out[l.src] = append(out[l.src], l)
+	}
+	t.markCycles(names, out)
+	t.breakCycles(names, out)
+	t.assignLayers(names)
+	t.addSegments()
+	t.orderLayers()
+	t.place()
+	return t
+}
+
+// markCycles flags links and nodes inside a strongly connected component.
+func (t *topoLayout) markCycles(names []string, out map[string][]*mapLink) {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	comp := make(map[string]int)
+	var stack []string
+	next, ncomp := 0, 0
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, l := range out[v] {
+			w := l.dst
+			if _, seen := index[w]; !seen {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var members []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			comp[w] = ncomp
+			members = append(members, w)
+			if w == v {
+				break
+			}
+		}
+		if len(members) > 1 {
+			for _, w := range members {
+				t.nodes[w].cyclic = true
+			}
+		}
+		ncomp++
+	}
+	for _, name := range names {
+		if _, seen := index[name]; !seen {
+			visit(name)
+		}
+	}
+	for _, l := range t.links {
+		if comp[l.src] == comp[l.dst] {
+			l.cycle = true
+		}
+	}
+}
+
+// breakCycles reverses the back edges of a depth-first search, which
+// leaves the links acyclic.
+func (t *topoLayout) breakCycles(names []string, out map[string][]*mapLink) {
+	const (
+		white = iota
+		grey
+		black
+	)
+	state := make(map[string]int)
+	var visit func(v string)
+	visit = func(v string) {
+		state[v] = grey
+		for _, l := range out[v] {
+			switch state[l.dst] {
+			case white:
+				visit(l.dst)
+			case grey:
+				l.reversed = true
+			}
+		}
+		state[v] = black
+	}
+	for _, name := range names {
+		if state[name] == white {
+			visit(name)
+		}
+	}
+}
+
+// ends returns a link's endpoints in drawing order (left to right).
+func (l *mapLink) ends() (string, string) {
+	if l.reversed {
+		return l.dst, l.src
+	}
+	return l.src, l.dst
+}
+
+func (t *topoLayout) assignLayers(names []string) {
+	preds := make(map[string][]string)
+	for _, l := range t.links {
+		a, b := l.ends()
+		preds[b] = append(preds[b], a)
+	}
+	done := make(map[string]bool)
+	var layer func(v string) int
+	layer = func(v string) int {
+		n := t.nodes[v]
+		if done[v] {
+			return n.layer
+		}
+		done[v] = true
+		for _, p := range preds[v] {
+			n.layer = max(n.layer, layer(p)+1)
+		}
+		return n.layer
+	}
+	for _, name := range names {
+		n := t.nodes[name]
+		layer(name)
+		for len(t.layers) <= n.layer {
+			t.layers = append(t.layers, nil)
+		}
+		t.layers[n.layer] = append(t.layers[n.layer], n)
+	}
+}
+
+// addSegments splits every link into one segment per layer it crosses.
+func (t *topoLayout) addSegments() {
+	for _, l := range t.links {
+		a, b := l.ends()
+		from, to := t.nodes[a], t.nodes[b]
+		for layer := from.layer + 1; layer <= to.layer; layer++ {
+			next := to
+			if layer < to.layer {
+				next = &mapNode{name: a + "→" + b, dummy: true, layer: layer}
+				t.layers[layer] = append(t.layers[layer], next)
+			}
+			t.segments = append(t.segments, mapSegment{
+				from: from,
+				to:   next,
+				link: l,
+				head: next == to && !l.reversed,
+				tail: layer == from.layer+1 && l.reversed,
+			})
+			from = next
+		}
+	}
+}
+
+// orderLayers sorts the first layer by name and each following layer by
+// the mean slot of its predecessors, which keeps crossings down.
+func (t *topoLayout) orderLayers() {
+	for i, layer := range t.layers {
+		if i > 0 {
+			sum := make(map[*mapNode]float64)
+			cnt := make(map[*mapNode]int)
+			for _, s := range t.segments {
+				if s.to.layer == i {
+					sum[s.to] += float64(s.from.slot)
+					cnt[s.to]++
+				}
+			}
+			for _, n := range layer {
+				if cnt[n] > 0 {
+					n.bary = sum[n] / float64(cnt[n])
+				}
+			}
+		}
+		sort.SliceStable(layer, func(a, b int) bool {
+			if layer[a].bary != layer[b].bary {
+				return layer[a].bary < layer[b].bary
+			}
+			return layer[a].name < layer[b].name
+		})
+		for slot, n := range layer {
+			n.slot = slot
+		}
+	}
+}
+
+// place computes column widths, edge lanes and canvas coordinates. Each
+// source in a gap gets its own vertical lane so fan-outs stay readable.
+func (t *topoLayout) place() {
+	t.colX = make([]int, len(t.layers))
+	t.colW = make([]int, len(t.layers))
+	t.lanes = make([]map[*mapNode]int, len(t.layers))
+	slots := 0
+	for i, layer := range t.layers {
+		t.colW[i] = 3
+		for _, n := range layer {
+			if !n.dummy {
+				t.colW[i] = max(t.colW[i], lipgloss.Width(n.label())+4)
+			}
+		}
+		slots = max(slots, len(layer))
+		t.lanes[i] = make(map[*mapNode]int)
+	}
+	for _, s := range t.segments {
+		t.lanes[s.from.layer][s.from] = s.from.slot
+	}
+	x := 0
+	for i, layer := range t.layers {
+		t.colX[i] = x
+		lane := 0
+		for _, n := range layer {
+			n.x, n.y = x, n.slot*mapSlotHeight
+			if _, ok := t.lanes[i][n]; ok {
+				t.lanes[i][n] = lane
+				lane++
+			}
+		}
+		x += t.colW[i]
+		if i < len(t.layers)-1 {
+			x += lane + 4
+		}
+	}
+	t.width = x
+	t.height = max(0, slots*mapSlotHeight-1)
+}
+
+// draw renders the layout with sel highlighted.
+func (t *topoLayout) draw(sel string) *mapCanvas {
+	c := newMapCanvas(t.width, t.height)
+	for _, s := range t.segments {
+		st := s.link.style()
+		l := s.from.layer
+		gs := t.colX[l] + t.colW[l]
+		ge := t.colX[l+1] - 1
+		lane := gs + 2 + t.lanes[l][s.from]
+		sy, dy := s.from.y+1, s.to.y+1
+		c.hline(sy, gs, lane, st)
+		c.vline(lane, sy, dy, st)
+		c.hline(dy, lane, ge, st)
+		if s.from.dummy {
+			c.hline(sy, s.from.x, gs, st)
+		}
+		if s.head {
+			c.text(ge, dy, "▶", st)
+		}
+		if s.tail {
+			c.text(gs, sy, "◀", st)
+		}
+	}
+	for _, layer := range t.layers {
+		for _, n := range layer {
+			if n.dummy {
+				continue
+			}
+			st := mapBox
+			if n.name == sel {
+				st = mapBoxSelected
+			}
+			c.box(n.x, n.y, t.colW[n.layer], n.label(), st)
+		}
+	}
+	return c
+}
+
+// Screen4 draws the whole call graph as a layered topology map.
+type Screen4 struct {
+	layout       *topoLayout
+	cycleEdges   map[string]bool
+	anomalyEdges map[string]bool
+	selected     string
+
+	width  int
+	height int
+}
+
+func NewScreen4() Screen4 {
+	return Screen4{layout: buildTopology(graph.CallGraphSnapshot{}, nil, nil)}
+}
+
+func (s *Screen4) SetSize(w, h int) {
+	s.width = w
+	s.height = h
+}
+
+func (s *Screen4) SetCycleEdges(keys map[string]bool) {
+	s.cycleEdges = keys
+}
+
+func (s *Screen4) SetAnomalyEdges(keys map[string]bool) {
+	s.anomalyEdges = keys
+}
+
+// Select moves the cursor to service if it is on the map.
+func (s *Screen4) Select(service string) {
+	if _, ok := s.layout.nodes[service]; ok {
+		s.selected = service
+	}
+}
+
+func (s *Screen4) Update(snap graph.CallGraphSnapshot) {
+	s.layout = buildTopology(snap, s.cycleEdges, s.anomalyEdges)
+	if _, ok := s.layout.nodes[s.selected]; !ok {
+		s.selected = ""
+		if len(s.layout.layers) > 0 {
+			s.selected = s.layout.layers[0][0].name
+		}
+	}
+}
+
+// SelectedService returns the service under the cursor.
+func (s *Screen4) SelectedService() string {
+	return s.selected
+}
+
+func (s *Screen4) HandleKey(msg tea.KeyMsg) {
+	cur, ok := s.layout.nodes[s.selected]
+	if !ok {
+		return
+	}
+	switch msg.String() {
+	case "up", "k":
+		s.moveInLayer(cur, -1)
+	case "down", "j":
+		s.moveInLayer(cur, 1)
+	case "left", "h":
+		s.moveAcross(cur, -1)
+	case "right", "l":
+		s.moveAcross(cur, 1)
+	}
+}
+
+func (s *Screen4) moveInLayer(cur *mapNode, dir int) {
+	layer := s.layout.layers[cur.layer]
+	for i := cur.slot + dir; i >= 0 && i < len(layer); i += dir {
+		if !layer[i].dummy {
+			s.selected = layer[i].name
+			return
+		}
+	}
+}
+
+// moveAcross selects the service nearest the cursor's row in the next
+// layer, in direction dir, that has one.
+func (s *Screen4) moveAcross(cur *mapNode, dir int) {
+	for l := cur.layer + dir; l >= 0 && l < len(s.layout.layers); l += dir {
+		var best *mapNode
+		for _, n := range s.layout.layers[l] {
+			if n.dummy {
+				continue
+			}
+			if best == nil || abs(n.slot-cur.slot) < abs(best.slot-cur.slot) {
+				best = n
+			}
+		}
+		if best != nil {
+			s.selected = best.name
+			return
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (s *Screen4) View() string {
+	var b strings.Builder
+
+	services := 0
+	for _, n := range s.layout.nodes {
+		if !n.dummy {
+			services++
+		}
+	}
+	title := StyleTitle.Render("⬡ Topology")
+	counts := StyleDim.Render(fmt.Sprintf("%d services  %d links", services, len(s.layout.links)))
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top,
+		title,
+		strings.Repeat(" ", max(0, s.width-lipgloss.Width(title)-lipgloss.Width(counts)-2)),
+		counts,
+	) + "\n")
+	b.WriteString("  " + strings.Join([]string{
+		StyleOK.Render("──▶ ok"),
+		StyleWarn.Render("──▶ ≥1% errors"),
+		StyleError.Render("──▶ ≥5% errors"),
+		mapEdgeAnomaly.style().Render("──▶ anomaly"),
+		StyleCycleBadge.Render("──▶ cycle ↺"),
+	}, "   ") + "\n\n")
+
+	if services == 0 {
+		b.WriteString(StyleDim.Render("  — no services yet —") + "\n")
+		return b.String()
+	}
+
+	c := s.layout.draw(s.selected)
+	viewW, viewH := s.width-2, s.height-8
+	if viewW <= 0 {
+		viewW = c.w
+	}
+	if viewH <= 0 {
+		viewH = c.h
+	}
+	x0, y0 := 0, 0
+	if n, ok := s.layout.nodes[s.selected]; ok {
+		if right := n.x + s.layout.colW[n.layer]; right > viewW {
+			x0 = right - viewW
+		}
+		if bottom := n.y + 3; bottom > viewH {
+			y0 = bottom - viewH
+		}
+	}
+	for _, line := range strings.Split(c.render(x0, y0, viewW, viewH), "\n") {
+		b.WriteString("  " + line + "\n")
+	}
+	return b.String()
+}
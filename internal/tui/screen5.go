@@ -0,0 +1,258 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"collector/internal/anomaly"
+)
+
+const maxAnomalies = 500
+
+// muteDurations are the mute lengths cycled through with "d".
+var muteDurations = []time.Duration{15 * time.Minute, time.Hour, 4 * time.Hour, 24 * time.Hour}
+
+// AnomalyEntry is one fired anomaly with its edge split for display.
+type AnomalyEntry struct {
+	Event               anomaly.AnomalyEvent
+	Src, Dst, Operation string
+}
+
+// Screen5 is the anomaly timeline: every alert the detector raised, newest
+// first, with acknowledge/mute per edge.
+type Screen5 struct {
+	entries []AnomalyEntry // oldest first
+	shown   []int          // indices into entries passing the filters, newest first
+	cursor  int            // index into shown
+	metrics []string       // metrics seen so far, sorted
+	metric  int            // 0 for all, else index into metrics + 1
+	filter  textinput.Model
+	typing  bool
+	muteFor int // index into muteDurations
+	acks    *anomaly.AckStore
+	notice  string // result of the last ack, shown under the controls
+
+	width  int
+	height int
+}
+
+func NewScreen5() Screen5 {
+	ti := textinput.New()
+	ti.Placeholder = "filter by service..."
+	ti.CharLimit = 64
+	return Screen5{filter: ti, muteFor: 1}
+}
+
+func (s *Screen5) SetSize(w, h int) {
+	s.width = w
+	s.height = h
+}
+
+// SetAcks supplies the store the mute status column is read from.
+func (s *Screen5) SetAcks(acks *anomaly.AckStore) {
+	s.acks = acks
+}
+
+// SetNotice shows a one-line status message, e.g. a failed save.
+func (s *Screen5) SetNotice(n string) {
+	s.notice = n
+}
+
+// Capturing reports whether the service filter is taking keystrokes.
+func (s *Screen5) Capturing() bool {
+	return s.typing
+}
+
+// Add records a fired anomaly.
+func (s *Screen5) Add(ev anomaly.AnomalyEvent) {
+	parts := strings.SplitN(ev.EdgeKey, "|", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	s.entries = append(s.entries, AnomalyEntry{Event: ev, Src: parts[0], Dst: parts[1], Operation: parts[2]})
+	if len(s.entries) > maxAnomalies {
+		s.entries = s.entries[len(s.entries)-maxAnomalies:]
+	}
+	if i := sort.SearchStrings(s.metrics, ev.Metric); i == len(s.metrics) || s.metrics[i] != ev.Metric {
+		s.metrics = append(s.metrics, "")
+		copy(s.metrics[i+1:], s.metrics[i:])
+		s.metrics[i] = ev.Metric
+		if s.metric > i {
+			s.metric++
+		}
+	}
+	s.refilter()
+}
+
+func (s *Screen5) refilter() {
+	query := strings.ToLower(strings.TrimSpace(s.filter.Value()))
+	metric := ""
+	if s.metric > 0 {
+		metric = s.metrics[s.metric-1]
+	}
+	s.shown = s.shown[:0]
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if metric != "" && e.Event.Metric != metric {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(e.Src), query) &&
+			!strings.Contains(strings.ToLower(e.Dst), query) {
+			continue
+		}
+		s.shown = append(s.shown, i)
+	}
+	if s.cursor >= len(s.shown) {
+		s.cursor = max(0, len(s.shown)-1)
+	}
+}
+
+// Selected returns the anomaly under the cursor, or nil.
+func (s *Screen5) Selected() *AnomalyEntry {
+	if s.cursor < 0 || s.cursor >= len(s.shown) {
+		return nil
+	}
+	return &s.entries[s.shown[s.cursor]]
+}
+
+// HandleKey returns a command emitting a MuteMsg when the selected edge
+// is acknowledged or unmuted.
+func (s *Screen5) HandleKey(msg tea.KeyMsg) tea.Cmd {
+	if s.typing {
+		switch msg.String() {
+		case "enter":
+			s.typing = false
+			s.filter.Blur()
+		case "esc":
+			s.typing = false
+			s.filter.Blur()
+			s.filter.SetValue("")
+		default:
+			s.filter, _ = s.filter.Update(msg)
+		}
+		s.refilter()
+		return nil
+	}
+	switch msg.String() {
+	case "up", "k":
+		if s.cursor > 0 {
+			s.cursor--
+		}
+	case "down", "j":
+		if s.cursor < len(s.shown)-1 {
+			s.cursor++
+		}
+	case "/":
+		s.typing = true
+		s.filter.Focus()
+	case "f":
+		s.metric = (s.metric + 1) % (len(s.metrics) + 1)
+		s.refilter()
+	case "d":
+		s.muteFor = (s.muteFor + 1) % len(muteDurations)
+	case "a":
+		if e := s.Selected(); e != nil {
+			msg := MuteMsg{EdgeKey: e.Event.EdgeKey, Until: time.Now().Add(muteDurations[s.muteFor])}
+			return func() tea.Msg { return msg }
+		}
+	case "u":
+		if e := s.Selected(); e != nil {
+			msg := MuteMsg{EdgeKey: e.Event.EdgeKey}
+			return func() tea.Msg { return msg }
+		}
+	}
+	return nil
+}
+
+func (s *Screen5) View() string {
+	var b strings.Builder
+	now := time.Now()
+
+	muted := 0
+	if s.acks != nil {
+		muted = len(s.acks.Active(now))
+	}
+	b.WriteString(StyleTitle.Render("⚠ Anomalies") +
+		StyleDim.Render(fmt.Sprintf("  %d fired, %d edges muted", len(s.entries), muted)) + "\n")
+	b.WriteString(s.controlsLine() + "\n")
+	switch {
+	case s.typing || s.filter.Value() != "":
+		b.WriteString("  " + s.filter.View() + "\n")
+	case s.notice != "":
+		b.WriteString("  " + StyleWarn.Render(s.notice) + "\n")
+	default:
+		b.WriteString("\n")
+	}
+
+	b.WriteString(StyleDim.Render(fmt.Sprintf("  %-8s %-10s %-36s %10s %7s %10s %10s  %s",
+		"TIME", "METRIC", "EDGE", "VALUE", "Z", "MEAN", "STDDEV", "STATUS")) + "\n")
+
+	switch {
+	case len(s.entries) == 0:
+		b.WriteString(StyleDim.Render("  — no anomalies yet —") + "\n")
+	case len(s.shown) == 0:
+		b.WriteString(StyleDim.Render("  — no anomalies match the filters —") + "\n")
+	}
+
+	visible := max(5, s.height-10)
+	start := max(0, s.cursor-visible+1)
+	end := min(len(s.shown), start+visible)
+	for i := start; i < end; i++ {
+		b.WriteString(s.renderRow(s.entries[s.shown[i]], i == s.cursor, now) + "\n")
+	}
+	return b.String()
+}
+
+func (s *Screen5) controlsLine() string {
+	sep := StyleDim.Render("  |  ")
+	svc := s.filter.Value()
+	if svc == "" {
+		svc = "all"
+	}
+	metric := "all"
+	if s.metric > 0 {
+		metric = s.metrics[s.metric-1]
+	}
+	return strings.Join([]string{
+		StyleDim.Render("service: ") + svc + StyleDim.Render(" [/]"),
+		StyleDim.Render("metric: ") + metric + StyleDim.Render(" [f]"),
+		StyleDim.Render("mute for: ") + muteDurations[s.muteFor].String() + StyleDim.Render(" [d]"),
+		StyleDim.Render(fmt.Sprintf("showing %d/%d", len(s.shown), len(s.entries))),
+	}, sep)
+}
+
+func (s *Screen5) renderRow(e AnomalyEntry, selected bool, now time.Time) string {
+	ev := e.Event
+	edge := fmt.Sprintf("%s → %s", e.Src, e.Dst)
+	if e.Operation != "" {
+		edge += " " + e.Operation
+	}
+
+	status := ""
+	if s.acks != nil {
+		if until, ok := s.acks.Until(ev.EdgeKey, now); ok {
+			status = "muted " + until.Sub(now).Round(time.Minute).String()
+		}
+	}
+
+	line := fmt.Sprintf("  %-8s %-10s %-36s %10.2f %7.2f %10.2f %10.2f  %s",
+		ev.Timestamp.Format("15:04:05"),
+		truncateName(ev.Metric, 10),
+		truncateName(edge, 36),
+		ev.Value, ev.ZScore, ev.Mean, ev.StdDev,
+		status,
+	)
+
+	switch {
+	case selected:
+		return StyleSelected.Width(s.width).Render(line)
+	case status != "":
+		return StyleDim.Render(line)
+	}
+	return StyleError.Render(line)
+}
@@ -1,9 +1,16 @@
 package tui
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
+
+	"collector/internal/anomaly"
 	"collector/internal/event"
 	"collector/internal/graph"
 )
@@ -255,3 +262,435 @@ func makeNormEvent(src, dst, op string) *event.NormalizedEvent {
 		Timestamp:  time.Now(),
 	}
 }
+
+func TestEventFeed_FiltersByFocus(t *testing.T) {
+	f := NewEventFeed(4)
+	f.Publish(makeNormEvent("a", "b", "op"))
+	if len(f.Events()) != 0 {
+		t.Fatal("nothing should be queued without a focused edge")
+	}
+	f.SetFocus("a|b|op")
+	f.Publish(makeNormEvent("x", "y", "op"))
+	f.Publish(makeNormEvent("a", "b", "op"))
+	if len(f.Events()) != 1 {
+		t.Errorf("want 1 queued event, got %d", len(f.Events()))
+	}
+}
+
+func TestEventFeed_DropsOldest(t *testing.T) {
+	f := NewEventFeed(3)
+	f.SetFocus("a|b|op")
+	for i := 0; i < 5; i++ {
+		ev := makeNormEvent("a", "b", "op")
+		ev.StatusCode = 200 + i
+		f.Publish(ev)
+	}
+	if f.Dropped() != 2 {
+		t.Errorf("Dropped = %d, want 2", f.Dropped())
+	}
+	msg := listenEventFeed(f)().(EventMsg)
+	if len(msg.Events) != 3 || msg.Events[0].StatusCode != 202 || msg.Events[2].StatusCode != 204 {
+		t.Errorf("want the 3 newest events in order, got %d events", len(msg.Events))
+	}
+}
+
+func TestScreen3_PauseResume(t *testing.T) {
+	s := NewScreen3()
+	s.SetEdge("a", "b", "op", "a|b|op")
+	s.AddEvent(makeNormEvent("a", "b", "op"))
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	s.AddEvent(makeNormEvent("a", "b", "op"))
+	s.AddEvent(makeNormEvent("a", "b", "op"))
+	if len(s.events) != 1 || len(s.pending) != 2 {
+		t.Fatalf("paused: events=%d pending=%d", len(s.events), len(s.pending))
+	}
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if len(s.events) != 3 || len(s.pending) != 0 || s.cursor != 2 {
+		t.Errorf("resumed: events=%d pending=%d cursor=%d", len(s.events), len(s.pending), s.cursor)
+	}
+}
+
+func TestScreen3_LevelFilterAndSearch(t *testing.T) {
+	s := NewScreen3()
+	s.SetEdge("a", "b", "op", "a|b|op")
+	for _, tc := range []struct {
+		level string
+		code  int
+		trace string
+	}{{"debug", 200, "t-1"}, {"", 503, "t-2"}, {"warn", 200, "t-3"}, {"info", 404, "needle"}} {
+		ev := makeNormEvent("a", "b", "op")
+		ev.Level, ev.StatusCode, ev.TraceID = tc.level, tc.code, tc.trace
+		s.AddEvent(ev)
+	}
+
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")}) // debug+
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")}) // info+
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")}) // warn+
+	if len(s.shown) != 2 {
+		t.Errorf("warn+: want 2 events (503 derived error, warn), got %d", len(s.shown))
+	}
+
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")}) // back to all
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	if !s.Capturing() {
+		t.Fatal("search input should capture keys")
+	}
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("NEEDLE")})
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(s.shown) != 1 || s.selected().Event.TraceID != "needle" {
+		t.Errorf("search: want the needle event, got %d matches", len(s.shown))
+	}
+}
+
+func TestScreen3_DetailPane(t *testing.T) {
+	s := NewScreen3()
+	s.SetSize(120, 40)
+	s.SetEdge("a", "b", "op", "a|b|op")
+	ev := makeNormEvent("a", "b", "op")
+	ev.Raw = map[string]any{"message": "upstream timed out"}
+	s.AddEvent(ev)
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if !s.showPopup || !s.Capturing() {
+		t.Fatal("enter should open the detail pane")
+	}
+	view := s.View()
+	for _, want := range []string{"Parsed", "src_service", "Raw", "upstream timed out"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("detail pane missing %q", want)
+		}
+	}
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	if s.showPopup {
+		t.Error("esc should close the detail pane")
+	}
+}
+
+func topoSnapshot() graph.CallGraphSnapshot {
+	return graph.CallGraphSnapshot{
+		Nodes: []graph.NodeID{"gateway", "orders", "payments", "inventory", "db"},
+		Edges: []graph.Edge{
+			{Src: "gateway", Dst: "orders", Operation: "POST /orders", CallCount: 100},
+			{Src: "gateway", Dst: "inventory", Operation: "GET /stock", CallCount: 100, ErrorCount: 10},
+			{Src: "orders", Dst: "payments", Operation: "charge", CallCount: 50},
+			{Src: "payments", Dst: "orders", Operation: "callback", CallCount: 5},
+			{Src: "orders", Dst: "db", Operation: "query", CallCount: 80},
+			{Src: "gateway", Dst: "db", Operation: "query", CallCount: 10},
+		},
+	}
+}
+
+func TestTopology_Layers(t *testing.T) {
+	l := buildTopology(topoSnapshot(), nil, nil)
+	for name, want := range map[string]int{"gateway": 0, "orders": 1, "inventory": 1, "payments": 2, "db": 2} {
+		if got := l.nodes[name].layer; got != want {
+			t.Errorf("%s: layer %d, want %d", name, got, want)
+		}
+	}
+	for _, name := range []string{"orders", "payments"} {
+		if !l.nodes[name].cyclic {
+			t.Errorf("%s should be marked as part of a cycle", name)
+		}
+	}
+	for _, link := range l.links {
+		inCycle := link.src != "gateway" && link.dst != "db"
+		if link.cycle != inCycle {
+			t.Errorf("%s→%s: cycle = %v", link.src, link.dst, link.cycle)
+		}
+	}
+	// gateway→db spans two layers, so it is routed through a dummy node.
+	dummies := 0
+	for _, n := range l.layers[1] {
+		if n.dummy {
+			dummies++
+		}
+	}
+	if dummies != 1 {
+		t.Errorf("want 1 dummy node in layer 1, got %d", dummies)
+	}
+}
+
+func TestTopology_EdgeStyles(t *testing.T) {
+	l := buildTopology(topoSnapshot(), nil, map[string]bool{"orders|db|query": true})
+	want := map[[2]string]mapStyle{
+		{"gateway", "orders"}:    mapEdgeOK,
+		{"gateway", "inventory"}: mapEdgeError,
+		{"orders", "payments"}:   mapEdgeCycle,
+		{"orders", "db"}:         mapEdgeAnomaly,
+	}
+	for _, link := range l.links {
+		if st, ok := want[[2]string{link.src, link.dst}]; ok && link.style() != st {
+			t.Errorf("%s→%s: style %d, want %d", link.src, link.dst, link.style(), st)
+		}
+	}
+}
+
+func TestScreen4_Navigation(t *testing.T) {
+	s := NewScreen4()
+	s.Update(topoSnapshot())
+	if s.SelectedService() != "gateway" {
+		t.Fatalf("initial selection %q, want gateway", s.SelectedService())
+	}
+	key := func(k tea.KeyType) { s.HandleKey(tea.KeyMsg{Type: k}) }
+
+	key(tea.KeyRight)
+	first := s.SelectedService()
+	if first != "inventory" && first != "orders" {
+		t.Fatalf("right from gateway selected %q", first)
+	}
+	key(tea.KeyDown)
+	key(tea.KeyDown)
+	if s.SelectedService() == first || s.layout.nodes[s.SelectedService()].layer != 1 {
+		t.Errorf("down should move within layer 1 skipping dummies, got %q", s.SelectedService())
+	}
+	key(tea.KeyRight)
+	if l := s.layout.nodes[s.SelectedService()].layer; l != 2 {
+		t.Errorf("right should reach layer 2, got layer %d", l)
+	}
+	key(tea.KeyRight)
+	if l := s.layout.nodes[s.SelectedService()].layer; l != 2 {
+		t.Errorf("right on the last layer should stay put, got layer %d", l)
+	}
+	key(tea.KeyLeft)
+	key(tea.KeyLeft)
+	if s.SelectedService() != "gateway" {
+		t.Errorf("left twice should return to gateway, got %q", s.SelectedService())
+	}
+}
+
+func TestScreen4_View(t *testing.T) {
+	s := NewScreen4()
+	s.SetSize(160, 40)
+	s.Update(topoSnapshot())
+	view := s.View()
+	for _, want := range []string{"gateway", "orders ↺", "payments ↺", "inventory", "db", "▶", "◀", "╭"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("map missing %q", want)
+		}
+	}
+
+	empty := NewScreen4()
+	if !strings.Contains(empty.View(), "no services") {
+		t.Error("empty map should say so")
+	}
+}
+
+func TestModel_TopologyEnterOpensDependencies(t *testing.T) {
+	m := New(Local(nil, nil), func() {})
+	m.lastSnapshot = topoSnapshot()
+	press := func(k tea.KeyMsg) {
+		next, _ := m.Update(k)
+		m = next.(Model)
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	if m.screen != ScreenTopology {
+		t.Fatalf("m should open the map, screen = %d", m.screen)
+	}
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.screen != ScreenDependency || m.screen2.service != "gateway" {
+		t.Fatalf("enter should open Screen 2 for gateway, screen = %d service = %q", m.screen, m.screen2.service)
+	}
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.screen != ScreenTopology {
+		t.Errorf("esc from Screen 2 should return to the map, screen = %d", m.screen)
+	}
+}
+
+func TestHistory_RecordDeltas(t *testing.T) {
+	h := NewHistory()
+	at := time.Unix(1000, 0)
+	snap := func(calls, errs int64) graph.CallGraphSnapshot {
+		return graph.CallGraphSnapshot{
+			Nodes: []graph.NodeID{"a", "b"},
+			Edges: []graph.Edge{{Src: "a", Dst: "b", Operation: "op", CallCount: calls, ErrorCount: errs, LatencyP99: 120 * time.Millisecond}},
+		}
+	}
+	h.Record(snap(100, 0), at)
+	if h.Edge("a|b|op") != nil {
+		t.Fatal("the first snapshot only sets the baseline")
+	}
+	h.Record(snap(120, 5), at.Add(2*time.Second))
+	h.Record(snap(3, 0), at.Add(3*time.Second)) // edge expired and came back
+
+	e := h.Edge("a|b|op")
+	if e == nil || len(e.calls) != 2 {
+		t.Fatalf("want 2 edge samples, got %+v", e)
+	}
+	if e.calls[0] != 10 || e.errors[0] != 0.25 || e.p99[0] != 120 {
+		t.Errorf("sample 0 = %v/s %v err %vms, want 10/s 0.25 err 120ms", e.calls[0], e.errors[0], e.p99[0])
+	}
+	if e.calls[1] != 3 {
+		t.Errorf("after a counter reset want 3/s, got %v", e.calls[1])
+	}
+	if s := h.Service("a"); s == nil || s.calls[0] != 10 {
+		t.Errorf("service a should aggregate its outgoing edge, got %+v", s)
+	}
+	if s := h.Service("b"); s == nil || s.calls[0] != 0 {
+		t.Errorf("service b has no outgoing calls, got %+v", s)
+	}
+
+	h.Record(graph.CallGraphSnapshot{Nodes: []graph.NodeID{"a"}}, at.Add(4*time.Second))
+	if h.Edge("a|b|op") != nil || h.Service("b") != nil {
+		t.Error("series of vanished edges and services should be dropped")
+	}
+}
+
+func TestHistory_Bounded(t *testing.T) {
+	h := NewHistory()
+	at := time.Unix(1000, 0)
+	for i := 0; i <= historyLen+10; i++ {
+		h.Record(graph.CallGraphSnapshot{Nodes: []graph.NodeID{"a"}}, at.Add(time.Duration(i)*time.Second))
+	}
+	if n := len(h.Service("a").calls); n != historyLen {
+		t.Errorf("want %d samples, got %d", historyLen, n)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	for _, tc := range []struct {
+		vals  []float64
+		width int
+		want  string
+	}{
+		{nil, 4, "    "},
+		{[]float64{0, 0}, 3, " ▁▁"},
+		{[]float64{0, 7, 14}, 3, "▁▄█"},
+		{[]float64{9, 0, 7, 14}, 3, "▁▄█"},
+	} {
+		if got := sparkline(tc.vals, tc.width); got != tc.want {
+			t.Errorf("sparkline(%v, %d) = %q, want %q", tc.vals, tc.width, got, tc.want)
+		}
+	}
+}
+
+func TestChart(t *testing.T) {
+	out := chart("calls/s", []float64{2, 4}, 4, 2, formatRate, StyleOK)
+	lines := strings.Split(out, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want caption and 2 rows, got %d lines:\n%s", len(lines), out)
+	}
+	if !strings.Contains(lines[0], "now 4.0/s") || !strings.Contains(lines[0], "max 4.0/s") {
+		t.Errorf("caption = %q", lines[0])
+	}
+	if lines[1] != "   █" || lines[2] != "  ██" {
+		t.Errorf("bars = %q / %q", lines[1], lines[2])
+	}
+}
+
+func TestScreen2_ChartPanel(t *testing.T) {
+	h := NewHistory()
+	at := time.Unix(1000, 0)
+	edges := func(calls int64) graph.CallGraphSnapshot {
+		return graph.CallGraphSnapshot{
+			Nodes: []graph.NodeID{"api", "db"},
+			Edges: []graph.Edge{{Src: "api", Dst: "db", Operation: "query", CallCount: calls}},
+		}
+	}
+	h.Record(edges(0), at)
+	h.Record(edges(50), at.Add(time.Second))
+
+	s := NewScreen2()
+	s.SetSize(200, 40)
+	s.SetHistory(h)
+	s.SetService("api")
+	s.Update(edges(50))
+	view := s.View(0)
+	for _, want := range []string{"CALLS/S", "calls/s", "now 50.0/s", "p99"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q", want)
+		}
+	}
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if strings.Contains(s.View(0), "now 50.0/s") {
+		t.Error("c should hide the chart panel")
+	}
+}
+
+func TestScreen5_FiltersAndAck(t *testing.T) {
+	s := NewScreen5()
+	for _, ev := range []anomaly.AnomalyEvent{
+		{EdgeKey: "api|db|query", Metric: "latency", Value: 900, ZScore: 4.2},
+		{EdgeKey: "web|api|GET /", Metric: "error_rate", Value: 0.4, ZScore: 5.1},
+		{EdgeKey: "api|cache|get", Metric: "latency", Value: 300, ZScore: 3.3},
+	} {
+		s.Add(ev)
+	}
+	if got := s.Selected(); got == nil || got.Event.EdgeKey != "api|cache|get" || got.Dst != "cache" {
+		t.Fatalf("the newest anomaly should be listed first, got %+v", got)
+	}
+
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")}) // error_rate
+	if len(s.shown) != 1 || s.Selected().Event.Metric != "error_rate" {
+		t.Errorf("metric filter: got %d rows", len(s.shown))
+	}
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")}) // latency
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("cache")})
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(s.shown) != 1 || s.Selected().Dst != "cache" {
+		t.Fatalf("service filter: got %d rows", len(s.shown))
+	}
+
+	s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")}) // 4h
+	cmd := s.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if cmd == nil {
+		t.Fatal("a should acknowledge the selected edge")
+	}
+	mute := cmd().(MuteMsg)
+	if mute.EdgeKey != "api|cache|get" || time.Until(mute.Until) < 3*time.Hour {
+		t.Errorf("mute = %+v, want api|cache|get for 4h", mute)
+	}
+}
+
+func TestModel_MuteUpdatesAcks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acks.json")
+	store, err := anomaly.LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	det := anomaly.NewZScoreDetector(50, 3.0, 8)
+	m := New(Local(nil, det), func() {}).WithAcks(store)
+
+	next, _ := m.Update(MuteMsg{EdgeKey: "a|b|op", Until: time.Now().Add(time.Hour)})
+	m = next.(Model)
+	reloaded, _ := anomaly.LoadAcks(path)
+	if len(reloaded.Active(time.Now())) != 1 {
+		t.Fatal("ack should be persisted")
+	}
+	if !strings.Contains(m.screen5.View(), "muted a|b|op") {
+		t.Error("screen should confirm the mute")
+	}
+
+	next, _ = m.Update(MuteMsg{EdgeKey: "a|b|op"})
+	m = next.(Model)
+	reloaded, _ = anomaly.LoadAcks(path)
+	if len(reloaded.Active(time.Now())) != 0 {
+		t.Error("unmute should remove the ack")
+	}
+}
+
+// readOnlyBackend refuses mutes, as a remote collector does.
+type readOnlyBackend struct{ Backend }
+
+func (readOnlyBackend) Mute(string, time.Time) error { return errors.New("mute not supported") }
+
+func TestModel_RefusedMuteIsNotRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acks.json")
+	store, err := anomaly.LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(readOnlyBackend{Local(nil, nil)}, func() {}).WithAcks(store)
+
+	next, _ := m.Update(MuteMsg{EdgeKey: "a|b|op", Until: time.Now().Add(time.Hour)})
+	m = next.(Model)
+	if len(store.Active(time.Now())) != 0 {
+		t.Error("a refused mute should not be acknowledged")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("ack file written: %v", err)
+	}
+	if view := m.screen5.View(); !strings.Contains(view, "mute not applied") {
+		t.Errorf("screen should show the error:\n%s", view)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"collector/internal/anomaly"
+	"collector/internal/event"
 	"collector/internal/graph"
 )
 
@@ -14,6 +15,8 @@ const (
 	ScreenServiceList Screen = iota
 	ScreenDependency
 	ScreenEventLog
+	ScreenTopology
+	ScreenAnomalies
 )
 
 // SortColumn identifies which column to sort by on Screen 1.
@@ -47,6 +50,18 @@ type GraphEventMsg struct {
 	Event graph.GraphEvent
 }
 
+// EventMsg carries pipeline events for the event log.
+type EventMsg struct {
+	Events []*event.NormalizedEvent
+}
+
+// MuteMsg acknowledges an edge, silencing its alerts until Until. A zero
+// Until lifts the mute.
+type MuteMsg struct {
+	EdgeKey string
+	Until   time.Time
+}
+
 // SelectServiceMsg navigates to Screen 2 for the given service.
 type SelectServiceMsg struct {
 	Service string